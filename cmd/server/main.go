@@ -1,72 +1,158 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"mi-bot-unne/internal/config"
 	"mi-bot-unne/internal/database"
 	"mi-bot-unne/internal/handlers"
+	"mi-bot-unne/internal/logging"
 	"mi-bot-unne/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Inicializar Base de Datos
-	db, err := database.InitDB("./data/mesas.db")
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Print(config.Usage())
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuración inválida:\n%v\n\n%s", err, config.Usage())
+		os.Exit(2)
+	}
+
+	// "server migrate ..." administra el esquema sin levantar el servidor
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(runMigrate(cfg.DBPath, args[1:]))
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "argumento desconocido: %q\n\n%s", args[0], config.Usage())
+		os.Exit(2)
+	}
+	if err := cfg.ValidateServer(); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuración inválida:\n%v\n\n%s", err, config.Usage())
+		os.Exit(2)
+	}
+
+	// Todo lo que se registra desde acá sale en JSON, incluido lo que pase por el paquete log
+	slog.SetDefault(logging.New(os.Stderr, cfg.LogLevel))
+
+	// gin solo imprime rutas y avisos de desarrollo en modo debug
+	if cfg.LogLevel == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	if cfg.Session.Secret == "" {
+		slog.Warn("SESSION_SECRET sin definir: se genera una clave al iniciar y los formularios abiertos dejan de valer al reiniciar")
+	}
+
+	// Inicializar Base de Datos (aplica las migraciones pendientes)
+	db, err := database.InitDB(cfg.DBPath)
 	if err != nil {
-		log.Fatal(err)
+		fatal("abriendo la base", err)
 	}
-	defer db.Close()
-
-	// Inicializar Repositorio
-	mesaRepo := repository.NewMesaRepository(db)
-	paramsRepo := repository.NewParamsRepository(db)
-
-	// Inicializar Handlers
-	chatHandler := handlers.NewChatHandler(mesaRepo, paramsRepo)
-	authHandler := handlers.NewAuthHandler()
-	adminHandler := handlers.NewAdminHandler(mesaRepo, paramsRepo)
-
-	// Configurar Gin
-	r := gin.Default()
-	r.LoadHTMLGlob("templates/*")
-
-	// Rutas Públicas
-	r.GET("/", chatHandler.ShowChat)
-	r.GET("/ws", chatHandler.HandleWebSocket)
-
-	// Rutas de Autenticación
-	r.GET("/login", authHandler.ShowLogin)
-	r.POST("/do-login", authHandler.Login)
-	r.GET("/logout", authHandler.Logout)
-
-	// Rutas Protegidas (Admin)
-	adminGroup := r.Group("/admin")
-	adminGroup.Use(handlers.AuthMiddleware())
-	{
-		adminGroup.GET("", adminHandler.ShowDashboard)
-		adminGroup.GET("/config", adminHandler.ShowParams) // New config page
-		adminGroup.POST("/guardar", adminHandler.CreateMesa)
-		adminGroup.GET("/borrar/:id", adminHandler.DeleteMesa)
-		adminGroup.POST("/materias", adminHandler.StoreMateria)
-		adminGroup.POST("/carreras", adminHandler.StoreCarrera) // New carrera handler
-		adminGroup.POST("/sedes", adminHandler.StoreSede)
-		adminGroup.POST("/aulas", adminHandler.StoreAula)
-
-		adminGroup.GET("/api/aulas", adminHandler.GetAulas)
-
-		adminGroup.POST("/turnos", adminHandler.StoreTurnoConfig)
-		adminGroup.POST("/turnos/update/:id", adminHandler.UpdateTurnoConfig)
-		adminGroup.GET("/turnos/delete/:id", adminHandler.DeleteTurnoConfig)
-
-		// Generic Config CRUD
-		adminGroup.GET("/config/edit/:type/:id", adminHandler.ShowEditParam)
-		adminGroup.POST("/config/update/:type/:id", adminHandler.UpdateParam)
-		adminGroup.GET("/config/delete/:type/:id", adminHandler.DeleteParam)
+
+	// Las credenciales de la configuración solo crean el primer superadmin
+	userRepo := repository.NewUserRepository(db)
+	created, err := userRepo.EnsureBootstrapAdmin(cfg.Admin.Email, cfg.Admin.Password)
+	if err != nil {
+		slog.Warn("creando el superadmin inicial", "error", err)
+	} else if created {
+		slog.Info("superadmin inicial creado", "email", cfg.Admin.Email)
+	}
+
+	r, err := handlers.NewRouter(db, handlers.RouterConfig{
+		Templates: cfg.TemplatesGlob(),
+		Cookie: handlers.CookieConfig{
+			Secure:   cfg.Session.CookieSecure,
+			SameSite: cfg.SameSite(),
+			TTL:      cfg.Session.TTL,
+			Secret:   []byte(cfg.Session.Secret),
+		},
+		Chat: handlers.ChatConfig{
+			ResultsPause:   cfg.Chat.ResultsPause,
+			MaxTurno:       cfg.Chat.MaxTurno,
+			MessageLimit:   cfg.Chat.MessageLimit,
+			MessageWindow:  cfg.Chat.MessageWindow,
+			AllowedOrigins: cfg.WebSocket.AllowedOrigins,
+			MaxMessageSize: cfg.WebSocket.MaxMessageSize,
+			PingInterval:   cfg.WebSocket.PingInterval,
+			IdleTimeout:    cfg.WebSocket.IdleTimeout,
+			MaxConnsPerIP:  cfg.WebSocket.MaxConnsPerIP,
+		},
+		API:            handlers.APIConfig{CORSOrigins: cfg.API.CORSOrigins},
+		TrustedProxies: cfg.TrustedProxies,
+		RequestLog:     cfg.LogLevel == "debug" || cfg.LogLevel == "info",
+	})
+	if err != nil {
+		db.Close()
+		fatal("configurando el router", err)
+	}
+
+	// La base se cierra recién cuando no queda ningún request ni chat usándola
+	err = serve(&http.Server{Addr: cfg.Listen, Handler: r}, r, cfg.ShutdownTimeout)
+	db.Close()
+	if err != nil {
+		fatal("atendiendo", err)
+	}
+	slog.Info("servidor detenido")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// serve atiende hasta recibir SIGINT o SIGTERM y entonces apaga: deja de aceptar
+// conexiones y, a la vez, espera los requests en curso y cierra los chats abiertos, todo
+// dentro de timeout. Vuelve cuando terminaron ambos, así la base se cierra después. Una
+// segunda señal corta sin esperar.
+func serve(srv *http.Server, r *handlers.Router, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("escuchando", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
 	}
+	stop()
 
-	// Iniciar servidor
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal(err)
+	slog.Info("apagando", "chats_abiertos", r.Chat.Sessions.Len(), "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// srv.Shutdown no espera las conexiones WebSocket (quedan fuera del servidor al
+	// hacer el upgrade), así que los chats se cierran en paralelo y con el mismo plazo
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := r.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("chats cortados al apagar", "error", err)
+		}
+	}()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("requests cortados al apagar", "error", err)
 	}
+	wg.Wait()
+	return nil
 }
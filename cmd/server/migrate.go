@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"mi-bot-unne/internal/database"
+)
+
+const migrateUsage = `uso: server [flags] migrate <comando>
+
+  up          aplica las migraciones pendientes
+  down [n]    revierte las últimas n migraciones (1 por defecto)
+  status      lista las migraciones y si están aplicadas
+`
+
+// runMigrate implementa el subcomando "migrate" y devuelve el código de salida
+func runMigrate(dbPath string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "abriendo %s: %v\n", dbPath, err)
+		return 1
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		done, err := database.MigrateUp(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Println("No hay migraciones pendientes")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fmt.Fprintf(os.Stderr, "cantidad inválida: %q\n", args[1])
+				return 2
+			}
+		}
+		done, err := database.MigrateDown(db, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Println("No hay migraciones aplicadas")
+		}
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range states {
+			estado := "pendiente"
+			if s.Applied() {
+				estado = "aplicada " + s.AppliedAt
+			}
+			fmt.Printf("%04d_%-28s %s\n", s.Version, s.Name, estado)
+		}
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
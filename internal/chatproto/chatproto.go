@@ -0,0 +1,164 @@
+// Package chatproto define el protocolo del chat por WebSocket. El bot arma cada
+// respuesta como un Message y cada tipo de cliente la recibe en su formato: JSON para
+// las apps y otros frontends, HTML para chat.html. El formato se negocia con el
+// subprotocolo de WebSocket; sin subprotocolo se usa HTML, como antes del protocolo.
+package chatproto
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"mi-bot-unne/internal/models"
+)
+
+// Subprotocolos que acepta /ws. La versión va en el nombre: un cambio incompatible
+// en los mensajes es un subprotocolo nuevo.
+const (
+	JSON = "mibot.v1.json"
+	HTML = "mibot.v1.html"
+)
+
+// Subprotocols en orden de preferencia del servidor
+var Subprotocols = []string{JSON, HTML}
+
+// Tipos de mensaje del bot
+const (
+	TypeText           = "text"
+	TypeError          = "error"
+	TypeMenu           = "menu"
+	TypeOptions        = "options"
+	TypeSchedule       = "schedule_card"
+	TypeTurnos         = "turnos_card"
+	TypeDownloadPrompt = "download_prompt"
+	TypeDownload       = "download"
+)
+
+// Message es un mensaje del bot. Type dice qué campos trae; los demás van vacíos.
+type Message struct {
+	Type string `json:"type"`
+	// Code identifica el texto, para los clientes que quieran mostrarlo a su manera
+	Code string `json:"code,omitempty"`
+	Text string `json:"text,omitempty"`
+	// Emphasis son fragmentos de Text a destacar, en el orden en que aparecen
+	Emphasis []string `json:"emphasis,omitempty"`
+	// Carrera es la carrera elegida, en el menú
+	Carrera string `json:"carrera,omitempty"`
+	// Options son las opciones del menú, las carreras o las materias a elegir
+	Options  []Option    `json:"options,omitempty"`
+	Schedule *Schedule   `json:"schedule,omitempty"`
+	Turnos   *TurnosCard `json:"turnos,omitempty"`
+	// CardID es la tarjeta a guardar como imagen, en download_prompt y download
+	CardID string `json:"card_id,omitempty"`
+}
+
+// Option es algo que el alumno puede elegir; Value es lo que el cliente manda al elegirla
+type Option struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// Schedule son las mesas de una materia: todas, o la de un turno si Turno no está vacío
+type Schedule struct {
+	CardID      string `json:"card_id"`
+	Materia     string `json:"materia"`
+	Carrera     string `json:"carrera"`
+	Turno       string `json:"turno,omitempty"`
+	Mesas       []Mesa `json:"mesas"`
+	CalendarURL string `json:"calendar_url"`
+}
+
+// Mesa es una fecha de examen. Fecha y hora van en ISO; vacías si están a confirmar.
+type Mesa struct {
+	Turno string           `json:"turno"`
+	Fecha models.Date      `json:"fecha"`
+	Hora  models.TimeOfDay `json:"hora"`
+	Aula  string           `json:"aula"`
+	Sede  string           `json:"sede"`
+	// Actualizada es la última edición de la mesa
+	Actualizada string `json:"actualizada"`
+}
+
+// TurnosCard son los turnos de examen que faltan en el año
+type TurnosCard struct {
+	CardID string  `json:"card_id"`
+	Turnos []Turno `json:"turnos"`
+}
+
+type Turno struct {
+	Nombre string      `json:"nombre"`
+	Desde  models.Date `json:"desde"`
+	Hasta  models.Date `json:"hasta"`
+	Receso bool        `json:"receso"`
+}
+
+// Text es un mensaje de texto del bot
+func Text(code, text string, emphasis ...string) Message {
+	return Message{Type: TypeText, Code: code, Text: text, Emphasis: emphasis}
+}
+
+// Error es un aviso de algo que salió mal o que el alumno tiene que corregir
+func Error(code, text string, emphasis ...string) Message {
+	return Message{Type: TypeError, Code: code, Text: text, Emphasis: emphasis}
+}
+
+// NewMesa pasa una mesa de la base al protocolo
+func NewMesa(m models.Mesa) Mesa {
+	return Mesa{Turno: m.Turno, Fecha: m.Fecha, Hora: m.Hora, Aula: m.Aula, Sede: m.Sede, Actualizada: m.FechaEdicion}
+}
+
+// NewTurno pasa un turno de la base al protocolo
+func NewTurno(t models.TurnoConfig) Turno {
+	return Turno{Nombre: t.Nombre, Desde: t.FechaInicio, Hasta: t.FechaFin, Receso: t.Receso}
+}
+
+// Segment es un pedazo de Text, destacado o no
+type Segment struct {
+	Text   string
+	Strong bool
+}
+
+// Segments parte Text en los fragmentos de Emphasis y el resto. Un fragmento que no
+// aparece después del anterior se ignora.
+func (m Message) Segments() []Segment {
+	var out []Segment
+	rest := m.Text
+	for _, e := range m.Emphasis {
+		i := strings.Index(rest, e)
+		if e == "" || i < 0 {
+			continue
+		}
+		if i > 0 {
+			out = append(out, Segment{Text: rest[:i]})
+		}
+		out = append(out, Segment{Text: e, Strong: true})
+		rest = rest[i+len(e):]
+	}
+	if rest != "" {
+		out = append(out, Segment{Text: rest})
+	}
+	return out
+}
+
+// Input es lo que manda un cliente JSON: por ahora solo mensajes de texto
+type Input struct {
+	Type string `json:"type"` // "message"
+	Text string `json:"text"`
+}
+
+// ErrBadInput es un mensaje de un cliente JSON que no respeta el protocolo
+var ErrBadInput = errors.New(`se esperaba {"type":"message","text":"..."}`)
+
+// EncodeJSON arma el frame de un mensaje para los clientes JSON
+func EncodeJSON(m Message) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// DecodeJSON lee el texto de un mensaje de un cliente JSON
+func DecodeJSON(data []byte) (string, error) {
+	var in Input
+	if err := json.Unmarshal(data, &in); err != nil || in.Type != "message" {
+		return "", ErrBadInput
+	}
+	return in.Text, nil
+}
@@ -0,0 +1,62 @@
+package chatproto
+
+import (
+	"reflect"
+	"testing"
+
+	"mi-bot-unne/internal/models"
+)
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		emphasis []string
+		want     []Segment
+	}{
+		{"sin destacados", "Hola", nil, []Segment{{Text: "Hola"}}},
+		{"en el medio", "Buscando algebra...", []string{"algebra"}, []Segment{{Text: "Buscando "}, {Text: "algebra", Strong: true}, {Text: "..."}}},
+		{"en orden", "sí o no", []string{"sí", "no"}, []Segment{{Text: "sí", Strong: true}, {Text: " o "}, {Text: "no", Strong: true}}},
+		{"ausente", "Hola", []string{"chau"}, []Segment{{Text: "Hola"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text("", tt.text, tt.emphasis...).Segments(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	fecha, _ := models.ParseDate("2099-02-18")
+	hora, _ := models.ParseTimeOfDay("08:00")
+	frame, err := EncodeJSON(Message{Type: TypeSchedule, Schedule: &Schedule{
+		CardID: "card-1", Materia: "Álgebra I", Mesas: []Mesa{{Turno: "1° Turno", Fecha: fecha, Hora: hora}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"schedule_card","schedule":{"card_id":"card-1","materia":"Álgebra I","carrera":"","mesas":[{"turno":"1° Turno","fecha":"2099-02-18","hora":"08:00","aula":"","sede":"","actualizada":""}],"calendar_url":""}}`
+	if string(frame) != want {
+		t.Errorf("got  %s\nwant %s", frame, want)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{`{"type":"message","text":"algebra"}`, "algebra", false},
+		{`{"type":"otro","text":"algebra"}`, "", true},
+		{`algebra`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := DecodeJSON([]byte(tt.in))
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("DecodeJSON(%s) = %q, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package chatview es el cliente HTML del protocolo del chat: arma el fragmento que
+// chat.html agrega a la conversación por cada chatproto.Message. Cada tipo de mensaje
+// es una plantilla con nombre en templates/, así que todo lo que viene de la base o
+// del alumno se escapa y los estilos quedan en las clases de chat.html.
+package chatview
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"mi-bot-unne/internal/chatproto"
+	"mi-bot-unne/internal/display"
+)
+
+//go:embed templates/*.html
+var files embed.FS
+
+var templates = template.Must(template.New("").Funcs(display.FuncMap()).Funcs(template.FuncMap{
+	// br escapa s y respeta sus saltos de línea
+	"br": func(s string) template.HTML {
+		return template.HTML(strings.ReplaceAll(template.HTMLEscapeString(s), "\n", "<br>"))
+	},
+}).ParseFS(files, "templates/*.html"))
+
+// Render ejecuta la plantilla name con data
+func Render(name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Encode arma el frame HTML de un mensaje para chat.html
+func Encode(m chatproto.Message) ([]byte, error) {
+	var (
+		name string
+		data any = m
+	)
+	switch m.Type {
+	case chatproto.TypeText, chatproto.TypeError, chatproto.TypeDownloadPrompt:
+		name = "texto"
+	case chatproto.TypeDownload:
+		name = "descarga"
+	case chatproto.TypeMenu:
+		name = "menu"
+	case chatproto.TypeOptions:
+		name = "opciones"
+		if m.Code == "carreras" {
+			name = "carreras"
+		}
+	case chatproto.TypeSchedule:
+		name, data = "cronograma", m.Schedule
+		if m.Schedule.Turno != "" {
+			name = "turno"
+		}
+	case chatproto.TypeTurnos:
+		name, data = "turnos", m.Turnos
+	default:
+		return nil, fmt.Errorf("tipo de mensaje desconocido: %q", m.Type)
+	}
+	html, err := Render(name, data)
+	return []byte(html), err
+}
@@ -0,0 +1,78 @@
+package chatview
+
+import (
+	"strings"
+	"testing"
+
+	"mi-bot-unne/internal/chatproto"
+	"mi-bot-unne/internal/models"
+)
+
+const xss = `<script>alert('x')</script>"`
+
+func TestEncodeEscapes(t *testing.T) {
+	fecha, _ := models.ParseDate("2099-02-18")
+	mesa := chatproto.Mesa{Turno: xss, Aula: xss, Sede: xss, Fecha: fecha}
+	schedule := &chatproto.Schedule{CardID: xss, Materia: xss, Carrera: xss, Mesas: []chatproto.Mesa{mesa}, CalendarURL: "/cal/materia/x.ics"}
+	options := []chatproto.Option{{Value: xss, Label: xss}}
+	tests := []struct {
+		name string
+		msg  chatproto.Message
+	}{
+		{"text", chatproto.Text("searching", "Buscando "+xss, xss)},
+		{"error", chatproto.Error("bad_request", xss)},
+		{"download", chatproto.Message{Type: chatproto.TypeDownload, CardID: xss, Text: xss}},
+		{"menu", chatproto.Message{Type: chatproto.TypeMenu, Text: "Menú", Carrera: xss, Options: options}},
+		{"carreras", chatproto.Message{Type: chatproto.TypeOptions, Code: "carreras", Text: xss, Options: options}},
+		{"materias", chatproto.Message{Type: chatproto.TypeOptions, Text: xss, Options: options}},
+		{"cronograma", chatproto.Message{Type: chatproto.TypeSchedule, Schedule: schedule}},
+		{"turno", chatproto.Message{Type: chatproto.TypeSchedule, Schedule: &chatproto.Schedule{CardID: xss, Materia: xss, Turno: xss, Mesas: []chatproto.Mesa{mesa}}}},
+		{"turnos", chatproto.Message{Type: chatproto.TypeTurnos, Turnos: &chatproto.TurnosCard{CardID: xss, Turnos: []chatproto.Turno{{Nombre: xss}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := Encode(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := string(frame)
+			if strings.Contains(html, "<script>") || strings.Contains(html, `x')`) {
+				t.Errorf("sin escapar:\n%s", html)
+			}
+			if !strings.Contains(html, "&lt;script&gt;") {
+				t.Errorf("no muestra el dato escapado:\n%s", html)
+			}
+		})
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	frame, err := Encode(chatproto.Text("help", "Ayuda:\n• 1 o 2 para elegir", "Ayuda:", "1", "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<p><strong>Ayuda:</strong><br>• <strong>1</strong> o <strong>2</strong> para elegir</p>"
+	if !strings.Contains(string(frame), want) {
+		t.Errorf("got\n%s\nwant %s", frame, want)
+	}
+
+	if _, err := Encode(chatproto.Message{Type: "desconocido"}); err == nil {
+		t.Error("un tipo desconocido no da error")
+	}
+}
+
+func TestEncodeCalendarURL(t *testing.T) {
+	card := func(url string) string {
+		frame, err := Encode(chatproto.Message{Type: chatproto.TypeSchedule, Schedule: &chatproto.Schedule{CardID: "card-1", CalendarURL: url}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(frame)
+	}
+	if html := card("javascript:alert(1)"); strings.Contains(html, "javascript:") {
+		t.Errorf("el enlace acepta javascript:\n%s", html)
+	}
+	if html := card("/cal/materia/%C3%81lgebra%20I.ics?turno=1%C2%B0+Turno"); !strings.Contains(html, `href="/cal/materia/%C3%81lgebra%20I.ics?turno=1%C2%B0&#43;Turno"`) {
+		t.Errorf("cambió el enlace al calendario:\n%s", html)
+	}
+}
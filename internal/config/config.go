@@ -0,0 +1,377 @@
+// Package config reúne la configuración del servidor. Cada valor sale, de menor a
+// mayor prioridad, de los valores por defecto, del archivo YAML, de las variables
+// de entorno y de los flags de la línea de comandos.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+type Config struct {
+	Listen    string `yaml:"listen"`
+	DBPath    string `yaml:"db_path"`
+	Templates string `yaml:"templates"` // Carpeta con las plantillas .html
+	// LogLevel es debug, info, warn o error. Solo en debug gin corre en modo desarrollo.
+	LogLevel string `yaml:"log_level"`
+	// TrustedProxies son las IPs o CIDRs cuyo X-Forwarded-For se usa como IP del cliente
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// ShutdownTimeout es cuánto se espera, al recibir SIGTERM, a que terminen los requests
+	// en curso y se cierren los chats antes de cortarlos
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	Session   Session   `yaml:"session"`
+	WebSocket WebSocket `yaml:"websocket"`
+	Chat      Chat      `yaml:"chat"`
+	API       API       `yaml:"api"`
+	Admin     Admin     `yaml:"admin"`
+}
+
+type Session struct {
+	// Secret firma los tokens CSRF. Vacío se genera uno al iniciar, y los formularios
+	// abiertos antes de un reinicio dejan de ser válidos.
+	Secret         string        `yaml:"secret"`
+	TTL            time.Duration `yaml:"ttl"`
+	CookieSecure   bool          `yaml:"cookie_secure"` // obligatorio si se sirve por HTTPS
+	CookieSameSite string        `yaml:"cookie_samesite"`
+}
+
+type WebSocket struct {
+	// AllowedOrigins son los orígenes (https://host[:puerto]) que pueden abrir el chat.
+	// Vacío acepta solo el mismo host que sirve la página; "*" acepta cualquiera.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	MaxMessageSize int64    `yaml:"max_message_size"` // bytes por mensaje del alumno
+	// PingInterval es cada cuánto se manda un ping; sin respuesta a dos se corta
+	PingInterval  time.Duration `yaml:"ping_interval"`
+	IdleTimeout   time.Duration `yaml:"idle_timeout"`     // sin mensajes del alumno se cierra el chat
+	MaxConnsPerIP int           `yaml:"max_conns_per_ip"` // chats abiertos a la vez desde una IP
+}
+
+type Chat struct {
+	ResultsPause  time.Duration `yaml:"results_pause"` // espera antes de ofrecer la descarga
+	MaxTurno      int           `yaml:"max_turno"`     // los turnos se piden del 1 a MaxTurno
+	MessageLimit  int           `yaml:"message_limit"` // mensajes por IP en cada MessageWindow
+	MessageWindow time.Duration `yaml:"message_window"`
+}
+
+type API struct {
+	// CORSOrigins son los orígenes que pueden llamar a /api/v1 desde el navegador.
+	// Vacío no habilita CORS; "*" acepta cualquiera.
+	CORSOrigins []string `yaml:"cors_origins"`
+}
+
+// Admin son las credenciales del primer superadmin. Solo se usan si la base no tiene
+// ningún usuario; después se ignoran y las cuentas se administran desde el panel.
+type Admin struct {
+	Email    string `yaml:"email"`
+	Password string `yaml:"password"`
+}
+
+// Default devuelve la configuración con la que corría el servidor antes de ser configurable
+func Default() Config {
+	return Config{
+		Listen:    ":8080",
+		DBPath:    "./data/mesas.db",
+		Templates: "templates",
+		LogLevel:  "info",
+		// docker stop espera 10s antes de matar el proceso
+		ShutdownTimeout: 8 * time.Second,
+		Session: Session{
+			TTL:            time.Hour,
+			CookieSameSite: "lax",
+		},
+		WebSocket: WebSocket{
+			MaxMessageSize: 1024,
+			PingInterval:   30 * time.Second,
+			IdleTimeout:    15 * time.Minute,
+			// Un laboratorio entero puede salir por la misma IP
+			MaxConnsPerIP: 10,
+		},
+		Chat: Chat{
+			ResultsPause:  600 * time.Millisecond,
+			MaxTurno:      10,
+			MessageLimit:  20,
+			MessageWindow: 10 * time.Second,
+		},
+	}
+}
+
+// setting describe un valor que se puede pisar por entorno y por flag
+type setting struct {
+	env, flag, usage string
+	set              func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"LISTEN_ADDR", "listen", "dirección en la que escucha el servidor", func(c *Config, v string) error { c.Listen = v; return nil }},
+	{"DB_PATH", "db", "ruta de la base SQLite", func(c *Config, v string) error { c.DBPath = v; return nil }},
+	{"TEMPLATES_DIR", "templates", "carpeta de las plantillas HTML", func(c *Config, v string) error { c.Templates = v; return nil }},
+	{"LOG_LEVEL", "log-level", "debug, info, warn o error", func(c *Config, v string) error { c.LogLevel = strings.ToLower(v); return nil }},
+	{"TRUSTED_PROXIES", "trusted-proxies", "proxies de confianza, separados por coma", func(c *Config, v string) error { c.TrustedProxies = splitList(v); return nil }},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "espera máxima al apagar el servidor", durationSetter(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"SESSION_SECRET", "session-secret", "clave para los tokens CSRF (32 caracteres o más)", func(c *Config, v string) error { c.Session.Secret = v; return nil }},
+	{"SESSION_TTL", "session-ttl", "duración de la sesión del panel, ej. 8h", durationSetter(func(c *Config) *time.Duration { return &c.Session.TTL })},
+	{"COOKIE_SECURE", "cookie-secure", "marca la cookie de sesión como Secure (true/false)", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.Session.CookieSecure = b
+		return err
+	}},
+	{"COOKIE_SAMESITE", "cookie-samesite", "lax, strict o none", func(c *Config, v string) error { c.Session.CookieSameSite = strings.ToLower(v); return nil }},
+	{"WS_ALLOWED_ORIGINS", "ws-origins", "orígenes que pueden abrir el chat, separados por coma", func(c *Config, v string) error { c.WebSocket.AllowedOrigins = splitList(v); return nil }},
+	{"WS_MAX_MESSAGE_SIZE", "ws-max-message-size", "bytes máximos de un mensaje del chat", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		c.WebSocket.MaxMessageSize = n
+		return err
+	}},
+	{"WS_PING_INTERVAL", "ws-ping-interval", "cada cuánto se manda un ping al chat", durationSetter(func(c *Config) *time.Duration { return &c.WebSocket.PingInterval })},
+	{"WS_IDLE_TIMEOUT", "ws-idle-timeout", "inactividad tras la que se cierra el chat", durationSetter(func(c *Config) *time.Duration { return &c.WebSocket.IdleTimeout })},
+	{"WS_MAX_CONNS_PER_IP", "ws-max-conns-per-ip", "chats abiertos a la vez desde una IP", intSetter(func(c *Config) *int { return &c.WebSocket.MaxConnsPerIP })},
+	{"CHAT_RESULTS_PAUSE", "chat-results-pause", "espera antes de ofrecer la descarga", durationSetter(func(c *Config) *time.Duration { return &c.Chat.ResultsPause })},
+	{"CHAT_MAX_TURNO", "chat-max-turno", "último número de turno que acepta el chat", intSetter(func(c *Config) *int { return &c.Chat.MaxTurno })},
+	{"CHAT_MESSAGE_LIMIT", "chat-message-limit", "mensajes por IP en cada ventana", intSetter(func(c *Config) *int { return &c.Chat.MessageLimit })},
+	{"CHAT_MESSAGE_WINDOW", "chat-message-window", "ventana del límite de mensajes", durationSetter(func(c *Config) *time.Duration { return &c.Chat.MessageWindow })},
+	{"API_CORS_ORIGINS", "api-cors-origins", "orígenes que pueden usar la API desde el navegador, separados por coma", func(c *Config, v string) error { c.API.CORSOrigins = splitList(v); return nil }},
+	{"ADMIN_EMAIL", "admin-email", "email del primer superadmin, si la base no tiene usuarios", func(c *Config, v string) error { c.Admin.Email = v; return nil }},
+	{"ADMIN_PASSWORD", "admin-password", "contraseña del primer superadmin", func(c *Config, v string) error { c.Admin.Password = v; return nil }},
+}
+
+func durationSetter(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		*field(c) = d
+		return err
+	}
+}
+
+func intSetter(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		*field(c) = n
+		return err
+	}
+}
+
+// Load arma la configuración a partir de args (sin el nombre del programa) y del
+// entorno, y la valida. Devuelve también los argumentos que quedan después de los
+// flags, para subcomandos como "migrate".
+func Load(args []string, getenv func(string) string) (Config, []string, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	file := fs.String("config", "", "archivo de configuración YAML (también CONFIG_FILE)")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.flag] = fs.String(s.flag, "", s.usage+" ("+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg := Default()
+
+	path := *file
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(&cfg, v); err != nil {
+				return Config{}, nil, fmt.Errorf("%s inválido: %q", s.env, v)
+			}
+		}
+	}
+
+	// Solo los flags presentes pisan lo anterior
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && err == nil {
+				if setErr := s.set(&cfg, *flagValues[s.flag]); setErr != nil {
+					err = fmt.Errorf("-%s inválido: %q", s.flag, *flagValues[s.flag])
+				}
+			}
+		}
+	})
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// Usage describe los flags y variables de entorno aceptados
+func Usage() string {
+	var b strings.Builder
+	b.WriteString("uso: server [flags] [migrate <comando>]\n\n")
+	fmt.Fprintf(&b, "  -%-22s %s\n", "config", "archivo de configuración YAML (CONFIG_FILE)")
+	for _, s := range settings {
+		fmt.Fprintf(&b, "  -%-22s %s (%s)\n", s.flag, s.usage, s.env)
+	}
+	return b.String()
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("leyendo la configuración: %w", err)
+	}
+	// Una clave mal escrita es un error, no un valor que se ignora en silencio
+	if err := yaml.UnmarshalWithOptions(data, c, yaml.DisallowUnknownField()); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+var logLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+// Validate revisa todos los valores y devuelve juntos los problemas que encuentra. La
+// carpeta de plantillas se revisa aparte, en ValidateServer, porque "migrate" no la usa.
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, port, err := net.SplitHostPort(c.Listen); err != nil || port == "" {
+		fail("listen: dirección inválida %q (ej. :8080)", c.Listen)
+	}
+	if c.DBPath == "" {
+		fail("db_path: no puede estar vacío")
+	}
+	if !logLevels[c.LogLevel] {
+		fail("log_level: %q no es debug, info, warn ni error", c.LogLevel)
+	}
+	for _, p := range c.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				fail("trusted_proxies: %q no es una IP ni un CIDR", p)
+			}
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		fail("shutdown_timeout: debe ser mayor que cero")
+	}
+
+	if c.Session.Secret != "" && len(c.Session.Secret) < 32 {
+		fail("session.secret: debe tener al menos 32 caracteres")
+	}
+	if c.Session.TTL <= 0 {
+		fail("session.ttl: debe ser mayor que cero")
+	}
+	switch c.Session.CookieSameSite {
+	case "lax", "strict":
+	case "none":
+		// Los navegadores rechazan SameSite=None sin Secure
+		if !c.Session.CookieSecure {
+			fail("session.cookie_samesite: none requiere cookie_secure")
+		}
+	default:
+		fail("session.cookie_samesite: %q no es lax, strict ni none", c.Session.CookieSameSite)
+	}
+
+	for _, o := range c.WebSocket.AllowedOrigins {
+		if !validOrigin(o) {
+			fail("websocket.allowed_origins: %q no es un origen como https://mesas.unne.edu.ar", o)
+		}
+	}
+
+	if c.WebSocket.MaxMessageSize < 64 || c.WebSocket.MaxMessageSize > 64<<10 {
+		fail("websocket.max_message_size: debe estar entre 64 y 65536 bytes")
+	}
+	if c.WebSocket.PingInterval < time.Second {
+		fail("websocket.ping_interval: debe ser de al menos 1s")
+	}
+	if c.WebSocket.IdleTimeout < c.WebSocket.PingInterval {
+		fail("websocket.idle_timeout: no puede ser menor que ping_interval")
+	}
+	if c.WebSocket.MaxConnsPerIP < 1 {
+		fail("websocket.max_conns_per_ip: debe ser al menos 1")
+	}
+
+	if c.Chat.ResultsPause <= 0 || c.Chat.ResultsPause > 10*time.Second {
+		fail("chat.results_pause: debe ser mayor que cero y de hasta 10s")
+	}
+	if c.Chat.MaxTurno < 1 || c.Chat.MaxTurno > 99 {
+		fail("chat.max_turno: debe estar entre 1 y 99")
+	}
+	if c.Chat.MessageLimit < 1 {
+		fail("chat.message_limit: debe ser al menos 1")
+	}
+	if c.Chat.MessageWindow <= 0 {
+		fail("chat.message_window: debe ser mayor que cero")
+	}
+
+	for _, o := range c.API.CORSOrigins {
+		if !validOrigin(o) {
+			fail("api.cors_origins: %q no es un origen como https://mesas.unne.edu.ar", o)
+		}
+	}
+
+	if (c.Admin.Email == "") != (c.Admin.Password == "") {
+		fail("admin: email y password se definen juntos")
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateServer revisa lo que solo hace falta para levantar el servidor HTTP
+func (c Config) ValidateServer() error {
+	if info, err := os.Stat(c.Templates); err != nil || !info.IsDir() {
+		return fmt.Errorf("templates: %q no es una carpeta", c.Templates)
+	}
+	return nil
+}
+
+// validOrigin acepta "*" o un esquema http(s) con host y sin ruta
+func validOrigin(o string) bool {
+	if o == "*" {
+		return true
+	}
+	u, err := url.Parse(o)
+	return err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https") && strings.Trim(u.Path, "/") == ""
+}
+
+// TemplatesGlob es el patrón que carga gin
+func (c Config) TemplatesGlob() string {
+	return filepath.Join(c.Templates, "*")
+}
+
+func (c Config) SameSite() http.SameSite {
+	switch c.Session.CookieSameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			items = append(items, p)
+		}
+	}
+	return items
+}
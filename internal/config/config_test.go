@@ -0,0 +1,249 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env arma un getenv a partir de un mapa
+func env(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+// chdirRepo deja el directorio de trabajo en la raíz del repo, donde está templates/
+func chdirRepo(t *testing.T) {
+	t.Helper()
+	t.Chdir("../..")
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	chdirRepo(t)
+	cfg, args, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":8080" || cfg.DBPath != "./data/mesas.db" || cfg.TemplatesGlob() != "templates/*" {
+		t.Errorf("defaults = %+v", cfg)
+	}
+	if cfg.Chat.ResultsPause != 600*time.Millisecond || cfg.Chat.MaxTurno != 10 {
+		t.Errorf("chat = %+v", cfg.Chat)
+	}
+	if len(args) != 0 {
+		t.Errorf("args = %q", args)
+	}
+	if err := cfg.ValidateServer(); err != nil {
+		t.Errorf("ValidateServer: %v", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	chdirRepo(t)
+	file := writeFile(t, `
+listen: ":9000"
+db_path: /var/lib/mesas/archivo.db
+log_level: warn
+session:
+  ttl: 8h
+  cookie_secure: true
+  cookie_samesite: none
+websocket:
+  allowed_origins: [https://mesas.unne.edu.ar]
+  idle_timeout: 5m
+chat:
+  results_pause: 1s
+  max_turno: 12
+api:
+  cors_origins: ["*"]
+admin:
+  email: archivo@unne.edu.ar
+  password: desde-el-archivo
+`)
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		check func(t *testing.T, c Config)
+	}{
+		{"archivo", []string{"-config", file}, nil, func(t *testing.T, c Config) {
+			if c.Listen != ":9000" || c.DBPath != "/var/lib/mesas/archivo.db" || c.LogLevel != "warn" {
+				t.Errorf("config = %+v", c)
+			}
+			if c.Session.TTL != 8*time.Hour || !c.Session.CookieSecure || c.SameSite() != http.SameSiteNoneMode {
+				t.Errorf("session = %+v", c.Session)
+			}
+			if c.Chat.ResultsPause != time.Second || c.Chat.MaxTurno != 12 || c.Chat.MessageLimit != 20 {
+				t.Errorf("chat = %+v", c.Chat)
+			}
+			if c.WebSocket.IdleTimeout != 5*time.Minute || c.WebSocket.MaxConnsPerIP != 10 {
+				t.Errorf("websocket = %+v", c.WebSocket)
+			}
+			if !slices.Equal(c.API.CORSOrigins, []string{"*"}) {
+				t.Errorf("api = %+v", c.API)
+			}
+			if c.Admin.Email != "archivo@unne.edu.ar" || c.Admin.Password != "desde-el-archivo" {
+				t.Errorf("admin = %+v", c.Admin)
+			}
+		}},
+		{"archivo por CONFIG_FILE", nil, map[string]string{"CONFIG_FILE": file}, func(t *testing.T, c Config) {
+			if c.Listen != ":9000" {
+				t.Errorf("listen = %q", c.Listen)
+			}
+		}},
+		{"el entorno pisa el archivo", []string{"-config", file}, map[string]string{"DB_PATH": "env.db", "CHAT_MAX_TURNO": "8", "WS_MAX_CONNS_PER_IP": "3", "WS_ALLOWED_ORIGINS": "https://a.com, https://b.com", "ADMIN_EMAIL": "env@unne.edu.ar", "ADMIN_PASSWORD": "desde-el-entorno"}, func(t *testing.T, c Config) {
+			if c.DBPath != "env.db" || c.Chat.MaxTurno != 8 || c.WebSocket.MaxConnsPerIP != 3 || c.Listen != ":9000" {
+				t.Errorf("config = %+v", c)
+			}
+			if c.Admin.Email != "env@unne.edu.ar" || c.Admin.Password != "desde-el-entorno" {
+				t.Errorf("admin = %+v", c.Admin)
+			}
+			if !slices.Equal(c.WebSocket.AllowedOrigins, []string{"https://a.com", "https://b.com"}) {
+				t.Errorf("origins = %q", c.WebSocket.AllowedOrigins)
+			}
+		}},
+		{"los flags pisan el entorno", []string{"-config", file, "-db", "flag.db", "-chat-results-pause", "50ms", "-api-cors-origins", "https://app.unne.edu.ar", "-admin-email", "flag@unne.edu.ar"}, map[string]string{"DB_PATH": "env.db", "API_CORS_ORIGINS": "https://a.com", "ADMIN_EMAIL": "env@unne.edu.ar", "ADMIN_PASSWORD": "desde-el-entorno"}, func(t *testing.T, c Config) {
+			if c.DBPath != "flag.db" || c.Chat.ResultsPause != 50*time.Millisecond {
+				t.Errorf("config = %+v", c)
+			}
+			if c.Admin.Email != "flag@unne.edu.ar" || c.Admin.Password != "desde-el-entorno" {
+				t.Errorf("admin = %+v", c.Admin)
+			}
+			if !slices.Equal(c.API.CORSOrigins, []string{"https://app.unne.edu.ar"}) {
+				t.Errorf("cors = %q", c.API.CORSOrigins)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := Load(tt.args, env(tt.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadLeavesSubcommand(t *testing.T) {
+	chdirRepo(t)
+	_, args, err := Load([]string{"-db", "otra.db", "migrate", "down", "2"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(args, []string{"migrate", "down", "2"}) {
+		t.Errorf("args = %q", args)
+	}
+}
+
+// migrate no sirve páginas: arranca aunque la carpeta de plantillas no exista
+func TestLoadWithoutTemplates(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg, args, err := Load([]string{"migrate", "status"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(args, []string{"migrate", "status"}) {
+		t.Errorf("args = %q", args)
+	}
+	if err := cfg.ValidateServer(); err == nil || !strings.Contains(err.Error(), "templates") {
+		t.Errorf("ValidateServer: err = %v, want templates", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	chdirRepo(t)
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		wantErr string
+	}{
+		{"flag desconocido", []string{"-puerto", "80"}, nil, "", "-puerto"},
+		{"duración mal escrita en el entorno", nil, map[string]string{"SESSION_TTL": "una hora"}, "", "SESSION_TTL inválido"},
+		{"booleano inválido", []string{"-cookie-secure=quizas"}, nil, "", "-cookie-secure inválido"},
+		{"clave desconocida en el archivo", nil, nil, "lisen: ':80'\n", "lisen"},
+		{"archivo inexistente", []string{"-config", "/no/existe.yaml"}, nil, "", "leyendo la configuración"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "-config", writeFile(t, tt.file))
+			}
+			_, _, err := Load(args, env(tt.env))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, _, err := Load([]string{"-h"}, env(nil)); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h: err = %v, want flag.ErrHelp", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	chdirRepo(t)
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{"listen sin puerto", func(c *Config) { c.Listen = "localhost" }, "listen"},
+		{"db vacía", func(c *Config) { c.DBPath = "" }, "db_path"},
+		{"nivel de log", func(c *Config) { c.LogLevel = "verbose" }, "log_level"},
+		{"proxy inválido", func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/33"} }, "trusted_proxies"},
+		{"secreto corto", func(c *Config) { c.Session.Secret = "corto" }, "session.secret"},
+		{"shutdown en cero", func(c *Config) { c.ShutdownTimeout = 0 }, "shutdown_timeout"},
+		{"ttl en cero", func(c *Config) { c.Session.TTL = 0 }, "session.ttl"},
+		{"samesite desconocido", func(c *Config) { c.Session.CookieSameSite = "relax" }, "cookie_samesite"},
+		{"samesite none sin secure", func(c *Config) { c.Session.CookieSameSite = "none" }, "requiere cookie_secure"},
+		{"origen con ruta", func(c *Config) { c.WebSocket.AllowedOrigins = []string{"https://unne.edu.ar/chat"} }, "allowed_origins"},
+		{"origen sin esquema", func(c *Config) { c.WebSocket.AllowedOrigins = []string{"unne.edu.ar"} }, "allowed_origins"},
+		{"origen de la API", func(c *Config) { c.API.CORSOrigins = []string{"app.unne.edu.ar"} }, "api.cors_origins"},
+		{"mensaje chico", func(c *Config) { c.WebSocket.MaxMessageSize = 10 }, "max_message_size"},
+		{"ping muy seguido", func(c *Config) { c.WebSocket.PingInterval = time.Millisecond }, "ping_interval"},
+		{"inactividad menor que el ping", func(c *Config) { c.WebSocket.IdleTimeout = 10 * time.Second }, "idle_timeout"},
+		{"conexiones por IP", func(c *Config) { c.WebSocket.MaxConnsPerIP = 0 }, "max_conns_per_ip"},
+		{"pausa excesiva", func(c *Config) { c.Chat.ResultsPause = time.Minute }, "results_pause"},
+		{"max turno", func(c *Config) { c.Chat.MaxTurno = 0 }, "max_turno"},
+		{"límite de mensajes", func(c *Config) { c.Chat.MessageLimit = 0 }, "message_limit"},
+		{"ventana de mensajes", func(c *Config) { c.Chat.MessageWindow = 0 }, "message_window"},
+		{"admin sin contraseña", func(c *Config) { c.Admin.Email = "admin@unne.edu.ar" }, "admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Todos los problemas se informan juntos
+	cfg := Default()
+	cfg.Listen, cfg.LogLevel = "", "x"
+	cfg.WebSocket.AllowedOrigins = []string{"*", "https://mesas.unne.edu.ar"}
+	if err := cfg.Validate(); err == nil || strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("err = %v, want dos errores", err)
+	}
+}
@@ -2,58 +2,49 @@ package database
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitDB inicializa la conexión a la base de datos y crea la tabla si no existe
-func InitDB(dataSourceName string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dataSourceName)
+// Open abre la conexión a la base de datos sin tocar el esquema. SQLite no aplica las
+// FOREIGN KEY si no se le pide en cada conexión, así que se agrega al DSN.
+func Open(dataSourceName string) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(dataSourceName, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite3", dataSourceName+sep+"_foreign_keys=on")
 	if err != nil {
 		return nil, err
 	}
 
+	// Cada conexión a ":memory:" abre una base vacía distinta: con una sola conexión
+	// las migraciones y las consultas ven la misma (es lo que usan los tests)
+	if strings.Contains(dataSourceName, ":memory:") || strings.Contains(dataSourceName, "mode=memory") {
+		db.SetMaxOpenConns(1)
+	}
+
 	if err = db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
+	return db, nil
+}
 
-	// Crear tabla mesas si no existe
-	sqlStmt := `
-	CREATE TABLE IF NOT EXISTS mesas (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		materia TEXT,
-		turno TEXT,
-		fecha TEXT,
-		hora TEXT,
-		aula TEXT,
-		carrera TEXT
-	);
-	CREATE TABLE IF NOT EXISTS sedes (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		nombre TEXT
-	);
-	CREATE TABLE IF NOT EXISTS aulas (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		nombre TEXT,
-		sede_id INTEGER,
-		FOREIGN KEY(sede_id) REFERENCES sedes(id)
-	);
-	CREATE TABLE IF NOT EXISTS carreras (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		nombre TEXT
-	);
-	CREATE TABLE IF NOT EXISTS materias (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		nombre TEXT
-	);
-	`
-	_, err = db.Exec(sqlStmt)
+// InitDB abre la base de datos y aplica las migraciones pendientes
+func InitDB(dataSourceName string) (*sql.DB, error) {
+	db, err := Open(dataSourceName)
 	if err != nil {
-		log.Printf("%q: %s\n", err, sqlStmt)
 		return nil, err
 	}
 
+	if _, err := MigrateUp(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("aplicando migraciones: %w", err)
+	}
+
 	// Seed data (Simple check to see if we need to seed)
 	seedData(db)
 
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// newTestDB crea una base en memoria con todas las migraciones y los datos de ejemplo
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestForeignKeysEnforced(t *testing.T) {
+	db := newTestDB(t)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"mesa con materia inexistente", "INSERT INTO mesas (materia_id, carrera_id) VALUES (999, 1)"},
+		{"mesa con turno inexistente", "INSERT INTO mesas (materia_id, carrera_id, turno_id) VALUES (1, 1, 999)"},
+		{"aula en sede inexistente", "INSERT INTO aulas (nombre, sede_id) VALUES ('Aula 9', 0)"},
+		{"borrar una carrera con mesas", "INSERT INTO mesas (materia_id, carrera_id) VALUES (1, 2); DELETE FROM carreras WHERE id = 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.Exec(tt.query); err == nil {
+				t.Errorf("se aceptó %q", tt.query)
+			}
+		})
+	}
+
+	var sede sql.NullInt64
+	if err := db.QueryRow("SELECT sede_id FROM aulas WHERE nombre = 'Sin definir'").Scan(&sede); err != nil || sede.Valid {
+		t.Errorf("sede del aula Sin definir = %v (err %v), want NULL", sede, err)
+	}
+}
+
+func TestIntegridadReferencialRepairsLegacyRows(t *testing.T) {
+	db := newTestDB(t)
+
+	// Vuelve a como estaba una base de antes: "sin sede" en 0 y referencias a filas borradas
+	if _, err := MigrateDown(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	_, err := db.Exec(`
+		PRAGMA foreign_keys = OFF;
+		INSERT INTO mesas (id, materia_id, carrera_id, turno_id, aula_id) VALUES (1, 1, 1, 99, 99);
+		INSERT INTO plan_materias (carrera_id, materia_id) VALUES (99, 1);
+		PRAGMA foreign_keys = ON;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var turno, aula sql.NullInt64
+	if err := db.QueryRow("SELECT turno_id, aula_id FROM mesas WHERE id = 1").Scan(&turno, &aula); err != nil {
+		t.Fatal(err)
+	}
+	if turno.Valid || aula.Valid {
+		t.Errorf("mesa: turno_id %v, aula_id %v, want NULL", turno, aula)
+	}
+	var sinSede, plan, violations int
+	db.QueryRow("SELECT COUNT(*) FROM aulas WHERE sede_id = 0").Scan(&sinSede)
+	db.QueryRow("SELECT COUNT(*) FROM plan_materias WHERE carrera_id = 99").Scan(&plan)
+	db.QueryRow("SELECT COUNT(*) FROM pragma_foreign_key_check").Scan(&violations)
+	if sinSede != 0 || plan != 0 || violations != 0 {
+		t.Errorf("aulas con sede 0 = %d, plan huérfano = %d, violaciones = %d", sinSede, plan, violations)
+	}
+}
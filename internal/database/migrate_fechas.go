@@ -0,0 +1,220 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Las fechas se guardan como YYYY-MM-DD y las horas como HH:MM. Los CHECK
+// impiden que vuelva a entrar texto libre; '+0 days' hace que date() normalice
+// días inexistentes ('2025-02-30' pasa a '2025-03-02' y no coincide).
+const (
+	fechaCheck = "CHECK (%[1]s IS NULL OR %[1]s IS date(%[1]s, '+0 days'))"
+	horaCheck  = "CHECK (%[1]s IS NULL OR %[1]s IS strftime('%%H:%%M', %[1]s))"
+)
+
+// Formatos que aparecían en las bases anteriores: ISO desde el <input type="date">
+// y DD/MM/AAAA cargado a mano o por la primera versión del panel.
+var (
+	legacyFechaLayouts = []string{"2006-01-02", "02/01/2006", "2/1/2006", "02-01-2006", "02/01/06"}
+	legacyHoraLayouts  = []string{"15:04", "15:04:05", "15.04", "15"}
+)
+
+// migrateFechasTipadas normaliza fecha y hora de mesas y turnos y reconstruye
+// las tablas con los CHECK. Lo que no se puede interpretar queda en NULL y,
+// para las mesas, se informa en mesas_sin_mapear para corregirlo desde el panel.
+func migrateFechasTipadas(tx *sql.Tx) error {
+	if err := backfillMesasFechas(tx); err != nil {
+		return err
+	}
+	if err := backfillTurnosFechas(tx); err != nil {
+		return err
+	}
+	if err := rebuildMesas(tx, true); err != nil {
+		return err
+	}
+	return rebuildTurnos(tx, true)
+}
+
+// revertFechasTipadas quita los CHECK; los valores quedan en formato ISO
+func revertFechasTipadas(tx *sql.Tx) error {
+	if err := rebuildMesas(tx, false); err != nil {
+		return err
+	}
+	return rebuildTurnos(tx, false)
+}
+
+// rebuildMesas recrea la tabla con o sin los CHECK (SQLite no permite cambiarlos con ALTER TABLE)
+func rebuildMesas(tx *sql.Tx, checks bool) error {
+	fecha, hora := "fecha TEXT", "hora TEXT"
+	if checks {
+		fecha += " " + fmt.Sprintf(fechaCheck, "fecha")
+		hora += " " + fmt.Sprintf(horaCheck, "hora")
+	}
+	_, err := tx.Exec(`
+		CREATE TABLE mesas_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			materia_id INTEGER NOT NULL,
+			carrera_id INTEGER NOT NULL,
+			turno_id INTEGER,
+			aula_id INTEGER,
+			` + fecha + `,
+			` + hora + `,
+			fecha_edicion TEXT,
+			FOREIGN KEY(materia_id) REFERENCES materias(id),
+			FOREIGN KEY(carrera_id) REFERENCES carreras(id),
+			FOREIGN KEY(turno_id) REFERENCES turnos_config(id),
+			FOREIGN KEY(aula_id) REFERENCES aulas(id)
+		);
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO mesas_new SELECT id, materia_id, carrera_id, turno_id, aula_id, fecha, hora, fecha_edicion FROM mesas;
+		DROP TABLE mesas;
+		ALTER TABLE mesas_new RENAME TO mesas;
+	`)
+	return err
+}
+
+// backfillMesasFechas lleva fecha y hora de cada mesa al formato canónico
+func backfillMesasFechas(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		SELECT m.id, COALESCE(m.fecha, ''), COALESCE(m.hora, ''),
+			COALESCE(mat.nombre, ''), COALESCE(car.nombre, ''), COALESCE(t.nombre, ''), COALESCE(a.nombre, '')
+		FROM mesas m
+		LEFT JOIN materias mat ON mat.id = m.materia_id
+		LEFT JOIN carreras car ON car.id = m.carrera_id
+		LEFT JOIN turnos_config t ON t.id = m.turno_id
+		LEFT JOIN aulas a ON a.id = m.aula_id
+	`)
+	if err != nil {
+		return err
+	}
+	type mesaFecha struct {
+		id                            int
+		fecha, hora                   string
+		materia, carrera, turno, aula string
+	}
+	var mesas []mesaFecha
+	for rows.Next() {
+		var m mesaFecha
+		if err := rows.Scan(&m.id, &m.fecha, &m.hora, &m.materia, &m.carrera, &m.turno, &m.aula); err != nil {
+			rows.Close()
+			return err
+		}
+		mesas = append(mesas, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	invalid := 0
+	for _, m := range mesas {
+		var motivos []string
+		fecha := parseLegacy(m.fecha, legacyFechaLayouts, "2006-01-02", "fecha", &motivos)
+		hora := parseLegacy(m.hora, legacyHoraLayouts, "15:04", "hora", &motivos)
+		if _, err := tx.Exec("UPDATE mesas SET fecha = ?, hora = ? WHERE id = ?", fecha, hora, m.id); err != nil {
+			return err
+		}
+		if len(motivos) == 0 {
+			continue
+		}
+		invalid++
+		slog.Warn("mesa sin fecha u hora válida", "mesa_id", m.id, "motivo", strings.Join(motivos, "; "))
+		_, err := tx.Exec(`INSERT INTO mesas_sin_mapear (legacy_id, mesa_id, materia, carrera, turno, aula, fecha, hora, motivo)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.id, m.id, m.materia, m.carrera, m.turno, m.aula, m.fecha, m.hora, strings.Join(motivos, "; "))
+		if err != nil {
+			return err
+		}
+	}
+	if invalid > 0 {
+		slog.Warn("migración de fechas: hay mesas sin fecha u hora; ver el panel de administración", "mesas", invalid)
+	}
+	return nil
+}
+
+func rebuildTurnos(tx *sql.Tx, checks bool) error {
+	inicio, fin := "fecha_inicio TEXT", "fecha_fin TEXT"
+	if checks {
+		inicio += " " + fmt.Sprintf(fechaCheck, "fecha_inicio")
+		fin += " " + fmt.Sprintf(fechaCheck, "fecha_fin")
+	}
+	_, err := tx.Exec(`
+		CREATE TABLE turnos_config_new (
+			id INTEGER PRIMARY KEY,
+			nombre TEXT,
+			` + inicio + `,
+			` + fin + `,
+			receso INTEGER
+		);
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO turnos_config_new SELECT id, nombre, fecha_inicio, fecha_fin, receso FROM turnos_config;
+		DROP TABLE turnos_config;
+		ALTER TABLE turnos_config_new RENAME TO turnos_config;
+	`)
+	return err
+}
+
+func backfillTurnosFechas(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, COALESCE(nombre, ''), COALESCE(fecha_inicio, ''), COALESCE(fecha_fin, '') FROM turnos_config")
+	if err != nil {
+		return err
+	}
+	type turnoFechas struct {
+		id                  int
+		nombre, inicio, fin string
+	}
+	var turnos []turnoFechas
+	for rows.Next() {
+		var t turnoFechas
+		if err := rows.Scan(&t.id, &t.nombre, &t.inicio, &t.fin); err != nil {
+			rows.Close()
+			return err
+		}
+		turnos = append(turnos, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range turnos {
+		var motivos []string
+		inicio := parseLegacy(t.inicio, legacyFechaLayouts, "2006-01-02", "fecha de inicio", &motivos)
+		fin := parseLegacy(t.fin, legacyFechaLayouts, "2006-01-02", "fecha de fin", &motivos)
+		if len(motivos) > 0 {
+			slog.Warn("turno sin fechas válidas; completarlo en Configuración", "turno", t.nombre, "motivo", strings.Join(motivos, "; "))
+		}
+		if _, err := tx.Exec("UPDATE turnos_config SET fecha_inicio = ?, fecha_fin = ? WHERE id = ?", inicio, fin, t.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLegacy lleva raw al formato canónico; vacío o ilegible devuelve NULL
+// (y en el segundo caso agrega el motivo)
+func parseLegacy(raw string, layouts []string, canonical, field string, motivos *[]string) any {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.Format(canonical)
+		}
+	}
+	*motivos = append(*motivos, fmt.Sprintf("%s %q inválida", field, raw))
+	return nil
+}
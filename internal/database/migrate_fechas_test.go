@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+)
+
+func TestParseLegacy(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		layouts    []string
+		canonical  string
+		want       any
+		wantMotivo string
+	}{
+		{"fecha ISO", "2025-03-27", legacyFechaLayouts, "2006-01-02", "2025-03-27", ""},
+		{"fecha DD/MM/AAAA", "27/03/2025", legacyFechaLayouts, "2006-01-02", "2025-03-27", ""},
+		{"fecha D/M/AAAA", "7/3/2025", legacyFechaLayouts, "2006-01-02", "2025-03-07", ""},
+		{"fecha DD-MM-AAAA", "27-03-2025", legacyFechaLayouts, "2006-01-02", "2025-03-27", ""},
+		{"fecha DD/MM/AA", "27/03/25", legacyFechaLayouts, "2006-01-02", "2025-03-27", ""},
+		{"fecha con espacios", "  27/03/2025 ", legacyFechaLayouts, "2006-01-02", "2025-03-27", ""},
+		{"fecha vacía", "", legacyFechaLayouts, "2006-01-02", nil, ""},
+		{"fecha en blanco", "   ", legacyFechaLayouts, "2006-01-02", nil, ""},
+		{"fecha con texto", "a confirmar", legacyFechaLayouts, "2006-01-02", nil, `campo "a confirmar" inválida`},
+		{"día inexistente", "30/02/2025", legacyFechaLayouts, "2006-01-02", nil, `campo "30/02/2025" inválida`},
+		{"mes inexistente", "2025-13-01", legacyFechaLayouts, "2006-01-02", nil, `campo "2025-13-01" inválida`},
+		{"hora HH:MM", "09:30", legacyHoraLayouts, "15:04", "09:30", ""},
+		{"hora H:MM", "9:30", legacyHoraLayouts, "15:04", "09:30", ""},
+		{"hora con segundos", "14:00:00", legacyHoraLayouts, "15:04", "14:00", ""},
+		{"hora con punto", "14.30", legacyHoraLayouts, "15:04", "14:30", ""},
+		{"hora sola", "8", legacyHoraLayouts, "15:04", "08:00", ""},
+		{"hora vacía", "", legacyHoraLayouts, "15:04", nil, ""},
+		{"hora con texto", "a la tarde", legacyHoraLayouts, "15:04", nil, `campo "a la tarde" inválida`},
+		{"hora fuera de rango", "25:00", legacyHoraLayouts, "15:04", nil, `campo "25:00" inválida`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var motivos []string
+			got := parseLegacy(tt.raw, tt.layouts, tt.canonical, "campo", &motivos)
+			if got != tt.want {
+				t.Errorf("parseLegacy(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+			var wantMotivos []string
+			if tt.wantMotivo != "" {
+				wantMotivos = []string{tt.wantMotivo}
+			}
+			if !slices.Equal(motivos, wantMotivos) {
+				t.Errorf("motivos = %q, want %q", motivos, wantMotivos)
+			}
+		})
+	}
+}
+
+func TestFechasTipadas(t *testing.T) {
+	db := newTestDB(t)
+	migrateDownTo(t, db, 6)
+
+	_, err := db.Exec(`
+		INSERT INTO mesas (id, materia_id, carrera_id, turno_id, fecha, hora) VALUES
+			(1, 1, 1, 3, '27/03/2025', '9.30'),
+			(2, 2, 1, 3, '2025-03-28', '14'),
+			(3, 3, 3, 3, 'a confirmar', '10:00'),
+			(4, 4, 1, NULL, '', NULL);
+		UPDATE turnos_config SET fecha_inicio = '10/03/2025', fecha_fin = '14-03-2025' WHERE id = 2;
+		UPDATE turnos_config SET fecha_fin = 'fin de mes' WHERE id = 4;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	type mesa struct {
+		id          int
+		fecha, hora sql.NullString
+	}
+	str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	want := []mesa{
+		{1, str("2025-03-27"), str("09:30")},
+		{2, str("2025-03-28"), str("14:00")},
+		{3, sql.NullString{}, str("10:00")},
+		{4, sql.NullString{}, sql.NullString{}},
+	}
+	rows, err := db.Query("SELECT id, fecha, hora FROM mesas ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []mesa
+	for rows.Next() {
+		var m mesa
+		if err := rows.Scan(&m.id, &m.fecha, &m.hora); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	rows.Close()
+	if !slices.Equal(got, want) {
+		t.Errorf("mesas = %+v, want %+v", got, want)
+	}
+
+	// Solo la fecha ilegible se informa; la vacía queda a confirmar
+	var legacyID, mesaID int
+	var fecha, motivo string
+	err = db.QueryRow("SELECT legacy_id, mesa_id, fecha, motivo FROM mesas_sin_mapear").Scan(&legacyID, &mesaID, &fecha, &motivo)
+	if err != nil || legacyID != 3 || mesaID != 3 || fecha != "a confirmar" || motivo != `fecha "a confirmar" inválida` {
+		t.Errorf("mesas_sin_mapear = %d, %d, %q, %q (err %v)", legacyID, mesaID, fecha, motivo, err)
+	}
+
+	var inicio, fin sql.NullString
+	db.QueryRow("SELECT fecha_inicio, fecha_fin FROM turnos_config WHERE id = 2").Scan(&inicio, &fin)
+	if inicio.String != "2025-03-10" || fin.String != "2025-03-14" {
+		t.Errorf("turno 2: %v a %v, want 2025-03-10 a 2025-03-14", inicio, fin)
+	}
+	db.QueryRow("SELECT fecha_inicio, fecha_fin FROM turnos_config WHERE id = 4").Scan(&inicio, &fin)
+	if inicio.String != "2025-05-05" || fin.Valid {
+		t.Errorf("turno 4: %v a %v, want 2025-05-05 a NULL", inicio, fin)
+	}
+
+	// Los CHECK no dejan volver a cargar texto libre
+	for _, q := range []string{
+		"UPDATE mesas SET fecha = '27/03/2025' WHERE id = 1",
+		"UPDATE mesas SET fecha = '2025-02-30' WHERE id = 1",
+		"UPDATE mesas SET hora = '9.30' WHERE id = 1",
+		"UPDATE turnos_config SET fecha_fin = 'fin de mes' WHERE id = 4",
+	} {
+		if _, err := db.Exec(q); err == nil {
+			t.Errorf("se aceptó %q", q)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"mi-bot-unne/internal/models"
+)
+
+// mesasSchema es la tabla de mesas relacional: cada mesa referencia por id a su
+// materia, carrera, turno y aula (la sede se obtiene a través del aula).
+const mesasSchema = `
+	CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		materia_id INTEGER NOT NULL,
+		carrera_id INTEGER NOT NULL,
+		turno_id INTEGER,
+		aula_id INTEGER,
+		fecha TEXT,
+		hora TEXT,
+		fecha_edicion TEXT,
+		FOREIGN KEY(materia_id) REFERENCES materias(id),
+		FOREIGN KEY(carrera_id) REFERENCES carreras(id),
+		FOREIGN KEY(turno_id) REFERENCES turnos_config(id),
+		FOREIGN KEY(aula_id) REFERENCES aulas(id)
+	);
+`
+
+// mesasSinMapearSchema guarda las filas de texto libre que la migración no pudo
+// resolver. mesa_id es NULL cuando la fila no se migró (sin materia o carrera);
+// si la mesa se migró pero perdió su aula o turno, apunta a la mesa nueva.
+const mesasSinMapearSchema = `
+	CREATE TABLE IF NOT EXISTS mesas_sin_mapear (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		legacy_id INTEGER NOT NULL,
+		mesa_id INTEGER,
+		materia TEXT,
+		carrera TEXT,
+		turno TEXT,
+		aula TEXT,
+		fecha TEXT,
+		hora TEXT,
+		motivo TEXT NOT NULL
+	);
+`
+
+// MesasMigrationReport resume la conversión de mesas de texto libre a ids
+type MesasMigrationReport struct {
+	Total    int
+	Migrated int
+	Unmapped []models.UnmappedMesa
+}
+
+// migrateMesasRelational convierte la tabla mesas con columnas de texto
+// (materia, carrera, turno, aula) a la versión con ids y deja el resultado en el log.
+func migrateMesasRelational(tx *sql.Tx) error {
+	report, err := mapMesas(tx)
+	if err != nil {
+		return err
+	}
+	if report != nil && report.Total > 0 {
+		logMesasMigration(report)
+	}
+	return nil
+}
+
+// mapMesas hace la conversión. Si mesas no tiene la columna de texto materia (ya está en
+// ids, o no es el esquema de texto libre) no toca la tabla: solo crea mesas_sin_mapear
+// vacía y devuelve un reporte nil.
+func mapMesas(tx *sql.Tx) (*MesasMigrationReport, error) {
+	cols, err := tableColumns(tx, "mesas")
+	if err != nil {
+		return nil, err
+	}
+	if cols["materia_id"] || !cols["materia"] {
+		_, err := tx.Exec(mesasSinMapearSchema)
+		return nil, err
+	}
+
+	materias, err := nameIndex(tx, "SELECT id, nombre FROM materias")
+	if err != nil {
+		return nil, err
+	}
+	carreras, err := nameIndex(tx, "SELECT id, nombre FROM carreras")
+	if err != nil {
+		return nil, err
+	}
+	turnos, err := nameIndex(tx, "SELECT id, nombre FROM turnos_config")
+	if err != nil {
+		return nil, err
+	}
+	aulas, err := nameIndex(tx, "SELECT id, nombre FROM aulas")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(mesasSchema, "mesas_new") + mesasSinMapearSchema); err != nil {
+		return nil, err
+	}
+
+	// fecha_edicion se agregó con ALTER TABLE, así que puede faltar en bases muy viejas
+	fechaEdicion := "''"
+	if cols["fecha_edicion"] {
+		fechaEdicion = "COALESCE(fecha_edicion, '')"
+	}
+	rows, err := tx.Query(`SELECT id, COALESCE(materia, ''), COALESCE(carrera, ''), COALESCE(turno, ''), COALESCE(aula, ''),
+		COALESCE(fecha, ''), COALESCE(hora, ''), ` + fechaEdicion + ` FROM mesas ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	type legacyMesa struct {
+		id                                                 int
+		materia, carrera, turno, aula, fecha, hora, edited string
+	}
+	var legacy []legacyMesa
+	for rows.Next() {
+		var m legacyMesa
+		if err := rows.Scan(&m.id, &m.materia, &m.carrera, &m.turno, &m.aula, &m.fecha, &m.hora, &m.edited); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		legacy = append(legacy, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &MesasMigrationReport{Total: len(legacy)}
+	for _, m := range legacy {
+		var motivos []string
+		materiaID := resolve(materias, m.materia, "materia", &motivos)
+		carreraID := resolve(carreras, m.carrera, "carrera", &motivos)
+		turnoID := resolve(turnos, m.turno, "turno", &motivos)
+		aulaID := resolve(aulas, m.aula, "aula", &motivos)
+
+		migrated := materiaID != 0 && carreraID != 0
+		var mesaID int
+		if migrated {
+			// Se conserva el id para no romper los UID de los calendarios ni la auditoría
+			_, err := tx.Exec(`INSERT INTO mesas_new (id, materia_id, carrera_id, turno_id, aula_id, fecha, hora, fecha_edicion)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				m.id, materiaID, carreraID, nullID(turnoID), nullID(aulaID), m.fecha, m.hora, m.edited)
+			if err != nil {
+				return nil, err
+			}
+			mesaID = m.id
+			report.Migrated++
+		}
+		if len(motivos) == 0 {
+			continue
+		}
+
+		u := models.UnmappedMesa{
+			LegacyID: m.id,
+			MesaID:   mesaID,
+			Materia:  m.materia,
+			Carrera:  m.carrera,
+			Turno:    m.turno,
+			Aula:     m.aula,
+			Fecha:    m.fecha,
+			Hora:     m.hora,
+			Motivo:   strings.Join(motivos, "; "),
+		}
+		report.Unmapped = append(report.Unmapped, u)
+		_, err := tx.Exec(`INSERT INTO mesas_sin_mapear (legacy_id, mesa_id, materia, carrera, turno, aula, fecha, hora, motivo)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.id, nullID(mesaID), m.materia, m.carrera, m.turno, m.aula, m.fecha, m.hora, u.Motivo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec("DROP TABLE mesas; ALTER TABLE mesas_new RENAME TO mesas;"); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// revertMesasRelational vuelve a la tabla con texto libre, recuperando también
+// las filas que no se habían podido migrar
+func revertMesasRelational(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE mesas_old (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			materia TEXT,
+			turno TEXT,
+			fecha TEXT,
+			hora TEXT,
+			aula TEXT,
+			carrera TEXT,
+			fecha_edicion TEXT
+		);
+		INSERT INTO mesas_old (id, materia, turno, fecha, hora, aula, carrera, fecha_edicion)
+			SELECT m.id, mat.nombre, COALESCE(t.nombre, u.turno), m.fecha, m.hora, COALESCE(a.nombre, u.aula), car.nombre, m.fecha_edicion
+			FROM mesas m
+			LEFT JOIN materias mat ON mat.id = m.materia_id
+			LEFT JOIN carreras car ON car.id = m.carrera_id
+			LEFT JOIN turnos_config t ON t.id = m.turno_id
+			LEFT JOIN aulas a ON a.id = m.aula_id
+			LEFT JOIN mesas_sin_mapear u ON u.id = (SELECT MIN(id) FROM mesas_sin_mapear WHERE mesa_id = m.id);
+		INSERT INTO mesas_old (id, materia, turno, fecha, hora, aula, carrera)
+			SELECT legacy_id, materia, turno, fecha, hora, aula, carrera
+			FROM mesas_sin_mapear
+			WHERE mesa_id IS NULL AND legacy_id NOT IN (SELECT id FROM mesas_old);
+		DROP TABLE mesas;
+		ALTER TABLE mesas_old RENAME TO mesas;
+		DROP TABLE mesas_sin_mapear;
+	`)
+	return err
+}
+
+// logMesasMigration deja en el log el resultado de la migración para revisarlo al desplegar
+func logMesasMigration(r *MesasMigrationReport) {
+	slog.Info("migración de mesas a ids", "migradas", r.Migrated, "total", r.Total, "sin_resolver", len(r.Unmapped))
+	for _, u := range r.Unmapped {
+		estado := "migrada sin esas referencias"
+		if u.MesaID == 0 {
+			estado = "NO migrada"
+		}
+		slog.Warn("mesa con referencias sin resolver", "legacy_id", u.LegacyID, "estado", estado, "motivo", u.Motivo)
+	}
+	if len(r.Unmapped) > 0 {
+		slog.Warn("el detalle queda en la tabla mesas_sin_mapear y en el panel de administración")
+	}
+}
+
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols[name] = true
+	}
+	return cols, rows.Err()
+}
+
+// nameIndex agrupa los ids por nombre normalizado; más de un id significa nombre ambiguo
+func nameIndex(tx *sql.Tx, query string) (map[string][]int, error) {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := map[string][]int{}
+	for rows.Next() {
+		var id int
+		var nombre sql.NullString
+		if err := rows.Scan(&id, &nombre); err != nil {
+			return nil, err
+		}
+		k := foldName(nombre.String)
+		index[k] = append(index[k], id)
+	}
+	return index, rows.Err()
+}
+
+func resolve(index map[string][]int, raw, field string, motivos *[]string) int {
+	if strings.TrimSpace(raw) == "" {
+		*motivos = append(*motivos, "sin "+field)
+		return 0
+	}
+	ids := index[foldName(raw)]
+	switch len(ids) {
+	case 0:
+		*motivos = append(*motivos, fmt.Sprintf("%s %q no existe", field, raw))
+		return 0
+	case 1:
+		return ids[0]
+	default:
+		*motivos = append(*motivos, fmt.Sprintf("%s %q: nombre ambiguo (%d coincidencias)", field, raw, len(ids)))
+		return 0
+	}
+}
+
+var accentFolder = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// foldName compara nombres sin distinguir mayúsculas, acentos ni espacios sobrantes
+func foldName(s string) string {
+	return accentFolder.Replace(strings.ToLower(strings.Join(strings.Fields(s), " ")))
+}
+
+func nullID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
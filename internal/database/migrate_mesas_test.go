@@ -0,0 +1,188 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// migrateDownTo revierte todas las migraciones aplicadas posteriores a version
+func migrateDownTo(t *testing.T, db *sql.DB, version int) {
+	t.Helper()
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := 0
+	for _, s := range states {
+		if s.Applied() && s.Version > version {
+			steps++
+		}
+	}
+	if _, err := MigrateDown(db, steps); err != nil {
+		t.Fatalf("MigrateDown(%d): %v", steps, err)
+	}
+}
+
+// legacyMesa es una fila de la tabla mesas de texto libre
+type legacyMesa struct {
+	id                                   int
+	materia, carrera, turno, aula, fecha string
+}
+
+func insertLegacyMesas(t *testing.T, db *sql.DB, mesas []legacyMesa) {
+	t.Helper()
+	for _, m := range mesas {
+		_, err := db.Exec("INSERT INTO mesas (id, materia, carrera, turno, aula, fecha, hora) VALUES (?, ?, ?, ?, ?, ?, '09:00')",
+			m.id, m.materia, m.carrera, m.turno, m.aula, m.fecha)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMesasRelacionales(t *testing.T) {
+	db := newTestDB(t)
+	migrateDownTo(t, db, 5)
+
+	// Un segundo "Aula Magna", en Corrientes, vuelve ambiguo el nombre
+	if _, err := db.Exec("INSERT INTO aulas (id, nombre, sede_id) VALUES (8, 'Aula Magna', 2)"); err != nil {
+		t.Fatal(err)
+	}
+	insertLegacyMesas(t, db, []legacyMesa{
+		{10, "Álgebra I", "Ingeniería en Sistemas", "3° Turno", "Aula 1 - PB", "2025-03-27"},
+		{11, "  algebra   i ", "LICENCIATURA EN MATEMATICA", "1° turno", "laboratorio 1", "2025-02-18"},
+		{12, "Física I", "Profesorado en Física", "3° Turno", "Aula 99", "2025-03-28"},
+		{13, "Física I", "Profesorado en Física", "", "Aula Magna", "2025-03-28"},
+		{14, "Química", "", "3° Turno", "Aula 1 - PB", "2025-03-28"},
+	})
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// Se conservan los ids; lo que no se resolvió queda en NULL (0 acá)
+	type mesa struct{ id, materia, carrera, turno, aula int }
+	want := []mesa{
+		{10, 1, 1, 3, 2},
+		{11, 1, 2, 1, 5},
+		{12, 3, 3, 3, 0},
+		{13, 3, 3, 0, 0},
+	}
+	var got []mesa
+	rows, err := db.Query("SELECT id, materia_id, carrera_id, COALESCE(turno_id, 0), COALESCE(aula_id, 0) FROM mesas ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var m mesa
+		if err := rows.Scan(&m.id, &m.materia, &m.carrera, &m.turno, &m.aula); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	rows.Close()
+	if len(got) != len(want) {
+		t.Fatalf("mesas = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("mesa %d = %+v, want %+v", want[i].id, got[i], want[i])
+		}
+	}
+
+	type unmapped struct {
+		legacyID, mesaID int
+		motivo           string
+	}
+	wantUnmapped := []unmapped{
+		{12, 12, `aula "Aula 99" no existe`},
+		{13, 13, `sin turno; aula "Aula Magna": nombre ambiguo (2 coincidencias)`},
+		{14, 0, `materia "Química" no existe; sin carrera`},
+	}
+	var gotUnmapped []unmapped
+	rows, err = db.Query("SELECT legacy_id, COALESCE(mesa_id, 0), motivo FROM mesas_sin_mapear ORDER BY legacy_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var u unmapped
+		if err := rows.Scan(&u.legacyID, &u.mesaID, &u.motivo); err != nil {
+			t.Fatal(err)
+		}
+		gotUnmapped = append(gotUnmapped, u)
+	}
+	rows.Close()
+	if len(gotUnmapped) != len(wantUnmapped) {
+		t.Fatalf("mesas_sin_mapear = %+v, want %+v", gotUnmapped, wantUnmapped)
+	}
+	for i := range wantUnmapped {
+		if gotUnmapped[i] != wantUnmapped[i] {
+			t.Errorf("sin mapear %d = %+v, want %+v", wantUnmapped[i].legacyID, gotUnmapped[i], wantUnmapped[i])
+		}
+	}
+
+	// Al revertir vuelve el texto libre: los nombres resueltos salen de las tablas de
+	// parámetros y los que no se pudieron resolver, de mesas_sin_mapear
+	migrateDownTo(t, db, 5)
+	var cols map[string]bool
+	err = inTx(db, func(tx *sql.Tx) error {
+		cols, err = tableColumns(tx, "mesas")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cols["materia"] || !cols["aula"] || cols["materia_id"] {
+		t.Errorf("columnas después de revertir = %v", cols)
+	}
+	var sinMapear int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'mesas_sin_mapear'").Scan(&sinMapear)
+	if sinMapear != 0 {
+		t.Error("mesas_sin_mapear sigue existiendo después de revertir")
+	}
+
+	wantLegacy := []legacyMesa{
+		{10, "Álgebra I", "Ingeniería en Sistemas", "3° Turno", "Aula 1 - PB", "2025-03-27"},
+		{11, "Álgebra I", "Licenciatura en Matemática", "1° Turno", "Laboratorio 1", "2025-02-18"},
+		{12, "Física I", "Profesorado en Física", "3° Turno", "Aula 99", "2025-03-28"},
+		{13, "Física I", "Profesorado en Física", "", "Aula Magna", "2025-03-28"},
+		{14, "Química", "", "3° Turno", "Aula 1 - PB", "2025-03-28"},
+	}
+	var gotLegacy []legacyMesa
+	rows, err = db.Query(`SELECT id, COALESCE(materia, ''), COALESCE(carrera, ''), COALESCE(turno, ''), COALESCE(aula, ''), COALESCE(fecha, '')
+		FROM mesas ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var m legacyMesa
+		if err := rows.Scan(&m.id, &m.materia, &m.carrera, &m.turno, &m.aula, &m.fecha); err != nil {
+			t.Fatal(err)
+		}
+		gotLegacy = append(gotLegacy, m)
+	}
+	rows.Close()
+	if len(gotLegacy) != len(wantLegacy) {
+		t.Fatalf("mesas revertidas = %+v, want %+v", gotLegacy, wantLegacy)
+	}
+	for i := range wantLegacy {
+		if gotLegacy[i] != wantLegacy[i] {
+			t.Errorf("mesa revertida %d = %+v, want %+v", wantLegacy[i].id, gotLegacy[i], wantLegacy[i])
+		}
+	}
+}
+
+func TestMapMesasSinColumnasDeTexto(t *testing.T) {
+	db := newTestDB(t)
+
+	// Con la tabla ya en ids no hay nada que convertir
+	var report *MesasMigrationReport
+	err := inTx(db, func(tx *sql.Tx) error {
+		var err error
+		report, err = mapMesas(tx)
+		return err
+	})
+	if err != nil || report != nil {
+		t.Errorf("mapMesas = %+v, %v; want nil, nil", report, err)
+	}
+}
@@ -0,0 +1,378 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Las migraciones SQL viven en migrations/NNNN_nombre.up.sql y .down.sql.
+// Las que necesitan lógica (mapear datos, revisar columnas) se registran en goMigrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration es un cambio de esquema versionado. Up y Down corren dentro de una
+// transacción junto con el registro en schema_migrations.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// MigrationState indica si una migración ya se aplicó y cuándo
+type MigrationState struct {
+	Migration
+	AppliedAt string
+}
+
+func (s MigrationState) Applied() bool {
+	return s.AppliedAt != ""
+}
+
+var goMigrations = []Migration{
+	{Version: 2, Name: "mesas_fecha_edicion", Up: addMesasFechaEdicion, Down: dropMesasFechaEdicion},
+	{Version: 3, Name: "turnos_y_aula_sin_definir", Up: seedTurnosYAula, Down: unseedTurnosYAula},
+	{Version: 6, Name: "mesas_relacionales", Up: migrateMesasRelational, Down: revertMesasRelational},
+	{Version: 7, Name: "fechas_tipadas", Up: migrateFechasTipadas, Down: revertFechasTipadas},
+}
+
+// Migrations devuelve todas las migraciones conocidas, ordenadas por versión
+func Migrations() ([]Migration, error) {
+	byVersion := map[int]*Migration{}
+	for i := range goMigrations {
+		m := goMigrations[i]
+		byVersion[m.Version] = &m
+	}
+
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		version, name, direction, err := parseMigrationFilename(path.Base(file))
+		if err != nil {
+			return nil, err
+		}
+		body, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migración %d: nombres distintos %q y %q", version, m.Name, name)
+		}
+		run := execSQL(string(body))
+		if direction == "up" {
+			if m.Up != nil {
+				return nil, fmt.Errorf("migración %d: up duplicado", version)
+			}
+			m.Up = run
+		} else {
+			if m.Down != nil {
+				return nil, fmt.Errorf("migración %d: down duplicado", version)
+			}
+			m.Down = run
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == nil || m.Down == nil {
+			return nil, fmt.Errorf("migración %d (%s): falta up o down", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parseMigrationFilename separa "0004_usuarios.up.sql" en 4, "usuarios", "up"
+func parseMigrationFilename(filename string) (int, string, string, error) {
+	base := strings.TrimSuffix(filename, ".sql")
+	base, direction, ok := cutLast(base, ".")
+	if !ok || (direction != "up" && direction != "down") {
+		return 0, "", "", fmt.Errorf("migración %q: se esperaba .up.sql o .down.sql", filename)
+	}
+	number, name, ok := strings.Cut(base, "_")
+	version, err := strconv.Atoi(number)
+	if !ok || err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migración %q: se esperaba NNNN_nombre", filename)
+	}
+	return version, name, direction, nil
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+func execSQL(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		);
+	`)
+	return err
+}
+
+// MigrationStatus lista cada migración con su fecha de aplicación (vacía si está pendiente)
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i] = MigrationState{Migration: m, AppliedAt: applied[m.Version]}
+		delete(applied, m.Version)
+	}
+	// Una versión aplicada que este binario no conoce indica una base más nueva que el código
+	if len(applied) > 0 {
+		var unknown []string
+		for version := range applied {
+			unknown = append(unknown, strconv.Itoa(version))
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("la base tiene aplicadas migraciones que este binario no conoce: %s", strings.Join(unknown, ", "))
+	}
+	return states, nil
+}
+
+// MigrateUp aplica en orden todas las migraciones pendientes y devuelve las aplicadas
+func MigrateUp(db *sql.DB) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, s := range states {
+		if s.Applied() {
+			continue
+		}
+		err := inTx(db, func(tx *sql.Tx) error {
+			if err := s.Up(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				s.Version, s.Name, time.Now().Format("2006-01-02 15:04:05"))
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migración %04d_%s: %w", s.Version, s.Name, err)
+		}
+		slog.Info("migración aplicada", "version", s.Version, "name", s.Name)
+		done = append(done, s.Migration)
+	}
+	if len(done) > 0 {
+		if err := logForeignKeyViolations(db); err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// MigrateDown revierte las últimas steps migraciones aplicadas, de la más nueva a la más vieja
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+		s := states[i]
+		if !s.Applied() {
+			continue
+		}
+		err := inTx(db, func(tx *sql.Tx) error {
+			if err := s.Down(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", s.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("revirtiendo %04d_%s: %w", s.Version, s.Name, err)
+		}
+		slog.Info("migración revertida", "version", s.Version, "name", s.Name)
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+// inTx corre una migración en una transacción. Las migraciones reconstruyen tablas
+// (crear la nueva, copiar, DROP y RENAME) y con las claves foráneas activas el DROP
+// fallaría, así que se apagan solo en esta conexión mientras dura. SQLite ignora el
+// PRAGMA dentro de una transacción, por eso va antes del BEGIN.
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// logForeignKeyViolations avisa de las filas que apuntan a otras que ya no existen. No
+// corta el arranque: son datos de antes de que se aplicaran las claves foráneas y hay
+// que corregirlos a mano (el panel muestra las mesas afectadas como sin resolver).
+func logForeignKeyViolations(db *sql.DB) error {
+	rows, err := db.Query("SELECT \"table\", COUNT(*) FROM pragma_foreign_key_check GROUP BY \"table\"")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		var count int
+		if err := rows.Scan(&table, &count); err != nil {
+			return err
+		}
+		slog.Warn("filas con referencias a registros inexistentes", "tabla", table, "filas", count)
+	}
+	return rows.Err()
+}
+
+// --- Migraciones en Go ---
+
+// addMesasFechaEdicion reemplaza el ALTER TABLE que antes corría NewMesaRepository en cada arranque
+func addMesasFechaEdicion(tx *sql.Tx) error {
+	cols, err := tableColumns(tx, "mesas")
+	if err != nil {
+		return err
+	}
+	if cols["fecha_edicion"] {
+		return nil
+	}
+	_, err = tx.Exec("ALTER TABLE mesas ADD COLUMN fecha_edicion TEXT")
+	return err
+}
+
+func dropMesasFechaEdicion(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE mesas DROP COLUMN fecha_edicion")
+	return err
+}
+
+// seedTurnos son los turnos del calendario académico 2025 (receso indica si se suspenden las clases)
+var seedTurnos = []struct {
+	nombre, inicio, fin string
+	receso              bool
+}{
+	{"1° Turno", "2025-02-17", "2025-02-21", true},
+	{"2° Turno", "2025-03-10", "2025-03-14", true},
+	{"3° Turno", "2025-03-25", "2025-03-31", true},
+	{"4° Turno", "2025-05-05", "2025-05-30", false}, // Mesa expandida
+	{"5° Turno", "2025-06-30", "2025-07-04", true},
+	{"6° Turno", "2025-07-28", "2025-08-01", true},
+	{"7° Turno", "2025-09-01", "2025-09-26", false}, // Mesa expandida
+	{"8° Turno", "2025-10-06", "2025-10-31", false},
+	{"9° Turno", "2025-11-24", "2025-12-01", true},
+	{"10° Turno", "2025-12-15", "2025-12-19", true},
+}
+
+// seedTurnosYAula carga los turnos si la tabla está vacía y el aula "Sin definir"
+// que usan las mesas sin aula asignada (antes lo hacía NewParamsRepository). El aula
+// no pertenece a ninguna sede: sede_id queda NULL.
+func seedTurnosYAula(tx *sql.Tx) error {
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM turnos_config").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		for _, t := range seedTurnos {
+			receso := 0
+			if t.receso {
+				receso = 1
+			}
+			if _, err := tx.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)",
+				t.nombre, t.inicio, t.fin, receso); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.QueryRow("SELECT COUNT(*) FROM aulas WHERE nombre = 'Sin definir'").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := tx.Exec("INSERT INTO aulas (nombre, sede_id) VALUES ('Sin definir', NULL)"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unseedTurnosYAula borra solo las filas sembradas que no se modificaron
+func unseedTurnosYAula(tx *sql.Tx) error {
+	for _, t := range seedTurnos {
+		if _, err := tx.Exec("DELETE FROM turnos_config WHERE nombre = ? AND fecha_inicio = ? AND fecha_fin = ?",
+			t.nombre, t.inicio, t.fin); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec("DELETE FROM aulas WHERE nombre = 'Sin definir' AND (sede_id IS NULL OR sede_id = 0)")
+	return err
+}
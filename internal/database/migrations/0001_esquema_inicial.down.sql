@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS mesas;
+DROP TABLE IF EXISTS turnos_config;
+DROP TABLE IF EXISTS materias;
+DROP TABLE IF EXISTS carreras;
+DROP TABLE IF EXISTS aulas;
+DROP TABLE IF EXISTS sedes;
@@ -0,0 +1,36 @@
+-- Esquema original. Usa IF NOT EXISTS porque las bases anteriores al sistema de
+-- migraciones ya tienen estas tablas y no tienen historial en schema_migrations.
+CREATE TABLE IF NOT EXISTS mesas (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	materia TEXT,
+	turno TEXT,
+	fecha TEXT,
+	hora TEXT,
+	aula TEXT,
+	carrera TEXT
+);
+CREATE TABLE IF NOT EXISTS sedes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	nombre TEXT
+);
+CREATE TABLE IF NOT EXISTS aulas (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	nombre TEXT,
+	sede_id INTEGER,
+	FOREIGN KEY(sede_id) REFERENCES sedes(id)
+);
+CREATE TABLE IF NOT EXISTS carreras (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	nombre TEXT
+);
+CREATE TABLE IF NOT EXISTS materias (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	nombre TEXT
+);
+CREATE TABLE IF NOT EXISTS turnos_config (
+	id INTEGER PRIMARY KEY,
+	nombre TEXT,
+	fecha_inicio TEXT,
+	fecha_fin TEXT,
+	receso INTEGER
+);
@@ -0,0 +1,19 @@
+-- Plan de estudios: qué materias tiene cada carrera. Una materia puede estar en
+-- varias carreras (ej. Álgebra I), con distinto año, cuatrimestre y código.
+-- cuatrimestre NULL significa materia anual.
+CREATE TABLE plan_materias (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	carrera_id INTEGER NOT NULL,
+	materia_id INTEGER NOT NULL,
+	anio INTEGER CHECK (anio IS NULL OR anio BETWEEN 1 AND 7),
+	cuatrimestre INTEGER CHECK (cuatrimestre IS NULL OR cuatrimestre IN (1, 2)),
+	codigo TEXT,
+	UNIQUE (carrera_id, materia_id),
+	FOREIGN KEY(carrera_id) REFERENCES carreras(id),
+	FOREIGN KEY(materia_id) REFERENCES materias(id)
+);
+CREATE INDEX idx_plan_materias_materia ON plan_materias(materia_id);
+
+-- Las mesas ya cargadas indican qué materias se rinden en cada carrera
+INSERT OR IGNORE INTO plan_materias (carrera_id, materia_id)
+	SELECT DISTINCT carrera_id, materia_id FROM mesas;
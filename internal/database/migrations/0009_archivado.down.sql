@@ -0,0 +1,4 @@
+ALTER TABLE materias DROP COLUMN archivado_at;
+ALTER TABLE carreras DROP COLUMN archivado_at;
+ALTER TABLE aulas DROP COLUMN archivado_at;
+ALTER TABLE sedes DROP COLUMN archivado_at;
@@ -0,0 +1,6 @@
+-- Baja lógica de parámetros: lo archivado deja de ofrecerse en los formularios
+-- y en el chat, pero las mesas que ya lo referencian se siguen mostrando.
+ALTER TABLE sedes ADD COLUMN archivado_at TEXT;
+ALTER TABLE aulas ADD COLUMN archivado_at TEXT;
+ALTER TABLE carreras ADD COLUMN archivado_at TEXT;
+ALTER TABLE materias ADD COLUMN archivado_at TEXT;
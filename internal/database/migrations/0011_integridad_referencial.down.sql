@@ -0,0 +1,3 @@
+-- Solo se puede volver a la convención anterior de "sin sede"; las referencias a filas
+-- borradas no se recuperan.
+UPDATE aulas SET sede_id = 0 WHERE sede_id IS NULL;
@@ -0,0 +1,9 @@
+-- Desde esta versión SQLite aplica las FOREIGN KEY. Se corrigen los datos que las
+-- violarían al modificarlos: "sin sede" pasa de 0 a NULL y las referencias opcionales
+-- a filas borradas quedan sin asignar (el panel las lista como mesas sin resolver).
+UPDATE aulas SET sede_id = NULL WHERE sede_id = 0 OR sede_id NOT IN (SELECT id FROM sedes);
+UPDATE mesas SET turno_id = NULL WHERE turno_id NOT IN (SELECT id FROM turnos_config);
+UPDATE mesas SET aula_id = NULL WHERE aula_id NOT IN (SELECT id FROM aulas);
+UPDATE users SET carrera_id = NULL WHERE carrera_id NOT IN (SELECT id FROM carreras);
+DELETE FROM plan_materias
+	WHERE carrera_id NOT IN (SELECT id FROM carreras) OR materia_id NOT IN (SELECT id FROM materias);
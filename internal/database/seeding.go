@@ -38,4 +38,19 @@ func seedData(db *sql.DB) {
 	for _, m := range materias {
 		db.Exec("INSERT INTO materias (nombre) VALUES (?)", m)
 	}
+
+	// Seed Plan de Estudios (carrera_id, materia_id, año, cuatrimestre, código)
+	// Álgebra I y Análisis Matemático I son compartidas entre carreras
+	plan := []struct {
+		carrera, materia, anio, cuatrimestre int
+		codigo                               string
+	}{
+		{1, 1, 1, 1, "SIS-101"}, {1, 2, 1, 1, "SIS-102"}, {1, 4, 1, 2, "SIS-105"}, {1, 5, 3, 1, "SIS-301"},
+		{2, 1, 1, 1, "MAT-101"}, {2, 2, 1, 2, "MAT-102"},
+		{3, 2, 1, 1, "FIS-101"}, {3, 3, 1, 2, "FIS-102"},
+	}
+	for _, p := range plan {
+		db.Exec("INSERT INTO plan_materias (carrera_id, materia_id, anio, cuatrimestre, codigo) VALUES (?, ?, ?, ?, ?)",
+			p.carrera, p.materia, p.anio, p.cuatrimestre, p.codigo)
+	}
 }
@@ -0,0 +1,56 @@
+// Package display es el único lugar donde se decide cómo ven las fechas y horas
+// los alumnos y el panel. La base, los formularios y las exportaciones usan ISO.
+package display
+
+import (
+	"html/template"
+	"time"
+
+	"mi-bot-unne/internal/models"
+)
+
+const (
+	fechaLayout     = "02/01/2006"
+	timestampLayout = "2006-01-02 15:04:05" // como se guarda fecha_edicion
+	sinDefinir      = "A confirmar"
+)
+
+// Fecha muestra una fecha como DD/MM/AAAA
+func Fecha(d models.Date) string {
+	if d.IsZero() {
+		return sinDefinir
+	}
+	return d.Format(fechaLayout)
+}
+
+// Hora muestra una hora como HH:MM
+func Hora(t models.TimeOfDay) string {
+	if t.IsZero() {
+		return sinDefinir
+	}
+	return t.String()
+}
+
+// Rango muestra el período de un turno
+func Rango(desde, hasta models.Date) string {
+	return Fecha(desde) + " - " + Fecha(hasta)
+}
+
+// Timestamp muestra un instante guardado como "2006-01-02 15:04:05" (ej. fecha_edicion) sin los segundos
+func Timestamp(s string) string {
+	t, err := time.Parse(timestampLayout, s)
+	if err != nil {
+		return s
+	}
+	return t.Format(fechaLayout + " 15:04")
+}
+
+// FuncMap expone los formatos a las plantillas: {{ fecha .Fecha }}, {{ hora .Hora }}, etc.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"fecha":     Fecha,
+		"hora":      Hora,
+		"rango":     Rango,
+		"timestamp": Timestamp,
+	}
+}
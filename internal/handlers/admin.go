@@ -1,27 +1,53 @@
 package handlers
 
 import (
-	"log"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
+	"mi-bot-unne/internal/logging"
 	"mi-bot-unne/internal/models"
 	"mi-bot-unne/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
 )
 
 type AdminHandler struct {
-	Repo       *repository.MesaRepository
-	ParamsRepo *repository.ParamsRepository
+	Repo           *repository.MesaRepository
+	ParamsRepo     *repository.ParamsRepository
+	PendingImports *cache.Cache // Lotes importados esperando confirmación
+	importMu       sync.Mutex   // Hace atómico tomar un lote de PendingImports
 }
 
 func NewAdminHandler(repo *repository.MesaRepository, paramsRepo *repository.ParamsRepository) *AdminHandler {
-	return &AdminHandler{Repo: repo, ParamsRepo: paramsRepo}
+	return &AdminHandler{
+		Repo:           repo,
+		ParamsRepo:     paramsRepo,
+		PendingImports: cache.New(30*time.Minute, 10*time.Minute),
+	}
+}
+
+// mesas y params devuelven los repos con el usuario logueado como actor de auditoría
+func (h *AdminHandler) mesas(c *gin.Context) *repository.MesaRepository {
+	return h.Repo.As(currentUser(c).Email)
+}
+
+func (h *AdminHandler) params(c *gin.Context) *repository.ParamsRepository {
+	return h.ParamsRepo.As(currentUser(c).Email)
 }
 
 func (h *AdminHandler) ShowDashboard(c *gin.Context) {
+	h.renderDashboard(c, http.StatusOK, gin.H{})
+}
+
+// renderDashboard muestra el panel. Si data trae "form" y "errors", el formulario de
+// carga vuelve con lo que se envió y el error de cada campo.
+func (h *AdminHandler) renderDashboard(c *gin.Context, status int, data gin.H) {
 	mesas, err := h.Repo.GetAll()
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Error leyendo DB")
@@ -34,13 +60,30 @@ func (h *AdminHandler) ShowDashboard(c *gin.Context) {
 	materias, _ := h.ParamsRepo.GetAllMaterias()
 	turnos, _ := h.ParamsRepo.GetTurnoConfigs()
 
-	c.HTML(http.StatusOK, "admin.html", gin.H{
-		"mesas":    mesas,
-		"sedes":    sedes,
-		"carreras": carreras,
-		"materias": materias,
-		"turnos":   turnos, // Now available in dashboard
-	})
+	// Solo el superadmin puede corregir lo que quedó pendiente de la migración a ids
+	var unmapped []models.UnmappedMesa
+	if currentUser(c).IsSuperadmin() {
+		unmapped, _ = h.Repo.GetUnmapped()
+	}
+
+	if _, ok := data["form"]; !ok {
+		data["form"] = models.Mesa{}
+		data["errors"] = fieldErrors{}
+	}
+	// Con una sede ya elegida el select de aulas vuelve cargado
+	sedeID, _ := data["form_sede"].(int)
+	data["form_sede"] = sedeID
+	if sedeID != 0 {
+		data["form_aulas"], _ = h.ParamsRepo.GetAulasBySede(sedeID)
+	}
+
+	data["mesas"] = mesas
+	data["sedes"] = sedes
+	data["carreras"] = carreras
+	data["materias"] = materias
+	data["turnos"] = turnos // Now available in dashboard
+	data["unmapped"] = unmapped
+	renderHTML(c, status, "admin.html", data)
 }
 
 func (h *AdminHandler) GetAulas(c *gin.Context) {
@@ -60,6 +103,12 @@ func (h *AdminHandler) GetAulas(c *gin.Context) {
 }
 
 func (h *AdminHandler) ShowParams(c *gin.Context) {
+	h.renderParams(c, http.StatusOK, gin.H{})
+}
+
+// renderParams muestra la configuración. Un alta de turno inválida vuelve en "turno_form"
+// con sus "errors"; una edición, además con "turno_error_id" para marcar su fila.
+func (h *AdminHandler) renderParams(c *gin.Context, status int, data gin.H) {
 	sedes, err := h.ParamsRepo.GetAllSedes()
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Error leyendo Sedes")
@@ -84,20 +133,39 @@ func (h *AdminHandler) ShowParams(c *gin.Context) {
 		return
 	}
 	turnos, _ := h.ParamsRepo.GetTurnoConfigs()
+	if _, ok := data["errors"]; !ok {
+		data["errors"] = fieldErrors{}
+	}
+	if _, ok := data["turno_form"]; !ok {
+		data["turno_form"] = models.TurnoConfig{}
+	}
+	errorID, _ := data["turno_error_id"].(int)
+	data["turno_error_id"] = errorID
+	// La fila editada muestra lo que se envió, no lo guardado
+	for i := range turnos {
+		if turnos[i].ID == errorID {
+			turnos[i] = data["turno_form"].(models.TurnoConfig)
+		}
+	}
+	archivados, err := h.ParamsRepo.GetArchivados()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error leyendo archivados")
+		return
+	}
 
-	c.HTML(http.StatusOK, "admin_params.html", gin.H{
-		"sedes":    sedes,
-		"carreras": carreras,
-		"materias": materias,
-		"aulas":    aulas,
-		"turnos":   turnos,
-	})
+	data["sedes"] = sedes
+	data["carreras"] = carreras
+	data["materias"] = materias
+	data["aulas"] = aulas
+	data["turnos"] = turnos
+	data["archivados"] = archivados
+	renderHTML(c, status, "admin_params.html", data)
 }
 
 func (h *AdminHandler) StoreCarrera(c *gin.Context) {
 	nombre := c.PostForm("nombre")
 	if nombre != "" {
-		h.ParamsRepo.CreateCarrera(nombre)
+		h.params(c).CreateCarrera(nombre)
 	}
 	c.Redirect(http.StatusFound, "/admin/config")
 }
@@ -105,7 +173,7 @@ func (h *AdminHandler) StoreCarrera(c *gin.Context) {
 func (h *AdminHandler) StoreMateria(c *gin.Context) {
 	nombre := c.PostForm("nombre")
 	if nombre != "" {
-		h.ParamsRepo.CreateMateria(nombre)
+		h.params(c).CreateMateria(nombre)
 	}
 	c.Redirect(http.StatusFound, "/admin/config")
 }
@@ -113,7 +181,7 @@ func (h *AdminHandler) StoreMateria(c *gin.Context) {
 func (h *AdminHandler) StoreSede(c *gin.Context) {
 	nombre := c.PostForm("nombre")
 	if nombre != "" {
-		h.ParamsRepo.CreateSede(nombre)
+		h.params(c).CreateSede(nombre)
 	}
 	c.Redirect(http.StatusFound, "/admin/config")
 }
@@ -121,7 +189,7 @@ func (h *AdminHandler) StoreSede(c *gin.Context) {
 func (h *AdminHandler) StoreAula(c *gin.Context) {
 	nombre := c.PostForm("nombre")
 	sedeID, _ := strconv.Atoi(c.PostForm("sede_id"))
-	if err := h.ParamsRepo.CreateAula(nombre, sedeID); err != nil {
+	if _, err := h.params(c).CreateAula(nombre, sedeID); err != nil {
 		c.String(http.StatusInternalServerError, "Error al crear aula")
 		return
 	}
@@ -129,19 +197,13 @@ func (h *AdminHandler) StoreAula(c *gin.Context) {
 }
 
 func (h *AdminHandler) StoreTurnoConfig(c *gin.Context) {
-	var t models.TurnoConfig
-	// Checkbox handling: "on" or absent. Gin Bind might struggle with unchecked = false if using ShouldBind.
-	// We'll rely on PostForm for boolean safety if ShouldBind fails on unchecked.
-	t.Nombre = c.PostForm("nombre")
-	t.FechaInicio = c.PostForm("fecha_inicio")
-	t.FechaFin = c.PostForm("fecha_fin")
-	if c.PostForm("receso") == "on" {
-		t.Receso = true
-	} else {
-		t.Receso = false
-	}
-
-	if err := h.ParamsRepo.CreateTurnoConfig(t); err != nil {
+	t, errs := turnoFromForm(c)
+	if len(errs) > 0 {
+		h.renderParams(c, http.StatusBadRequest, gin.H{"turno_form": t, "errors": errs})
+		return
+	}
+
+	if _, err := h.params(c).CreateTurnoConfig(t); err != nil {
 		c.String(http.StatusInternalServerError, "Error al crear turno")
 		return
 	}
@@ -152,18 +214,22 @@ func (h *AdminHandler) UpdateTurnoConfig(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id")) // From URL param if used directly or hidden input
 	// Actually for "quick edit" we might post to /update/:id
 
-	var t models.TurnoConfig
+	t, errs := turnoFromForm(c)
 	t.ID = id
-	t.Nombre = c.PostForm("nombre")
-	t.FechaInicio = c.PostForm("fecha_inicio")
-	t.FechaFin = c.PostForm("fecha_fin")
-	if c.PostForm("receso") == "on" {
-		t.Receso = true
-	} else {
-		t.Receso = false
+	if len(errs) > 0 {
+		h.renderParams(c, http.StatusBadRequest, gin.H{"turno_form": t, "errors": errs, "turno_error_id": id})
+		return
 	}
 
-	if err := h.ParamsRepo.UpdateTurnoConfig(t); err != nil {
+	err := h.params(c).UpdateTurnoConfig(t)
+	if errors.Is(err, repository.ErrMesasFueraDelTurno) {
+		errs, err = turnoMesasFuera(h.ParamsRepo, t)
+		if err == nil {
+			h.renderParams(c, http.StatusBadRequest, gin.H{"turno_form": t, "errors": errs, "turno_error_id": id})
+			return
+		}
+	}
+	if err != nil {
 		c.String(http.StatusInternalServerError, "Error al actualizar turno")
 		return
 	}
@@ -172,32 +238,48 @@ func (h *AdminHandler) UpdateTurnoConfig(c *gin.Context) {
 
 func (h *AdminHandler) DeleteTurnoConfig(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	if err := h.ParamsRepo.DeleteTurnoConfig(id); err != nil {
+	err := h.params(c).DeleteTurnoConfig(id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.String(http.StatusNotFound, "Turno no encontrado")
+	case errors.Is(err, repository.ErrTurnoEnUso):
+		deps, _ := h.ParamsRepo.GetDependencias("turno", id)
+		h.renderParams(c, http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("No se puede borrar el turno #%d: tiene %d mesa(s). Movelas a otro turno o borralas primero.", id, deps.Mesas),
+		})
+	case err != nil:
 		c.String(http.StatusInternalServerError, "Error al eliminar turno")
-		return
+	default:
+		c.Redirect(http.StatusSeeOther, "/admin/config")
 	}
-	c.Redirect(http.StatusSeeOther, "/admin/config")
 }
 
 func (h *AdminHandler) CreateMesa(c *gin.Context) {
-	// Debugging: Print raw form values
-	log.Printf("DEBUG FORM: Carrera=%s, Hora=%s, Aula=%s, SedeID=%s",
-		c.PostForm("carrera"), c.PostForm("hora"), c.PostForm("aula"), c.PostForm("sede"))
+	errs := fieldErrors{}
+	nuevaMesa := mesaFromForm(c, errs)
+	if err := newMesaChecker(h.ParamsRepo).check(nuevaMesa, "", errs); err != nil {
+		logger(c).Error("validando la mesa", "error", err)
+		c.String(http.StatusInternalServerError, "Error leyendo DB")
+		return
+	}
+	if len(errs) > 0 {
+		// El formulario completo solo se vuelca en debug
+		logger(c).Debug("mesa inválida", "fields", errs, logging.Form(c.Request.PostForm))
+		sedeID, _ := strconv.Atoi(c.PostForm("sede"))
+		h.renderDashboard(c, http.StatusBadRequest, gin.H{"form": nuevaMesa, "form_sede": sedeID, "errors": errs})
+		return
+	}
 
-	var nuevaMesa models.Mesa
-	if err := c.ShouldBind(&nuevaMesa); err != nil {
-		log.Printf("BIND ERROR: %v", err)
-		c.String(http.StatusBadRequest, "Datos inválidos")
+	if !currentUser(c).CanEditCarrera(nuevaMesa.CarreraID) {
+		c.String(http.StatusForbidden, "No tenés permisos sobre esta carrera")
 		return
 	}
 
 	// Set current timestamp
 	nuevaMesa.FechaEdicion = time.Now().Format("2006-01-02 15:04:05")
 
-	log.Printf("STRUCT AFTER BIND: %+v", nuevaMesa)
-
-	if err := h.Repo.Create(nuevaMesa); err != nil {
-		log.Printf("DB ERROR: %v", err)
+	if _, err := h.mesas(c).Create(nuevaMesa); err != nil {
+		logger(c).Error("creando la mesa", "error", err)
 		c.String(http.StatusInternalServerError, "Error guardando en DB")
 		return
 	}
@@ -205,9 +287,139 @@ func (h *AdminHandler) CreateMesa(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/admin")
 }
 
+// DismissUnmapped quita del aviso una fila de la migración que ya se resolvió a mano
+func (h *AdminHandler) DismissUnmapped(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := h.Repo.DismissUnmapped(id); err != nil {
+		c.String(http.StatusInternalServerError, "Error descartando fila")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+func (h *AdminHandler) ShowEditMesa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	mesa, err := h.Repo.GetByID(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Mesa no encontrada")
+		return
+	}
+	if !currentUser(c).CanEditCarrera(mesa.CarreraID) {
+		c.String(http.StatusForbidden, "No tenés permisos sobre esta carrera")
+		return
+	}
+	h.renderEditMesa(c, http.StatusOK, mesa, mesa, mesa.SedeID, fieldErrors{})
+}
+
+// renderEditMesa muestra el formulario de edición con los valores de form (los guardados,
+// o los enviados si hubo errores). Lo archivado que usa la mesa guardada sigue en las listas.
+func (h *AdminHandler) renderEditMesa(c *gin.Context, status int, mesa, form models.Mesa, sedeID int, errs fieldErrors) {
+	sedes, _ := h.ParamsRepo.GetAllSedes()
+	carreras, _ := h.ParamsRepo.GetAllCarreras()
+	materias, _ := h.ParamsRepo.GetAllMaterias()
+	turnos, _ := h.ParamsRepo.GetTurnoConfigs()
+
+	// Preselect the sede of the current aula so the cascading select starts populated
+	aulas, _ := h.ParamsRepo.GetAulasBySede(sedeID)
+
+	// Las listas no traen lo archivado; se agrega lo que la mesa ya usa para no cambiarlo al guardar
+	if !containsID(materias, mesa.MateriaID, func(m models.Materia) int { return m.ID }) {
+		materias = append(materias, models.Materia{ID: mesa.MateriaID, Nombre: mesa.Materia + " (archivada)"})
+	}
+	if !containsID(carreras, mesa.CarreraID, func(ca models.Carrera) int { return ca.ID }) {
+		carreras = append(carreras, models.Carrera{ID: mesa.CarreraID, Nombre: mesa.Carrera + " (archivada)"})
+	}
+	if mesa.SedeID != 0 && !containsID(sedes, mesa.SedeID, func(s models.Sede) int { return s.ID }) {
+		sedes = append(sedes, models.Sede{ID: mesa.SedeID, Nombre: mesa.Sede + " (archivada)"})
+	}
+	if mesa.AulaID != 0 && mesa.SedeID == sedeID && !containsID(aulas, mesa.AulaID, func(a models.Aula) int { return a.ID }) {
+		aulas = append(aulas, models.Aula{ID: mesa.AulaID, Nombre: mesa.Aula + " (archivada)", SedeID: mesa.SedeID})
+	}
+
+	form.ID = mesa.ID
+	form.FechaEdicion = mesa.FechaEdicion
+	renderHTML(c, status, "admin_edit_mesa.html", gin.H{
+		"mesa":     form,
+		"errors":   errs,
+		"sede_id":  sedeID,
+		"sedes":    sedes,
+		"aulas":    aulas,
+		"carreras": carreras,
+		"materias": materias,
+		"turnos":   turnos,
+	})
+}
+
+func (h *AdminHandler) UpdateMesa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	existing, err := h.Repo.GetByID(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Mesa no encontrada")
+		return
+	}
+	user := currentUser(c)
+	if !user.CanEditCarrera(existing.CarreraID) {
+		c.String(http.StatusForbidden, "No tenés permisos sobre esta carrera")
+		return
+	}
+
+	errs := fieldErrors{}
+	mesa := mesaFromForm(c, errs)
+	mesa.ID = id
+	if err := newMesaChecker(h.ParamsRepo).check(mesa, "", errs); err != nil {
+		logger(c).Error("validando la mesa", "mesa_id", id, "error", err)
+		c.String(http.StatusInternalServerError, "Error leyendo DB")
+		return
+	}
+	if len(errs) > 0 {
+		logger(c).Debug("mesa inválida", "fields", errs, logging.Form(c.Request.PostForm))
+		sedeID, _ := strconv.Atoi(c.PostForm("sede"))
+		h.renderEditMesa(c, http.StatusBadRequest, existing, mesa, sedeID, errs)
+		return
+	}
+	// Secretaría solo puede mover mesas dentro de su propia carrera
+	if !user.CanEditCarrera(mesa.CarreraID) {
+		c.String(http.StatusForbidden, "No tenés permisos sobre esta carrera")
+		return
+	}
+
+	if err := h.mesas(c).Update(mesa); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.String(http.StatusNotFound, "Mesa no encontrada")
+			return
+		}
+		logger(c).Error("actualizando la mesa", "mesa_id", mesa.ID, "error", err)
+		c.String(http.StatusInternalServerError, "Error actualizando mesa")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin")
+}
+
 func (h *AdminHandler) DeleteMesa(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.Repo.Delete(id); err != nil {
+	idInt, _ := strconv.Atoi(id)
+	mesa, err := h.Repo.GetByID(idInt)
+	if err != nil {
+		c.String(http.StatusNotFound, "Mesa no encontrada")
+		return
+	}
+	if !currentUser(c).CanEditCarrera(mesa.CarreraID) {
+		c.String(http.StatusForbidden, "No tenés permisos sobre esta carrera")
+		return
+	}
+
+	if err := h.mesas(c).Delete(id); err != nil {
 		c.String(http.StatusInternalServerError, "Error eliminando mesa")
 		return
 	}
@@ -251,7 +463,7 @@ func (h *AdminHandler) ShowEditParam(c *gin.Context) {
 		return
 	}
 
-	c.HTML(http.StatusOK, "admin_edit_param.html", data)
+	renderHTML(c, http.StatusOK, "admin_edit_param.html", data)
 }
 
 func (h *AdminHandler) UpdateParam(c *gin.Context) {
@@ -262,14 +474,14 @@ func (h *AdminHandler) UpdateParam(c *gin.Context) {
 	var err error
 	switch paramType {
 	case "materia":
-		err = h.ParamsRepo.UpdateMateria(id, nombre)
+		err = h.params(c).UpdateMateria(id, nombre)
 	case "carrera":
-		err = h.ParamsRepo.UpdateCarrera(id, nombre)
+		err = h.params(c).UpdateCarrera(id, nombre)
 	case "sede":
-		err = h.ParamsRepo.UpdateSede(id, nombre)
+		err = h.params(c).UpdateSede(id, nombre)
 	case "aula":
 		sedeID, _ := strconv.Atoi(c.PostForm("sede_id"))
-		err = h.ParamsRepo.UpdateAula(id, nombre, sedeID)
+		err = h.params(c).UpdateAula(id, nombre, sedeID)
 	default:
 		c.String(http.StatusBadRequest, "Tipo inválido")
 		return
@@ -282,28 +494,123 @@ func (h *AdminHandler) UpdateParam(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/admin/config")
 }
 
+// ShowDeleteParam muestra cuántas mesas, aulas, filas del plan y usuarios dependen
+// del elemento antes de borrarlo, y ofrece archivarlo
+func (h *AdminHandler) ShowDeleteParam(c *gin.Context) {
+	h.renderDeleteParam(c, http.StatusOK, "")
+}
+
+func (h *AdminHandler) renderDeleteParam(c *gin.Context, status int, errMsg string) {
+	paramType := c.Param("type")
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	nombre, err := h.paramNombre(paramType, id)
+	if errors.Is(err, repository.ErrTipoInvalido) {
+		c.String(http.StatusBadRequest, "Tipo inválido")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusNotFound, "Elemento no encontrado")
+		return
+	}
+	deps, err := h.ParamsRepo.GetDependencias(paramType, id)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error contando dependencias")
+		return
+	}
+
+	renderHTML(c, status, "admin_delete_param.html", gin.H{
+		"type":   paramType,
+		"id":     id,
+		"nombre": nombre,
+		"deps":   deps,
+		"error":  errMsg,
+	})
+}
+
+func (h *AdminHandler) paramNombre(paramType string, id int) (string, error) {
+	switch paramType {
+	case "materia":
+		m, err := h.ParamsRepo.GetMateria(id)
+		return m.Nombre, err
+	case "carrera":
+		ca, err := h.ParamsRepo.GetCarrera(id)
+		return ca.Nombre, err
+	case "sede":
+		s, err := h.ParamsRepo.GetSede(id)
+		return s.Nombre, err
+	case "aula":
+		a, err := h.ParamsRepo.GetAula(id)
+		return a.Nombre, err
+	}
+	return "", repository.ErrTipoInvalido
+}
+
+// DeleteParam borra el elemento. Si arrastra aulas o filas del plan hace falta
+// confirmar=1; si tiene mesas o usuarios se rechaza y se vuelve a la confirmación.
 func (h *AdminHandler) DeleteParam(c *gin.Context) {
 	paramType := c.Param("type")
 	id, _ := strconv.Atoi(c.Param("id"))
+	cascada := c.PostForm("confirmar") == "1"
 
 	var err error
 	switch paramType {
 	case "materia":
-		err = h.ParamsRepo.DeleteMateria(id)
+		err = h.params(c).DeleteMateria(id, cascada)
 	case "carrera":
-		err = h.ParamsRepo.DeleteCarrera(id)
+		err = h.params(c).DeleteCarrera(id, cascada)
 	case "sede":
-		err = h.ParamsRepo.DeleteSede(id)
+		err = h.params(c).DeleteSede(id, cascada)
 	case "aula":
-		err = h.ParamsRepo.DeleteAula(id)
+		err = h.params(c).DeleteAula(id, cascada)
 	default:
 		c.String(http.StatusBadRequest, "Tipo inválido")
 		return
 	}
 
-	if err != nil {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.String(http.StatusNotFound, "Elemento no encontrado")
+	case errors.Is(err, repository.ErrEnUso):
+		h.renderDeleteParam(c, http.StatusConflict, "No se puede borrar: tiene mesas o usuarios asociados. Podés archivarlo.")
+	case errors.Is(err, repository.ErrConfirmarCascada):
+		h.renderDeleteParam(c, http.StatusConflict, "Marcá la confirmación para borrar también lo que depende de este elemento.")
+	case err != nil:
 		c.String(http.StatusInternalServerError, "Error eliminando")
-		return
+	default:
+		c.Redirect(http.StatusFound, "/admin/config")
 	}
-	c.Redirect(http.StatusFound, "/admin/config")
+}
+
+// ArchiveParam da de baja lógica: deja de ofrecerse pero las mesas existentes lo conservan
+func (h *AdminHandler) ArchiveParam(c *gin.Context) {
+	h.setArchivado(c, h.params(c).Archivar)
+}
+
+func (h *AdminHandler) RestoreParam(c *gin.Context) {
+	h.setArchivado(c, h.params(c).Restaurar)
+}
+
+func (h *AdminHandler) setArchivado(c *gin.Context, fn func(tipo string, id int) error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	err := fn(c.Param("type"), id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.String(http.StatusNotFound, "Elemento no encontrado")
+	case errors.Is(err, repository.ErrTipoInvalido):
+		c.String(http.StatusBadRequest, "Tipo inválido")
+	case err != nil:
+		c.String(http.StatusInternalServerError, "Error actualizando")
+	default:
+		c.Redirect(http.StatusFound, "/admin/config")
+	}
+}
+
+func containsID[T any](items []T, id int, idOf func(T) int) bool {
+	for _, item := range items {
+		if idOf(item) == id {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,561 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPIBody es el tamaño máximo de un cuerpo JSON de la API de administración;
+// alcanza para un lote de varios miles de mesas
+const maxAPIBody = 1 << 20
+
+// AdminAPIHandler sirve /api/admin/v1: el ABM del panel en JSON, para scripts e
+// integraciones. Se autentica con tokens (TokenAuth) y cada ruta pide un alcance.
+type AdminAPIHandler struct {
+	Repo       *repository.MesaRepository
+	ParamsRepo *repository.ParamsRepository
+}
+
+func NewAdminAPIHandler(repo *repository.MesaRepository, paramsRepo *repository.ParamsRepository) *AdminAPIHandler {
+	return &AdminAPIHandler{Repo: repo, ParamsRepo: paramsRepo}
+}
+
+// apiActor es quien figura en la auditoría: el usuario y el token que usó
+func apiActor(c *gin.Context) string {
+	return fmt.Sprintf("%s (API: %s)", currentUser(c).Email, currentToken(c).Nombre)
+}
+
+func (h *AdminAPIHandler) mesas(c *gin.Context) *repository.MesaRepository {
+	return h.Repo.As(apiActor(c))
+}
+
+func (h *AdminAPIHandler) params(c *gin.Context) *repository.ParamsRepository {
+	return h.ParamsRepo.As(apiActor(c))
+}
+
+func validationError(c *gin.Context, errs fieldErrors) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "datos inválidos", "fields": errs})
+}
+
+// bindJSON lee el cuerpo en dst sin aceptar campos desconocidos. Si falla ya respondió:
+// 400 si no es JSON y 422 si un campo no existe o tiene otro tipo.
+func bindJSON(c *gin.Context, dst any) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxAPIBody))
+	if err != nil {
+		apiError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("el cuerpo supera los %d bytes", maxAPIBody))
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(dst)
+	if err == nil {
+		return true
+	}
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		validationError(c, fieldErrors{typeErr.Field: "tipo inválido: se esperaba " + typeErr.Type.String()})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		validationError(c, fieldErrors{field: "campo desconocido"})
+	default:
+		apiError(c, http.StatusBadRequest, "JSON inválido: "+err.Error())
+	}
+	return false
+}
+
+// idParam lee el :id de la ruta; si no es un número responde 400
+func idParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		apiError(c, http.StatusBadRequest, "id inválido")
+		return 0, false
+	}
+	return id, true
+}
+
+// --- Mesas ---
+
+// CreateMesa sirve POST /mesas y responde la mesa creada
+func (h *AdminAPIHandler) CreateMesa(c *gin.Context) {
+	var in mesaInput
+	if !bindJSON(c, &in) {
+		return
+	}
+	errs := fieldErrors{}
+	mesa := in.mesa("", errs)
+	if !h.checkMesas(c, errs, []models.Mesa{mesa}, false) {
+		return
+	}
+	if !currentUser(c).CanEditCarrera(mesa.CarreraID) {
+		apiError(c, http.StatusForbidden, "no tenés permisos sobre esta carrera")
+		return
+	}
+
+	mesa.FechaEdicion = time.Now().Format("2006-01-02 15:04:05")
+	id, err := h.mesas(c).Create(mesa)
+	if err != nil {
+		internalError(c, "creando la mesa", err)
+		return
+	}
+	h.respondMesa(c, http.StatusCreated, id)
+}
+
+// CreateMesas sirve POST /mesas/lote: un arreglo de mesas que se guardan todas o ninguna
+func (h *AdminAPIHandler) CreateMesas(c *gin.Context) {
+	var in []mesaInput
+	if !bindJSON(c, &in) {
+		return
+	}
+	if len(in) == 0 {
+		apiError(c, http.StatusBadRequest, "el lote está vacío")
+		return
+	}
+	errs := fieldErrors{}
+	user := currentUser(c)
+	now := time.Now().Format("2006-01-02 15:04:05")
+	mesas := make([]models.Mesa, len(in))
+	for i, m := range in {
+		mesas[i] = m.mesa(fmt.Sprintf("[%d].", i), errs)
+		mesas[i].FechaEdicion = now
+	}
+	if !h.checkMesas(c, errs, mesas, true) {
+		return
+	}
+	for i, m := range mesas {
+		if !user.CanEditCarrera(m.CarreraID) {
+			apiError(c, http.StatusForbidden, fmt.Sprintf("mesa %d: no tenés permisos sobre la carrera %d", i, m.CarreraID))
+			return
+		}
+	}
+
+	if err := h.mesas(c).CreateBatch(mesas); err != nil {
+		internalError(c, "creando el lote de mesas", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"created": len(mesas)})
+}
+
+// UpdateMesa sirve PUT /mesas/:id: reemplaza la mesa completa
+func (h *AdminAPIHandler) UpdateMesa(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	var in mesaInput
+	if !bindJSON(c, &in) {
+		return
+	}
+	errs := fieldErrors{}
+	mesa := in.mesa("", errs)
+	if !h.checkMesas(c, errs, []models.Mesa{mesa}, false) {
+		return
+	}
+	mesa.ID = id
+
+	existing, err := h.Repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(c, http.StatusNotFound, "mesa no encontrada")
+		return
+	}
+	if err != nil {
+		internalError(c, "leyendo mesa", err)
+		return
+	}
+	// Secretaría solo puede mover mesas dentro de su propia carrera
+	user := currentUser(c)
+	if !user.CanEditCarrera(existing.CarreraID) || !user.CanEditCarrera(mesa.CarreraID) {
+		apiError(c, http.StatusForbidden, "no tenés permisos sobre esta carrera")
+		return
+	}
+
+	if err := h.mesas(c).Update(mesa); err != nil {
+		internalError(c, "actualizando la mesa", err)
+		return
+	}
+	h.respondMesa(c, http.StatusOK, id)
+}
+
+// DeleteMesa sirve DELETE /mesas/:id
+func (h *AdminAPIHandler) DeleteMesa(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	mesa, err := h.Repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(c, http.StatusNotFound, "mesa no encontrada")
+		return
+	}
+	if err != nil {
+		internalError(c, "leyendo mesa", err)
+		return
+	}
+	if !currentUser(c).CanEditCarrera(mesa.CarreraID) {
+		apiError(c, http.StatusForbidden, "no tenés permisos sobre esta carrera")
+		return
+	}
+	if err := h.mesas(c).Delete(strconv.Itoa(id)); err != nil {
+		internalError(c, "eliminando la mesa", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkMesas completa errs con las referencias y fechas de las mesas; en un lote los
+// campos llevan el índice. Si algo no es válido ya respondió 422 (o 500 si falló la base).
+func (h *AdminAPIHandler) checkMesas(c *gin.Context, errs fieldErrors, mesas []models.Mesa, lote bool) bool {
+	checker := newMesaChecker(h.ParamsRepo)
+	for i, m := range mesas {
+		prefix := ""
+		if lote {
+			prefix = fmt.Sprintf("[%d].", i)
+		}
+		if err := checker.check(m, prefix, errs); err != nil {
+			internalError(c, "validando mesas", err)
+			return false
+		}
+	}
+	if len(errs) > 0 {
+		validationError(c, errs)
+		return false
+	}
+	return true
+}
+
+// respondMesa responde la mesa como quedó en la base, con sus nombres
+func (h *AdminAPIHandler) respondMesa(c *gin.Context, status, id int) {
+	mesa, err := h.Repo.GetByID(id)
+	if err != nil {
+		internalError(c, "leyendo mesa", err)
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/admin/v1/mesas/%d", id))
+	c.JSON(status, mesa)
+}
+
+// --- Materias, carreras, sedes y aulas ---
+
+// paramInput es el cuerpo de alta y edición de un parámetro; SedeID solo lo usan las aulas
+type paramInput struct {
+	Nombre string `json:"nombre"`
+	SedeID int    `json:"sede_id"`
+}
+
+// paramOps son las operaciones de ParamsRepository de cada tipo de parámetro
+type paramOps struct {
+	get    func(r *repository.ParamsRepository, id int) (any, error)
+	create func(r *repository.ParamsRepository, in paramInput) (int, error)
+	update func(r *repository.ParamsRepository, id int, in paramInput) error
+	delete func(r *repository.ParamsRepository, id int, cascada bool) error
+}
+
+// paramRoutes son los tipos de parámetro de la API, por el nombre de su ruta
+var paramRoutes = map[string]string{"materias": "materia", "carreras": "carrera", "sedes": "sede", "aulas": "aula"}
+
+var paramAPI = map[string]paramOps{
+	"materia": {
+		get:    func(r *repository.ParamsRepository, id int) (any, error) { return r.GetMateria(id) },
+		create: func(r *repository.ParamsRepository, in paramInput) (int, error) { return r.CreateMateria(in.Nombre) },
+		update: func(r *repository.ParamsRepository, id int, in paramInput) error {
+			return r.UpdateMateria(id, in.Nombre)
+		},
+		delete: (*repository.ParamsRepository).DeleteMateria,
+	},
+	"carrera": {
+		get:    func(r *repository.ParamsRepository, id int) (any, error) { return r.GetCarrera(id) },
+		create: func(r *repository.ParamsRepository, in paramInput) (int, error) { return r.CreateCarrera(in.Nombre) },
+		update: func(r *repository.ParamsRepository, id int, in paramInput) error {
+			return r.UpdateCarrera(id, in.Nombre)
+		},
+		delete: (*repository.ParamsRepository).DeleteCarrera,
+	},
+	"sede": {
+		get:    func(r *repository.ParamsRepository, id int) (any, error) { return r.GetSede(id) },
+		create: func(r *repository.ParamsRepository, in paramInput) (int, error) { return r.CreateSede(in.Nombre) },
+		update: func(r *repository.ParamsRepository, id int, in paramInput) error { return r.UpdateSede(id, in.Nombre) },
+		delete: (*repository.ParamsRepository).DeleteSede,
+	},
+	"aula": {
+		get: func(r *repository.ParamsRepository, id int) (any, error) { return r.GetAula(id) },
+		create: func(r *repository.ParamsRepository, in paramInput) (int, error) {
+			return r.CreateAula(in.Nombre, in.SedeID)
+		},
+		update: func(r *repository.ParamsRepository, id int, in paramInput) error {
+			return r.UpdateAula(id, in.Nombre, in.SedeID)
+		},
+		delete: (*repository.ParamsRepository).DeleteAula,
+	},
+}
+
+// validParam revisa el nombre y, en las aulas, que la sede exista (0 es sin sede)
+func (h *AdminAPIHandler) validParam(c *gin.Context, tipo string, in *paramInput) bool {
+	errs := fieldErrors{}
+	in.Nombre = strings.TrimSpace(in.Nombre)
+	if in.Nombre == "" {
+		errs.add("nombre", "obligatorio")
+	}
+	if tipo == "aula" && in.SedeID != 0 {
+		if _, err := h.ParamsRepo.GetSede(in.SedeID); errors.Is(err, sql.ErrNoRows) {
+			errs.add("sede_id", "la sede no existe")
+		} else if err != nil {
+			internalError(c, "leyendo sede", err)
+			return false
+		}
+	} else if in.SedeID != 0 {
+		errs.add("sede_id", "solo se usa en las aulas")
+	}
+	if len(errs) > 0 {
+		validationError(c, errs)
+		return false
+	}
+	return true
+}
+
+// GetParam sirve GET /<tipo>/:id
+func (h *AdminAPIHandler) GetParam(tipo string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		h.respondParam(c, http.StatusOK, tipo, id)
+	}
+}
+
+// CreateParam sirve POST /<tipo> y responde el elemento creado
+func (h *AdminAPIHandler) CreateParam(tipo string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in paramInput
+		if !bindJSON(c, &in) || !h.validParam(c, tipo, &in) {
+			return
+		}
+		id, err := paramAPI[tipo].create(h.params(c), in)
+		if err != nil {
+			internalError(c, "creando "+tipo, err)
+			return
+		}
+		c.Header("Location", fmt.Sprintf("%s/%d", c.Request.URL.Path, id))
+		h.respondParam(c, http.StatusCreated, tipo, id)
+	}
+}
+
+// UpdateParam sirve PUT /<tipo>/:id
+func (h *AdminAPIHandler) UpdateParam(tipo string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		var in paramInput
+		if !bindJSON(c, &in) || !h.validParam(c, tipo, &in) {
+			return
+		}
+		err := paramAPI[tipo].update(h.params(c), id, in)
+		if errors.Is(err, sql.ErrNoRows) {
+			apiError(c, http.StatusNotFound, tipo+" no encontrada")
+			return
+		}
+		if err != nil {
+			internalError(c, "actualizando "+tipo, err)
+			return
+		}
+		h.respondParam(c, http.StatusOK, tipo, id)
+	}
+}
+
+// DeleteParam sirve DELETE /<tipo>/:id. Como en el panel, con mesas o usuarios no se
+// puede borrar (409, hay que archivar) y si arrastra aulas o filas del plan hace falta
+// ?cascada=true.
+func (h *AdminAPIHandler) DeleteParam(tipo string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		cascada, _ := strconv.ParseBool(c.Query("cascada"))
+		err := paramAPI[tipo].delete(h.params(c), id, cascada)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			apiError(c, http.StatusNotFound, tipo+" no encontrada")
+		case errors.Is(err, repository.ErrEnUso), errors.Is(err, repository.ErrConfirmarCascada):
+			deps, depsErr := h.ParamsRepo.GetDependencias(tipo, id)
+			if depsErr != nil {
+				internalError(c, "contando dependencias", depsErr)
+				return
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "dependencias": deps})
+		case err != nil:
+			internalError(c, "eliminando "+tipo, err)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+// ArchiveParam sirve POST /<tipo>/:id/archivar y RestoreParam POST /<tipo>/:id/restaurar
+func (h *AdminAPIHandler) ArchiveParam(tipo string) gin.HandlerFunc {
+	return h.setArchivado(tipo, (*repository.ParamsRepository).Archivar)
+}
+
+func (h *AdminAPIHandler) RestoreParam(tipo string) gin.HandlerFunc {
+	return h.setArchivado(tipo, (*repository.ParamsRepository).Restaurar)
+}
+
+func (h *AdminAPIHandler) setArchivado(tipo string, fn func(r *repository.ParamsRepository, tipo string, id int) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		err := fn(h.params(c), tipo, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			apiError(c, http.StatusNotFound, tipo+" no encontrada")
+			return
+		}
+		if err != nil {
+			internalError(c, "archivando "+tipo, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (h *AdminAPIHandler) respondParam(c *gin.Context, status int, tipo string, id int) {
+	v, err := paramAPI[tipo].get(h.ParamsRepo, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(c, http.StatusNotFound, tipo+" no encontrada")
+		return
+	}
+	if err != nil {
+		internalError(c, "leyendo "+tipo, err)
+		return
+	}
+	c.JSON(status, v)
+}
+
+// --- Turnos ---
+
+// ListTurnos sirve GET /turnos: todos los turnos, también los que ya pasaron
+func (h *AdminAPIHandler) ListTurnos(c *gin.Context) {
+	turnos, err := h.ParamsRepo.GetTurnoConfigs()
+	if err != nil {
+		internalError(c, "listando turnos", err)
+		return
+	}
+	respondPage(c, turnos)
+}
+
+// GetTurno sirve GET /turnos/:id
+func (h *AdminAPIHandler) GetTurno(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	h.respondTurno(c, http.StatusOK, id)
+}
+
+// CreateTurno sirve POST /turnos
+func (h *AdminAPIHandler) CreateTurno(c *gin.Context) {
+	var in turnoInput
+	if !bindJSON(c, &in) {
+		return
+	}
+	t, errs := in.turno()
+	if len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+	id, err := h.params(c).CreateTurnoConfig(t)
+	if err != nil {
+		internalError(c, "creando turno", err)
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/admin/v1/turnos/%d", id))
+	h.respondTurno(c, http.StatusCreated, id)
+}
+
+// UpdateTurno sirve PUT /turnos/:id
+func (h *AdminAPIHandler) UpdateTurno(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	var in turnoInput
+	if !bindJSON(c, &in) {
+		return
+	}
+	t, errs := in.turno()
+	if len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+	t.ID = id
+	err := h.params(c).UpdateTurnoConfig(t)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(c, http.StatusNotFound, "turno no encontrado")
+		return
+	}
+	if errors.Is(err, repository.ErrMesasFueraDelTurno) {
+		errs, err = turnoMesasFuera(h.ParamsRepo, t)
+		if err == nil {
+			validationError(c, errs)
+			return
+		}
+	}
+	if err != nil {
+		internalError(c, "actualizando turno", err)
+		return
+	}
+	h.respondTurno(c, http.StatusOK, id)
+}
+
+// DeleteTurno sirve DELETE /turnos/:id. Un turno con mesas no se borra (409).
+func (h *AdminAPIHandler) DeleteTurno(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	err := h.params(c).DeleteTurnoConfig(id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apiError(c, http.StatusNotFound, "turno no encontrado")
+	case errors.Is(err, repository.ErrTurnoEnUso):
+		deps, depsErr := h.ParamsRepo.GetDependencias("turno", id)
+		if depsErr != nil {
+			internalError(c, "contando dependencias", depsErr)
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "dependencias": deps})
+	case err != nil:
+		internalError(c, "eliminando turno", err)
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (h *AdminAPIHandler) respondTurno(c *gin.Context, status, id int) {
+	t, err := h.ParamsRepo.GetTurnoConfig(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(c, http.StatusNotFound, "turno no encontrado")
+		return
+	}
+	if err != nil {
+		internalError(c, "leyendo turno", err)
+		return
+	}
+	c.JSON(status, t)
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+)
+
+// newToken crea un token de la API para el usuario sin pasar por el panel, así también
+// se pueden probar alcances que su rol no permite
+func newToken(t *testing.T, srv *testServer, email string, scopes ...string) string {
+	t.Helper()
+	user, err := repository.NewUserRepository(srv.DB).GetByEmail(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := repository.NewTokenRepository(srv.DB).Create(user.ID, "script de prueba", scopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+type apiResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// decode lee el cuerpo JSON en v
+func (r apiResponse) decode(t *testing.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(r.Body), v); err != nil {
+		t.Fatalf("cuerpo %q: %v", r.Body, err)
+	}
+}
+
+// adminAPI hace una request a /api/admin/v1 con el token
+func adminAPI(t *testing.T, srv *testServer, token, method, path, body string) apiResponse {
+	t.Helper()
+	req, _ := http.NewRequest(method, srv.URL+"/api/admin/v1"+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	return apiResponse{StatusCode: res.StatusCode, Header: res.Header, Body: string(b)}
+}
+
+func TestAdminAPIAuth(t *testing.T) {
+	srv := newTestServer(t)
+	token := newToken(t, srv, lecturaEmail, models.ScopeMesasRead)
+
+	res := adminAPI(t, srv, "", http.MethodGet, "/mesas", "")
+	if res.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(res.Header.Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("sin token: status %d, WWW-Authenticate %q", res.StatusCode, res.Header.Get("WWW-Authenticate"))
+	}
+	if res := adminAPI(t, srv, repository.TokenPrefix+"inventado", http.MethodGet, "/mesas", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token inventado: status %d, want 401", res.StatusCode)
+	}
+	if res := adminAPI(t, srv, token, http.MethodGet, "/mesas", ""); res.StatusCode != http.StatusOK {
+		t.Errorf("token válido: status %d, want 200", res.StatusCode)
+	}
+
+	// Revocado desde el panel deja de valer
+	c := srv.login(t, lecturaEmail)
+	if res := c.post("/admin/cuenta/tokens/1/revocar", nil); res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("revocar: status %d", res.StatusCode)
+	}
+	if res := adminAPI(t, srv, token, http.MethodGet, "/mesas", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token revocado: status %d, want 401", res.StatusCode)
+	}
+
+	// Al borrar el usuario se borran sus tokens
+	token = newToken(t, srv, lecturaEmail, models.ScopeMesasRead)
+	if res := srv.login(t, superEmail).post("/admin/usuarios/3/delete", nil); res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("borrar usuario: status %d", res.StatusCode)
+	}
+	if tokens, _ := repository.NewTokenRepository(srv.DB).ListForUser(3); len(tokens) != 0 {
+		t.Errorf("quedaron %d tokens del usuario borrado", len(tokens))
+	}
+	if res := adminAPI(t, srv, token, http.MethodGet, "/mesas", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("usuario borrado: status %d, want 401", res.StatusCode)
+	}
+}
+
+func TestAdminAPIScopes(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	mesa := `{"materia_id": 2, "carrera_id": 1, "turno_id": 3, "aula_id": 3, "fecha": "2025-03-27", "hora": "10:00"}`
+
+	tests := []struct {
+		name       string
+		email      string
+		scopes     []string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"lectura sin permiso de escritura", lecturaEmail, []string{models.ScopeMesasRead}, http.MethodPost, "/mesas", mesa, http.StatusForbidden},
+		// El token pide más de lo que el rol permite: vale el rol
+		{"lectura con alcance de escritura", lecturaEmail, []string{models.ScopeMesasWrite}, http.MethodPost, "/mesas", mesa, http.StatusForbidden},
+		{"secretaría sin alcance de lectura", secretariaEmail, []string{models.ScopeMesasWrite}, http.MethodGet, "/mesas", "", http.StatusForbidden},
+		{"secretaría en su carrera", secretariaEmail, []string{models.ScopeMesasWrite}, http.MethodPost, "/mesas", mesa, http.StatusCreated},
+		{"secretaría en otra carrera", secretariaEmail, []string{models.ScopeMesasWrite}, http.MethodPut, "/mesas/" + mesaMatematica, mesa, http.StatusForbidden},
+		{"secretaría no toca parámetros", secretariaEmail, []string{models.ScopeParamsWrite}, http.MethodPost, "/materias", `{"nombre": "Química"}`, http.StatusForbidden},
+		{"superadmin con parámetros", superEmail, []string{models.ScopeParamsWrite}, http.MethodPost, "/materias", `{"nombre": "Química"}`, http.StatusCreated},
+		{"superadmin sin alcance de mesas", superEmail, []string{models.ScopeParamsWrite}, http.MethodDelete, "/mesas/" + mesaSistemas, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := newToken(t, srv, tt.email, tt.scopes...)
+			if res := adminAPI(t, srv, token, tt.method, tt.path, tt.body); res.StatusCode != tt.wantStatus {
+				t.Errorf("status %d, want %d: %s", res.StatusCode, tt.wantStatus, res.Body)
+			}
+		})
+	}
+}
+
+func TestAdminAPIMesas(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	token := newToken(t, srv, secretariaEmail, models.ScopeMesasRead, models.ScopeMesasWrite)
+
+	res := adminAPI(t, srv, token, http.MethodPost, "/mesas", `{"materia_id": 2, "carrera_id": 1, "turno_id": 3, "aula_id": 5, "fecha": "2025-03-26", "hora": "10:00"}`)
+	var created models.Mesa
+	res.decode(t, &created)
+	if res.StatusCode != http.StatusCreated || created.ID != 3 || created.Materia != "Análisis Matemático I" || created.Aula != "Laboratorio 1" {
+		t.Fatalf("alta: status %d, mesa %+v", res.StatusCode, created)
+	}
+	if loc := res.Header.Get("Location"); loc != "/api/admin/v1/mesas/3" {
+		t.Errorf("Location = %q", loc)
+	}
+
+	res = adminAPI(t, srv, token, http.MethodPut, "/mesas/3", `{"materia_id": 2, "carrera_id": 1, "turno_id": 3, "aula_id": 5, "fecha": "2025-03-28", "hora": "14:30"}`)
+	var updated models.Mesa
+	res.decode(t, &updated)
+	if res.StatusCode != http.StatusOK || updated.Fecha.String() != "2025-03-28" || updated.Hora.String() != "14:30" {
+		t.Errorf("edición: status %d, mesa %+v", res.StatusCode, updated)
+	}
+	if res := adminAPI(t, srv, token, http.MethodPut, "/mesas/999", `{"materia_id": 2, "carrera_id": 1}`); res.StatusCode != http.StatusNotFound {
+		t.Errorf("edición de inexistente: status %d, want 404", res.StatusCode)
+	}
+
+	if res := adminAPI(t, srv, token, http.MethodDelete, "/mesas/3", ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("baja: status %d, want 204", res.StatusCode)
+	}
+	if res := adminAPI(t, srv, token, http.MethodGet, "/mesas/3", ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("después de la baja: status %d, want 404", res.StatusCode)
+	}
+
+	// La auditoría registra el usuario y el token
+	entries, err := repository.NewAuditRepository(srv.DB).List(repository.AuditFilter{Entity: "mesa", Actor: "API"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Actor != secretariaEmail+" (API: script de prueba)" {
+		t.Errorf("auditoría = %+v", entries)
+	}
+}
+
+func TestAdminAPIValidation(t *testing.T) {
+	srv := newTestServer(t)
+	token := newToken(t, srv, superEmail, models.Scopes...)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantFields []string
+	}{
+		{"JSON roto", http.MethodPost, "/mesas", `{"materia_id": `, http.StatusBadRequest, nil},
+		{"campos obligatorios y formatos", http.MethodPost, "/mesas", `{"carrera_id": 1, "fecha": "04/05/2099", "hora": "25:00"}`, http.StatusUnprocessableEntity, []string{"materia_id", "fecha", "hora"}},
+		{"tipo equivocado", http.MethodPost, "/mesas", `{"materia_id": "Álgebra", "carrera_id": 1}`, http.StatusUnprocessableEntity, []string{"materia_id"}},
+		{"campo desconocido", http.MethodPost, "/mesas", `{"materia_id": 1, "carrera_id": 1, "aula": "Magna"}`, http.StatusUnprocessableEntity, []string{"aula"}},
+		{"lote con una mesa inválida", http.MethodPost, "/mesas/lote", `[{"materia_id": 1, "carrera_id": 1}, {"materia_id": 1, "carrera_id": 1, "fecha": "mañana"}]`, http.StatusUnprocessableEntity, []string{"[1].fecha"}},
+		{"referencias inexistentes", http.MethodPost, "/mesas", `{"materia_id": 99, "carrera_id": 1, "turno_id": 99, "aula_id": 99}`, http.StatusUnprocessableEntity, []string{"materia_id", "turno_id", "aula_id"}},
+		{"fecha fuera del turno", http.MethodPost, "/mesas", `{"materia_id": 1, "carrera_id": 1, "turno_id": 1, "fecha": "2025-03-01"}`, http.StatusUnprocessableEntity, []string{"fecha"}},
+		{"lote con una carrera inexistente", http.MethodPost, "/mesas/lote", `[{"materia_id": 1, "carrera_id": 1}, {"materia_id": 1, "carrera_id": 99}]`, http.StatusUnprocessableEntity, []string{"[1].carrera_id"}},
+		{"lote vacío", http.MethodPost, "/mesas/lote", `[]`, http.StatusBadRequest, nil},
+		{"nombre vacío", http.MethodPost, "/sedes", `{"nombre": "  "}`, http.StatusUnprocessableEntity, []string{"nombre"}},
+		{"aula en sede inexistente", http.MethodPost, "/aulas", `{"nombre": "Aula 9", "sede_id": 99}`, http.StatusUnprocessableEntity, []string{"sede_id"}},
+		{"sede en una materia", http.MethodPut, "/materias/1", `{"nombre": "Álgebra", "sede_id": 1}`, http.StatusUnprocessableEntity, []string{"sede_id"}},
+		{"turno que termina antes de empezar", http.MethodPost, "/turnos", `{"nombre": "Especial", "fecha_inicio": "2099-08-07", "fecha_fin": "2099-08-03"}`, http.StatusUnprocessableEntity, []string{"fecha_fin"}},
+		{"id inválido", http.MethodPut, "/carreras/abc", `{"nombre": "x"}`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := adminAPI(t, srv, token, tt.method, tt.path, tt.body)
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", res.StatusCode, tt.wantStatus, res.Body)
+			}
+			var body struct {
+				Error  string            `json:"error"`
+				Fields map[string]string `json:"fields"`
+			}
+			res.decode(t, &body)
+			if body.Error == "" || len(body.Fields) != len(tt.wantFields) {
+				t.Errorf("error %q, campos %v, want %v", body.Error, body.Fields, tt.wantFields)
+			}
+			for _, f := range tt.wantFields {
+				if body.Fields[f] == "" {
+					t.Errorf("falta el error de %q en %v", f, body.Fields)
+				}
+			}
+		})
+	}
+
+	// Nada del lote inválido quedó guardado
+	if mesas, _ := repository.NewMesaRepository(srv.DB).GetAll(); len(mesas) != 0 {
+		t.Errorf("se guardaron %d mesas", len(mesas))
+	}
+}
+
+func TestAdminAPIBatch(t *testing.T) {
+	srv := newTestServer(t)
+	token := newToken(t, srv, secretariaEmail, models.ScopeMesasWrite)
+
+	lote := `[
+		{"materia_id": 1, "carrera_id": 1, "turno_id": 1, "aula_id": 2, "fecha": "2025-02-18", "hora": "08:00"},
+		{"materia_id": 2, "carrera_id": 1, "turno_id": 1}
+	]`
+	res := adminAPI(t, srv, token, http.MethodPost, "/mesas/lote", lote)
+	if res.StatusCode != http.StatusCreated || !strings.Contains(res.Body, `"created":2`) {
+		t.Errorf("lote: status %d %s", res.StatusCode, res.Body)
+	}
+
+	// Una sola mesa de otra carrera rechaza el lote entero
+	res = adminAPI(t, srv, token, http.MethodPost, "/mesas/lote", `[{"materia_id": 1, "carrera_id": 1}, {"materia_id": 1, "carrera_id": 2}]`)
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("lote con otra carrera: status %d, want 403", res.StatusCode)
+	}
+	if mesas, _ := repository.NewMesaRepository(srv.DB).GetAll(); len(mesas) != 2 {
+		t.Errorf("hay %d mesas, want 2", len(mesas))
+	}
+}
+
+func TestAdminAPIParams(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	token := newToken(t, srv, superEmail, models.ScopeParamsRead, models.ScopeParamsWrite)
+
+	res := adminAPI(t, srv, token, http.MethodPost, "/aulas", `{"nombre": "Aula 3 - PA", "sede_id": 1}`)
+	var aula models.Aula
+	res.decode(t, &aula)
+	if res.StatusCode != http.StatusCreated || aula.ID == 0 || aula.SedeID != 1 || res.Header.Get("Location") == "" {
+		t.Fatalf("alta de aula: status %d, %+v", res.StatusCode, aula)
+	}
+
+	res = adminAPI(t, srv, token, http.MethodPut, "/carreras/2", `{"nombre": "Lic. en Matemática"}`)
+	var carrera models.Carrera
+	res.decode(t, &carrera)
+	if res.StatusCode != http.StatusOK || carrera.Nombre != "Lic. en Matemática" {
+		t.Errorf("edición de carrera: status %d, %+v", res.StatusCode, carrera)
+	}
+	if res := adminAPI(t, srv, token, http.MethodGet, "/sedes/99", ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("sede inexistente: status %d, want 404", res.StatusCode)
+	}
+
+	// Con mesas no se borra: 409 con las dependencias, y se archiva
+	res = adminAPI(t, srv, token, http.MethodDelete, "/materias/1", "")
+	var conflict struct {
+		Error        string              `json:"error"`
+		Dependencias models.Dependencias `json:"dependencias"`
+	}
+	res.decode(t, &conflict)
+	if res.StatusCode != http.StatusConflict || conflict.Dependencias.Mesas != 2 {
+		t.Errorf("baja con mesas: status %d, %+v", res.StatusCode, conflict)
+	}
+	res = adminAPI(t, srv, token, http.MethodDelete, "/turnos/1", "")
+	res.decode(t, &conflict)
+	if res.StatusCode != http.StatusConflict || conflict.Dependencias.Mesas != 2 {
+		t.Errorf("baja de turno con mesas: status %d, %+v", res.StatusCode, conflict)
+	}
+	if res := adminAPI(t, srv, token, http.MethodPost, "/materias/1/archivar", ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("archivar: status %d, want 204", res.StatusCode)
+	}
+	if res := adminAPI(t, srv, token, http.MethodGet, "/materias?q=algebra", ""); strings.Contains(res.Body, "Álgebra I") {
+		t.Errorf("la materia archivada sigue en el listado: %s", res.Body)
+	}
+	if res := adminAPI(t, srv, token, http.MethodPost, "/materias/1/restaurar", ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("restaurar: status %d, want 204", res.StatusCode)
+	}
+
+	// Arrastra filas del plan: hace falta ?cascada=true
+	if res := adminAPI(t, srv, token, http.MethodDelete, "/materias/4", ""); res.StatusCode != http.StatusConflict {
+		t.Errorf("baja sin cascada: status %d, want 409", res.StatusCode)
+	}
+	if res := adminAPI(t, srv, token, http.MethodDelete, "/materias/4?cascada=true", ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("baja con cascada: status %d, want 204", res.StatusCode)
+	}
+}
+
+func TestAdminAPITurnos(t *testing.T) {
+	srv := newTestServer(t)
+	token := newToken(t, srv, superEmail, models.ScopeParamsRead, models.ScopeParamsWrite)
+
+	res := adminAPI(t, srv, token, http.MethodPost, "/turnos", `{"nombre": "Turno Especial", "fecha_inicio": "2099-08-03", "fecha_fin": "2099-08-07"}`)
+	var turno models.TurnoConfig
+	res.decode(t, &turno)
+	if res.StatusCode != http.StatusCreated || turno.ID == 0 || turno.FechaFin.String() != "2099-08-07" {
+		t.Fatalf("alta: status %d, %+v", res.StatusCode, turno)
+	}
+
+	path := "/turnos/" + res.Header.Get("Location")[strings.LastIndex(res.Header.Get("Location"), "/")+1:]
+	res = adminAPI(t, srv, token, http.MethodPut, path, `{"nombre": "Turno Especial", "fecha_inicio": "2099-08-03", "fecha_fin": "2099-08-14", "receso": true}`)
+	res.decode(t, &turno)
+	if res.StatusCode != http.StatusOK || !turno.Receso || turno.FechaFin.String() != "2099-08-14" {
+		t.Errorf("edición: status %d, %+v", res.StatusCode, turno)
+	}
+
+	// Achicarlo no puede dejar afuera sus mesas
+	fecha, _ := models.ParseDate("2099-08-10")
+	mesas := repository.NewMesaRepository(srv.DB)
+	mesaID, err := mesas.Create(models.Mesa{MateriaID: 1, CarreraID: 1, TurnoID: turno.ID, AulaID: 2, Fecha: fecha})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = adminAPI(t, srv, token, http.MethodPut, path, `{"nombre": "Turno Especial", "fecha_inicio": "2099-08-03", "fecha_fin": "2099-08-07"}`)
+	var invalid struct {
+		Fields map[string]string `json:"fields"`
+	}
+	res.decode(t, &invalid)
+	if res.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(invalid.Fields["fecha_fin"], "Hay 1 mesa(s)") {
+		t.Errorf("edición con mesas afuera: status %d, %+v", res.StatusCode, invalid)
+	}
+	if err := mesas.Delete(strconv.Itoa(mesaID)); err != nil {
+		t.Fatal(err)
+	}
+
+	// El listado trae también los turnos pasados, que la API pública no muestra
+	res = adminAPI(t, srv, token, http.MethodGet, "/turnos?per_page=200", "")
+	var page struct {
+		Pagination pagination `json:"pagination"`
+	}
+	res.decode(t, &page)
+	if page.Pagination.Total != 11 {
+		t.Errorf("turnos = %d, want 11", page.Pagination.Total)
+	}
+
+	if res := adminAPI(t, srv, token, http.MethodDelete, path, ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("baja: status %d, want 204", res.StatusCode)
+	}
+	if res := adminAPI(t, srv, token, http.MethodGet, path, ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("después de la baja: status %d, want 404", res.StatusCode)
+	}
+}
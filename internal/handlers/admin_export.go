@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+	"mi-bot-unne/internal/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+)
+
+var exportFormats = map[string]struct {
+	contentType string
+	newWriter   func(io.Writer) (spreadsheet.MesaWriter, error)
+}{
+	"csv":  {"text/csv; charset=utf-8", spreadsheet.NewCSVWriter},
+	"json": {"application/json; charset=utf-8", spreadsheet.NewJSONWriter},
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", spreadsheet.NewXLSXWriter},
+}
+
+// ExportMesas descarga el calendario completo en CSV, JSON o XLSX.
+// Acepta los filtros opcionales ?turno_id=, ?carrera_id= y ?sede_id=.
+func (h *AdminHandler) ExportMesas(c *gin.Context) {
+	format, ok := exportFormats[c.Param("format")]
+	if !ok {
+		c.String(http.StatusBadRequest, "Formato inválido")
+		return
+	}
+
+	var filter repository.MesaFilter
+	for param, dst := range map[string]*int{
+		"turno_id":   &filter.TurnoID,
+		"carrera_id": &filter.CarreraID,
+		"sede_id":    &filter.SedeID,
+	} {
+		if v := c.Query(param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				c.String(http.StatusBadRequest, param+" inválido")
+				return
+			}
+			*dst = id
+		}
+	}
+
+	filename := "mesas-" + time.Now().Format("20060102") + "." + c.Param("format")
+	c.Header("Content-Type", format.contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	w, err := format.newWriter(c.Writer)
+	if err != nil {
+		logger(c).Error("exportando mesas", "format", c.Param("format"), "error", err)
+		return
+	}
+	err = h.Repo.Each(filter, func(m models.Mesa) error {
+		return w.Write(m)
+	})
+	if err != nil {
+		// Los encabezados ya se enviaron; solo queda registrar el corte
+		logger(c).Error("exportando mesas", "format", c.Param("format"), "error", err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		logger(c).Error("exportando mesas", "format", c.Param("format"), "error", err)
+	}
+}
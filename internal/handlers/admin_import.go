@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+)
+
+const maxImportSize = 10 << 20 // 10 MB
+
+func (h *AdminHandler) ShowImport(c *gin.Context) {
+	renderHTML(c, http.StatusOK, "admin_import.html", gin.H{})
+}
+
+// PreviewImport parsea la planilla subida y muestra un dry-run con los errores por fila.
+// Nada se guarda hasta que se confirma con ConfirmImport.
+func (h *AdminHandler) PreviewImport(c *gin.Context) {
+	// BodyLimit ya acotó el cuerpo; acá se revisa solo el archivo
+	fileHeader, err := c.FormFile("archivo")
+	if err != nil {
+		renderHTML(c, http.StatusBadRequest, "admin_import.html", gin.H{"error": "Seleccioná un archivo CSV o XLSX"})
+		return
+	}
+	if fileHeader.Size > maxImportSize {
+		renderHTML(c, http.StatusBadRequest, "admin_import.html", gin.H{"error": "El archivo supera los 10 MB"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		renderHTML(c, http.StatusBadRequest, "admin_import.html", gin.H{"error": "No se pudo leer el archivo"})
+		return
+	}
+	defer file.Close()
+
+	rows, err := spreadsheet.ReadRows(file, fileHeader.Filename)
+	if err != nil {
+		renderHTML(c, http.StatusBadRequest, "admin_import.html", gin.H{"error": err.Error()})
+		return
+	}
+
+	catalog, err := h.importCatalog()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error leyendo parámetros")
+		return
+	}
+
+	parsed, err := spreadsheet.ParseMesas(rows, catalog)
+	if err != nil {
+		renderHTML(c, http.StatusBadRequest, "admin_import.html", gin.H{"error": err.Error()})
+		return
+	}
+
+	invalid, err := h.checkImport(c, parsed)
+	if err != nil {
+		logger(c).Error("validando la importación", "error", err)
+		c.String(http.StatusInternalServerError, "Error leyendo parámetros")
+		return
+	}
+
+	data := gin.H{
+		"filename": fileHeader.Filename,
+		"rows":     parsed,
+		"total":    len(parsed),
+		"invalid":  invalid,
+	}
+
+	// Solo se guarda el lote para confirmar si no tiene errores
+	if invalid == 0 {
+		token := newImportToken()
+		h.PendingImports.Set(token, pendingImport{userID: currentUser(c).ID, filename: fileHeader.Filename, rows: parsed}, cache.DefaultExpiration)
+		data["token"] = token
+	}
+
+	renderHTML(c, http.StatusOK, "admin_import.html", data)
+}
+
+// pendingImport es un lote validado en la vista previa que espera confirmación. Solo lo
+// puede confirmar quien subió la planilla, con sus permisos.
+type pendingImport struct {
+	userID   int
+	filename string
+	rows     []spreadsheet.ImportRow
+}
+
+// ConfirmImport guarda en una sola transacción el lote previamente validado. Las filas se
+// vuelven a validar porque los parámetros pueden haber cambiado desde la vista previa.
+func (h *AdminHandler) ConfirmImport(c *gin.Context) {
+	token := c.PostForm("token")
+	pending, err := h.claimImport(token, currentUser(c).ID)
+	switch {
+	case errors.Is(err, errImportAjeno):
+		renderHTML(c, http.StatusForbidden, "admin_import.html", gin.H{"error": "Esta vista previa la generó otro usuario. Subí el archivo vos para importarlo."})
+		return
+	case err != nil:
+		renderHTML(c, http.StatusBadRequest, "admin_import.html", gin.H{"error": "La vista previa expiró. Volvé a subir el archivo."})
+		return
+	}
+	// Se valida una copia: el lote queda intacto por si hay que devolverlo al cache
+	rows := cloneImportRows(pending.rows)
+	invalid, err := h.checkImport(c, rows)
+	if err != nil {
+		h.PendingImports.Set(token, pending, cache.DefaultExpiration)
+		logger(c).Error("validando la importación", "error", err)
+		c.String(http.StatusInternalServerError, "Error leyendo parámetros")
+		return
+	}
+	if invalid > 0 {
+		renderHTML(c, http.StatusBadRequest, "admin_import.html", gin.H{
+			"error":    "Los parámetros cambiaron desde la vista previa y hay filas con errores. No se importó ninguna fila.",
+			"filename": pending.filename,
+			"rows":     rows,
+			"total":    len(rows),
+			"invalid":  invalid,
+		})
+		return
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	mesas := make([]models.Mesa, len(rows))
+	for i, r := range rows {
+		mesas[i] = r.Mesa
+		mesas[i].FechaEdicion = now
+	}
+
+	if err := h.mesas(c).CreateBatch(mesas); err != nil {
+		logger(c).Error("importando mesas", "filas", len(mesas), "error", err)
+		// No se guardó nada: el lote vuelve al cache para poder reintentar
+		h.PendingImports.Set(token, pending, cache.DefaultExpiration)
+		renderHTML(c, http.StatusInternalServerError, "admin_import.html", gin.H{"error": "Error guardando las mesas. No se importó ninguna fila."})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin")
+}
+
+var (
+	errImportVencido = errors.New("la vista previa no existe o expiró")
+	errImportAjeno   = errors.New("la vista previa es de otro usuario")
+)
+
+// claimImport saca el lote del cache. Con dos confirmaciones del mismo token (doble
+// clic, dos pestañas) solo una lo obtiene; la otra lo ve como vencido. Si el lote es de
+// otro usuario queda en el cache para su dueño.
+func (h *AdminHandler) claimImport(token string, userID int) (pendingImport, error) {
+	h.importMu.Lock()
+	defer h.importMu.Unlock()
+	cached, ok := h.PendingImports.Get(token)
+	if !ok {
+		return pendingImport{}, errImportVencido
+	}
+	pending := cached.(pendingImport)
+	if pending.userID != userID {
+		return pendingImport{}, errImportAjeno
+	}
+	h.PendingImports.Delete(token)
+	return pending, nil
+}
+
+func cloneImportRows(rows []spreadsheet.ImportRow) []spreadsheet.ImportRow {
+	clone := slices.Clone(rows)
+	for i := range clone {
+		clone[i].Errors = slices.Clone(clone[i].Errors)
+	}
+	return clone
+}
+
+// importFields es el orden en que se muestran los errores de mesaChecker en cada fila
+var importFields = []string{"materia_id", "carrera_id", "turno_id", "aula_id", "fecha", "hora"}
+
+// checkImport valida las filas como el alta de mesas del panel: permisos sobre la carrera,
+// que existan las referencias y que la fecha caiga dentro del turno. Los errores se suman
+// a los de cada fila (sin repetir los de una vista previa anterior) y devuelve cuántas
+// filas tienen alguno.
+func (h *AdminHandler) checkImport(c *gin.Context, rows []spreadsheet.ImportRow) (int, error) {
+	user := currentUser(c)
+	checker := newMesaChecker(h.ParamsRepo)
+	errs := fieldErrors{}
+	invalid := 0
+	for i, r := range rows {
+		if r.Mesa.CarreraID != 0 && !user.CanEditCarrera(r.Mesa.CarreraID) {
+			rows[i].AddError("sin permisos sobre la carrera " + r.Mesa.Carrera)
+		}
+		prefix := fmt.Sprintf("fila %d.", r.Line)
+		if err := checker.check(r.Mesa, prefix, errs); err != nil {
+			return 0, err
+		}
+		for _, field := range importFields {
+			if msg, ok := errs[prefix+field]; ok {
+				rows[i].AddError(msg)
+			}
+		}
+		if !rows[i].Valid() {
+			invalid++
+		}
+	}
+	return invalid, nil
+}
+
+func (h *AdminHandler) importCatalog() (spreadsheet.Catalog, error) {
+	materias, err := h.ParamsRepo.GetAllMaterias()
+	if err != nil {
+		return spreadsheet.Catalog{}, err
+	}
+	carreras, err := h.ParamsRepo.GetAllCarreras()
+	if err != nil {
+		return spreadsheet.Catalog{}, err
+	}
+	turnos, err := h.ParamsRepo.GetTurnoConfigs()
+	if err != nil {
+		return spreadsheet.Catalog{}, err
+	}
+	aulas, err := h.ParamsRepo.GetAllAulas()
+	if err != nil {
+		return spreadsheet.Catalog{}, err
+	}
+	sedes, err := h.ParamsRepo.GetAllSedes()
+	if err != nil {
+		return spreadsheet.Catalog{}, err
+	}
+	return spreadsheet.NewCatalog(materias, carreras, turnos, aulas, sedes), nil
+}
+
+func newImportToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
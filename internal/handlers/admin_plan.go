@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShowPlan muestra el plan de estudios de la carrera elegida en ?carrera_id=
+// (por defecto la de la secretaría o la primera de la lista)
+func (h *AdminHandler) ShowPlan(c *gin.Context) {
+	user := currentUser(c)
+	carreras, err := h.ParamsRepo.GetAllCarreras()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error leyendo Carreras")
+		return
+	}
+
+	carreraID, _ := strconv.Atoi(c.Query("carrera_id"))
+	if carreraID == 0 {
+		carreraID = user.CarreraID
+	}
+	if carreraID == 0 && len(carreras) > 0 {
+		carreraID = carreras[0].ID
+	}
+
+	plan, err := h.ParamsRepo.GetPlan(carreraID)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error leyendo el plan")
+		return
+	}
+	materias, err := h.ParamsRepo.GetAllMaterias()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error leyendo Materias")
+		return
+	}
+
+	// Para agregar solo se ofrecen las materias que todavía no están en el plan
+	inPlan := map[int]bool{}
+	for _, p := range plan {
+		inPlan[p.MateriaID] = true
+	}
+	var disponibles []models.Materia
+	for _, m := range materias {
+		if !inPlan[m.ID] {
+			disponibles = append(disponibles, m)
+		}
+	}
+
+	renderHTML(c, http.StatusOK, "admin_plan.html", gin.H{
+		"user":       user,
+		"carreras":   carreras,
+		"carrera_id": carreraID,
+		"plan":       plan,
+		"materias":   disponibles,
+		"can_edit":   user.CanEditCarrera(carreraID),
+		"anios":      []int{1, 2, 3, 4, 5, 6},
+	})
+}
+
+func (h *AdminHandler) StorePlanMateria(c *gin.Context) {
+	p, ok := planMateriaFromForm(c)
+	if !ok {
+		return
+	}
+	p.CarreraID, _ = strconv.Atoi(c.PostForm("carrera_id"))
+	p.MateriaID, _ = strconv.Atoi(c.PostForm("materia_id"))
+	if p.CarreraID == 0 || p.MateriaID == 0 {
+		c.String(http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+	if !currentUser(c).CanEditCarrera(p.CarreraID) {
+		c.String(http.StatusForbidden, "No tenés permisos sobre esta carrera")
+		return
+	}
+
+	if err := h.params(c).CreatePlanMateria(p); err != nil {
+		if errors.Is(err, repository.ErrAlreadyInPlan) {
+			c.String(http.StatusConflict, "La materia ya está en el plan de esta carrera")
+			return
+		}
+		c.String(http.StatusInternalServerError, "Error agregando la materia al plan")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/plan?carrera_id="+strconv.Itoa(p.CarreraID))
+}
+
+func (h *AdminHandler) UpdatePlanMateria(c *gin.Context) {
+	old, ok := h.editablePlanMateria(c)
+	if !ok {
+		return
+	}
+	p, ok := planMateriaFromForm(c)
+	if !ok {
+		return
+	}
+	p.ID = old.ID
+
+	if err := h.params(c).UpdatePlanMateria(p); err != nil {
+		c.String(http.StatusInternalServerError, "Error actualizando el plan")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/plan?carrera_id="+strconv.Itoa(old.CarreraID))
+}
+
+func (h *AdminHandler) DeletePlanMateria(c *gin.Context) {
+	old, ok := h.editablePlanMateria(c)
+	if !ok {
+		return
+	}
+	if err := h.params(c).DeletePlanMateria(old.ID); err != nil {
+		c.String(http.StatusInternalServerError, "Error quitando la materia del plan")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/plan?carrera_id="+strconv.Itoa(old.CarreraID))
+}
+
+// editablePlanMateria carga la fila de :id y verifica que el usuario pueda editar su carrera
+func (h *AdminHandler) editablePlanMateria(c *gin.Context) (models.PlanMateria, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "ID inválido")
+		return models.PlanMateria{}, false
+	}
+	p, err := h.ParamsRepo.GetPlanMateria(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Materia del plan no encontrada")
+		return p, false
+	}
+	if !currentUser(c).CanEditCarrera(p.CarreraID) {
+		c.String(http.StatusForbidden, "No tenés permisos sobre esta carrera")
+		return p, false
+	}
+	return p, true
+}
+
+// planMateriaFromForm lee año, cuatrimestre y código (vacíos significan sin definir y anual)
+func planMateriaFromForm(c *gin.Context) (models.PlanMateria, bool) {
+	var p models.PlanMateria
+	p.Codigo = c.PostForm("codigo")
+	p.Anio, _ = strconv.Atoi(c.PostForm("anio"))
+	p.Cuatrimestre, _ = strconv.Atoi(c.PostForm("cuatrimestre"))
+	if p.Anio < 0 || p.Anio > 7 || p.Cuatrimestre < 0 || p.Cuatrimestre > 2 {
+		c.String(http.StatusBadRequest, "Año o cuatrimestre inválido")
+		return p, false
+	}
+	return p, true
+}
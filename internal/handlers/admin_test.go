@@ -0,0 +1,686 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+)
+
+// Mesas que crea seedPanel: una de Sistemas (la carrera de la secretaría) y una de Matemática
+const (
+	mesaSistemas   = "1"
+	mesaMatematica = "2"
+)
+
+// seedPanel carga las dos mesas que usan los tests del panel
+func seedPanel(t *testing.T, srv *testServer) {
+	t.Helper()
+	repo := repository.NewMesaRepository(srv.DB)
+	fecha, _ := models.ParseDate("2099-02-18")
+	hora, _ := models.ParseTimeOfDay("08:00")
+	for _, m := range []models.Mesa{
+		{MateriaID: 1, CarreraID: 1, TurnoID: 1, AulaID: 2, Fecha: fecha, Hora: hora},
+		{MateriaID: 1, CarreraID: 2, TurnoID: 1, AulaID: 4, Fecha: fecha, Hora: hora},
+	} {
+		if _, err := repo.Create(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func mesaForm(carreraID string) url.Values {
+	return url.Values{
+		"materia_id": {"2"}, "carrera_id": {carreraID}, "turno_id": {"3"},
+		"fecha": {"2025-03-27"}, "hora": {"10:00"}, "aula_id": {"3"},
+	}
+}
+
+// panelRoutes recorre cada ruta del panel con el rol que corresponde. Los casos comparten
+// servidor y se ejecutan en orden: las bajas van después de las lecturas que las necesitan.
+var panelRoutes = []struct {
+	route        string // método y patrón, como los registra NewRouter
+	path         string
+	as           string
+	form         url.Values
+	wantStatus   int
+	wantLocation string
+}{
+	{"GET /login", "/login", "", nil, http.StatusOK, ""},
+	{"POST /do-login", "/do-login", "", url.Values{"email": {lecturaEmail}, "password": {"incorrecta"}}, http.StatusUnauthorized, ""},
+
+	// Cualquier usuario logueado
+	{"GET /admin", "/admin", lecturaEmail, nil, http.StatusOK, ""},
+	{"GET /admin/config", "/admin/config", lecturaEmail, nil, http.StatusOK, ""},
+	{"GET /admin/plan", "/admin/plan?carrera_id=2", lecturaEmail, nil, http.StatusOK, ""},
+	{"GET /admin/exportar/:format", "/admin/exportar/csv", lecturaEmail, nil, http.StatusOK, ""},
+	{"GET /admin/api/aulas", "/admin/api/aulas?sede_id=1", lecturaEmail, nil, http.StatusOK, ""},
+	{"GET /admin/cuenta", "/admin/cuenta", lecturaEmail, nil, http.StatusOK, ""},
+	{"POST /admin/cuenta/password", "/admin/cuenta/password", lecturaEmail, url.Values{"current_password": {"incorrecta"}}, http.StatusBadRequest, ""},
+	{"POST /admin/cuenta/tokens", "/admin/cuenta/tokens", lecturaEmail, url.Values{"nombre": {"Exportación"}, "scopes": {models.ScopeMesasRead, models.ScopeMesasWrite}}, http.StatusForbidden, ""},
+	{"POST /admin/cuenta/tokens", "/admin/cuenta/tokens", lecturaEmail, url.Values{"nombre": {""}, "scopes": {models.ScopeMesasRead}}, http.StatusBadRequest, ""},
+	{"POST /admin/cuenta/tokens", "/admin/cuenta/tokens", lecturaEmail, url.Values{"nombre": {"Exportación"}, "scopes": {models.ScopeMesasRead}}, http.StatusOK, ""},
+	{"POST /admin/cuenta/tokens/:id/revocar", "/admin/cuenta/tokens/1/revocar", secretariaEmail, nil, http.StatusNotFound, ""}, // es de otro usuario
+	{"POST /admin/cuenta/tokens/:id/revocar", "/admin/cuenta/tokens/1/revocar", lecturaEmail, nil, http.StatusSeeOther, "/admin/cuenta"},
+
+	// Mesas: solo lectura no entra; la secretaría, solo en su carrera
+	{"GET /admin/mesas/:id/edit", "/admin/mesas/" + mesaSistemas + "/edit", lecturaEmail, nil, http.StatusForbidden, ""},
+	{"GET /admin/mesas/:id/edit", "/admin/mesas/" + mesaSistemas + "/edit", secretariaEmail, nil, http.StatusOK, ""},
+	{"GET /admin/mesas/:id/edit", "/admin/mesas/" + mesaMatematica + "/edit", secretariaEmail, nil, http.StatusForbidden, ""},
+	{"GET /admin/mesas/:id/edit", "/admin/mesas/999/edit", superEmail, nil, http.StatusNotFound, ""},
+	{"POST /admin/guardar", "/admin/guardar", lecturaEmail, mesaForm("1"), http.StatusForbidden, ""},
+	{"POST /admin/guardar", "/admin/guardar", secretariaEmail, mesaForm("2"), http.StatusForbidden, ""},
+	{"POST /admin/guardar", "/admin/guardar", secretariaEmail, mesaForm("1"), http.StatusFound, "/admin"},
+	{"POST /admin/guardar", "/admin/guardar", secretariaEmail, url.Values{"carrera_id": {"1"}}, http.StatusBadRequest, ""},
+	{"POST /admin/mesas/:id", "/admin/mesas/" + mesaSistemas, secretariaEmail, mesaForm("1"), http.StatusFound, "/admin"},
+	{"POST /admin/mesas/:id", "/admin/mesas/" + mesaSistemas, secretariaEmail, mesaForm("2"), http.StatusForbidden, ""},
+	{"POST /admin/mesas/:id", "/admin/mesas/" + mesaMatematica, secretariaEmail, mesaForm("1"), http.StatusForbidden, ""},
+	{"POST /admin/borrar/:id", "/admin/borrar/" + mesaMatematica, secretariaEmail, nil, http.StatusForbidden, ""},
+	{"POST /admin/borrar/:id", "/admin/borrar/" + mesaSistemas, secretariaEmail, nil, http.StatusFound, "/admin"},
+	{"POST /admin/borrar/:id", "/admin/borrar/" + mesaSistemas, secretariaEmail, nil, http.StatusNotFound, ""},
+	{"GET /admin/importar", "/admin/importar", secretariaEmail, nil, http.StatusOK, ""},
+	{"POST /admin/importar", "/admin/importar", secretariaEmail, nil, http.StatusBadRequest, ""},
+	{"POST /admin/importar/confirmar", "/admin/importar/confirmar", secretariaEmail, url.Values{"token": {"vencido"}}, http.StatusBadRequest, ""},
+
+	// Plan de estudios (filas 1-4 de Sistemas, 5-6 de Matemática)
+	{"POST /admin/plan", "/admin/plan", secretariaEmail, url.Values{"carrera_id": {"1"}, "materia_id": {"3"}, "anio": {"2"}}, http.StatusSeeOther, "/admin/plan?carrera_id=1"},
+	{"POST /admin/plan", "/admin/plan", secretariaEmail, url.Values{"carrera_id": {"1"}, "materia_id": {"3"}}, http.StatusConflict, ""},
+	{"POST /admin/plan", "/admin/plan", secretariaEmail, url.Values{"carrera_id": {"2"}, "materia_id": {"3"}}, http.StatusForbidden, ""},
+	{"POST /admin/plan/:id", "/admin/plan/1", secretariaEmail, url.Values{"anio": {"2"}, "cuatrimestre": {"1"}, "codigo": {"SIS-201"}}, http.StatusSeeOther, "/admin/plan?carrera_id=1"},
+	{"POST /admin/plan/:id", "/admin/plan/1", secretariaEmail, url.Values{"anio": {"9"}}, http.StatusBadRequest, ""},
+	{"POST /admin/plan/:id", "/admin/plan/5", secretariaEmail, url.Values{"anio": {"2"}}, http.StatusForbidden, ""},
+	{"POST /admin/plan/:id/delete", "/admin/plan/5/delete", secretariaEmail, nil, http.StatusForbidden, ""},
+	{"POST /admin/plan/:id/delete", "/admin/plan/2/delete", secretariaEmail, nil, http.StatusSeeOther, "/admin/plan?carrera_id=1"},
+
+	// Parámetros: solo superadmin
+	{"POST /admin/materias", "/admin/materias", secretariaEmail, url.Values{"nombre": {"Química"}}, http.StatusForbidden, ""},
+	{"POST /admin/materias", "/admin/materias", superEmail, url.Values{"nombre": {"Química"}}, http.StatusFound, "/admin/config"},
+	{"POST /admin/carreras", "/admin/carreras", superEmail, url.Values{"nombre": {"Lic. en Química"}}, http.StatusFound, "/admin/config"},
+	{"POST /admin/sedes", "/admin/sedes", superEmail, url.Values{"nombre": {"Campus Sáenz Peña"}}, http.StatusFound, "/admin/config"},
+	{"POST /admin/aulas", "/admin/aulas", superEmail, url.Values{"nombre": {"Aula 3-PA"}, "sede_id": {"1"}}, http.StatusSeeOther, "/admin/config"},
+	{"POST /admin/turnos", "/admin/turnos", superEmail, url.Values{"nombre": {"11° Turno"}, "fecha_inicio": {"2099-12-01"}, "fecha_fin": {"2099-12-05"}}, http.StatusSeeOther, "/admin/config"},
+	{"POST /admin/turnos", "/admin/turnos", superEmail, url.Values{"nombre": {"12° Turno"}, "fecha_inicio": {"2099-12-05"}, "fecha_fin": {"2099-12-01"}}, http.StatusBadRequest, ""},
+	{"POST /admin/turnos/update/:id", "/admin/turnos/update/10", superEmail, url.Values{"nombre": {"10° Turno"}, "fecha_inicio": {"2099-11-01"}, "receso": {"on"}}, http.StatusSeeOther, "/admin/config"},
+	{"POST /admin/turnos/delete/:id", "/admin/turnos/delete/1", superEmail, nil, http.StatusConflict, ""}, // tiene mesas
+	{"POST /admin/turnos/delete/:id", "/admin/turnos/delete/10", superEmail, nil, http.StatusSeeOther, "/admin/config"},
+	{"GET /admin/config/edit/:type/:id", "/admin/config/edit/aula/2", superEmail, nil, http.StatusOK, ""},
+	{"GET /admin/config/edit/:type/:id", "/admin/config/edit/otro/2", superEmail, nil, http.StatusBadRequest, ""},
+	{"GET /admin/config/edit/:type/:id", "/admin/config/edit/materia/999", superEmail, nil, http.StatusNotFound, ""},
+	{"POST /admin/config/update/:type/:id", "/admin/config/update/materia/3", superEmail, url.Values{"nombre": {"Física General I"}}, http.StatusFound, "/admin/config"},
+	{"POST /admin/config/update/:type/:id", "/admin/config/update/otro/3", superEmail, url.Values{"nombre": {"x"}}, http.StatusBadRequest, ""},
+	{"GET /admin/config/delete/:type/:id", "/admin/config/delete/sede/1", superEmail, nil, http.StatusOK, ""},
+	{"POST /admin/config/delete/:type/:id", "/admin/config/delete/materia/1", superEmail, url.Values{"confirmar": {"1"}}, http.StatusConflict, ""}, // tiene mesas
+	{"POST /admin/config/delete/:type/:id", "/admin/config/delete/materia/4", superEmail, nil, http.StatusConflict, ""},                            // está en el plan
+	{"POST /admin/config/delete/:type/:id", "/admin/config/delete/materia/4", superEmail, url.Values{"confirmar": {"1"}}, http.StatusFound, "/admin/config"},
+	{"POST /admin/config/delete/:type/:id", "/admin/config/delete/materia/4", superEmail, url.Values{"confirmar": {"1"}}, http.StatusNotFound, ""},
+	{"POST /admin/config/archive/:type/:id", "/admin/config/archive/sede/3", superEmail, nil, http.StatusFound, "/admin/config"},
+	{"POST /admin/config/archive/:type/:id", "/admin/config/archive/otro/3", superEmail, nil, http.StatusBadRequest, ""},
+	{"POST /admin/config/restore/:type/:id", "/admin/config/restore/sede/3", superEmail, nil, http.StatusFound, "/admin/config"},
+	{"POST /admin/sin-mapear/:id/descartar", "/admin/sin-mapear/1/descartar", superEmail, nil, http.StatusSeeOther, "/admin"},
+
+	// Usuarios (1 superadmin, 2 secretaría, 3 lectura) y auditoría
+	{"GET /admin/usuarios", "/admin/usuarios", secretariaEmail, nil, http.StatusForbidden, ""},
+	{"GET /admin/usuarios", "/admin/usuarios", superEmail, nil, http.StatusOK, ""},
+	{"POST /admin/usuarios", "/admin/usuarios", superEmail, url.Values{"email": {"nuevo@unne.edu.ar"}, "role": {models.RoleLectura}}, http.StatusOK, ""},
+	{"POST /admin/usuarios", "/admin/usuarios", superEmail, url.Values{"email": {"otro@unne.edu.ar"}, "role": {models.RoleSecretaria}}, http.StatusBadRequest, ""},
+	{"POST /admin/usuarios/:id", "/admin/usuarios/1", superEmail, url.Values{"role": {models.RoleLectura}}, http.StatusBadRequest, ""}, // último superadmin
+	{"POST /admin/usuarios/:id", "/admin/usuarios/4", superEmail, url.Values{"role": {models.RoleSecretaria}, "carrera_id": {"2"}}, http.StatusSeeOther, "/admin/usuarios"},
+	{"POST /admin/usuarios/:id/reset", "/admin/usuarios/4/reset", superEmail, nil, http.StatusOK, ""},
+	{"POST /admin/usuarios/:id/delete", "/admin/usuarios/1/delete", superEmail, nil, http.StatusBadRequest, ""},
+	{"POST /admin/usuarios/:id/delete", "/admin/usuarios/4/delete", superEmail, nil, http.StatusSeeOther, "/admin/usuarios"},
+	{"GET /admin/auditoria", "/admin/auditoria?entity=mesa", superEmail, nil, http.StatusOK, ""},
+
+	{"POST /logout", "/logout", lecturaEmail, nil, http.StatusFound, "/login"},
+}
+
+func TestPanelRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+
+	clients := map[string]*testClient{}
+	clientFor := func(email string) *testClient {
+		if email == "" {
+			return srv.client(t)
+		}
+		if clients[email] == nil {
+			clients[email] = srv.login(t, email)
+		}
+		return clients[email]
+	}
+
+	for _, tt := range panelRoutes {
+		c := clientFor(tt.as)
+		var res response
+		if strings.HasPrefix(tt.route, "GET ") {
+			res = c.get(tt.path)
+		} else {
+			res = c.post(tt.path, tt.form)
+		}
+		if res.StatusCode != tt.wantStatus || res.Location != tt.wantLocation {
+			t.Errorf("%s como %q = %d → %q, want %d → %q", tt.path, tt.as, res.StatusCode, res.Location, tt.wantStatus, tt.wantLocation)
+		}
+	}
+}
+
+// TestPanelRoutesCoverage falla si se registra una ruta del panel sin caso en panelRoutes
+func TestPanelRoutesCoverage(t *testing.T) {
+	srv := newTestServer(t)
+	covered := map[string]bool{}
+	for _, tt := range panelRoutes {
+		covered[tt.route] = true
+	}
+	panel := regexp.MustCompile(`\(\*(Admin|Auth|User|Audit)Handler\)`)
+	for _, r := range srv.Router.Routes() {
+		if panel.MatchString(r.Handler) && !covered[r.Method+" "+r.Path] {
+			t.Errorf("la ruta %s %s no tiene caso en panelRoutes", r.Method, r.Path)
+		}
+	}
+}
+
+func TestCreateMesaShowsOnDashboard(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, secretariaEmail)
+
+	if res := c.post("/admin/guardar", mesaForm("1")); res.StatusCode != http.StatusFound {
+		t.Fatalf("POST /admin/guardar = %d", res.StatusCode)
+	}
+	res := c.get("/admin")
+	for _, want := range []string{"Análisis Matemático I", "27/03/2025", "10:00", "Aula 2 - PB"} {
+		if !strings.Contains(res.Body, want) {
+			t.Errorf("el dashboard no muestra %q", want)
+		}
+	}
+
+	entries, err := repository.NewAuditRepository(srv.DB).List(repository.AuditFilter{Entity: "mesa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Actor != secretariaEmail || entries[0].Action != models.AuditCreate {
+		t.Errorf("auditoría = %+v, want un alta de %s", entries, secretariaEmail)
+	}
+}
+
+func TestMesaFormErrors(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	c := srv.login(t, secretariaEmail)
+
+	tests := []struct {
+		name string
+		path string
+		form url.Values
+		want []string // errores y valores enviados que vuelven en el formulario
+	}{
+		{"fecha fuera del turno", "/admin/guardar", url.Values{
+			"materia_id": {"2"}, "carrera_id": {"1"}, "turno_id": {"3"}, "fecha": {"2025-04-15"}, "hora": {"10:00"}, "sede": {"1"}, "aula_id": {"4"},
+		}, []string{"Fuera del 3° Turno (del 25/03/2025 al 31/03/2025)", `value="2025-04-15"`, `value="10:00"`, `<option value="4" selected>Aula Magna</option>`}},
+		{"referencias inexistentes", "/admin/guardar", url.Values{
+			"materia_id": {"99"}, "carrera_id": {"1"}, "turno_id": {"99"}, "fecha": {"2025-03-27"}, "aula_id": {"99"},
+		}, []string{"La materia no existe", "El turno no existe", "El aula no existe"}},
+		{"formatos inválidos", "/admin/guardar", url.Values{
+			"materia_id": {"uno"}, "carrera_id": {"1"}, "fecha": {"27/03/2025"}, "hora": {"diez"},
+		}, []string{"Valor inválido", "Fecha inválida (AAAA-MM-DD)", "Hora inválida (HH:MM)"}},
+		{"edición fuera del turno", "/admin/mesas/" + mesaSistemas, url.Values{
+			"materia_id": {"1"}, "carrera_id": {"1"}, "turno_id": {"1"}, "fecha": {"2025-12-01"}, "hora": {"09:00"}, "sede": {"2"}, "aula_id": {"5"},
+		}, []string{"No se guardaron los cambios", "Fuera del 1° Turno", `value="2025-12-01"`, `<option value="5" selected>Laboratorio 1</option>`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := c.post(tt.path, tt.form)
+			if res.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status %d, want 400", res.StatusCode)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(res.Body, want) {
+					t.Errorf("el formulario no muestra %q", want)
+				}
+			}
+		})
+	}
+
+	// Nada de lo inválido quedó guardado
+	mesa, _ := repository.NewMesaRepository(srv.DB).GetByID(1)
+	if mesas, _ := repository.NewMesaRepository(srv.DB).GetAll(); len(mesas) != 2 || mesa.AulaID != 2 {
+		t.Errorf("mesas = %d, aula de la mesa 1 = %d", len(mesas), mesa.AulaID)
+	}
+}
+
+func TestTurnoFormErrors(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, superEmail)
+
+	res := c.post("/admin/turnos", url.Values{"nombre": {"Turno Especial"}, "fecha_inicio": {"2099-08-07"}, "fecha_fin": {"2099-08-03"}})
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("alta: status %d, want 400", res.StatusCode)
+	}
+	for _, want := range []string{"No se agregó el turno", "Es anterior a la fecha de inicio", `value="Turno Especial"`, `value="2099-08-07"`} {
+		if !strings.Contains(res.Body, want) {
+			t.Errorf("el alta no muestra %q", want)
+		}
+	}
+
+	res = c.post("/admin/turnos/update/2", url.Values{"nombre": {" "}, "fecha_inicio": {"2025-03-10"}, "fecha_fin": {"2025-03-14"}})
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("edición: status %d, want 400", res.StatusCode)
+	}
+	for _, want := range []string{"No se guardó el turno #2", "El nombre es obligatorio"} {
+		if !strings.Contains(res.Body, want) {
+			t.Errorf("la edición no muestra %q", want)
+		}
+	}
+	if turno, _ := repository.NewParamsRepository(srv.DB).GetTurnoConfig(2); turno.Nombre != "2° Turno" {
+		t.Errorf("el turno cambió a %+v", turno)
+	}
+
+	seedPanel(t, srv)
+	res = c.post("/admin/turnos/delete/1", nil)
+	if res.StatusCode != http.StatusConflict || !strings.Contains(res.Body, "No se puede borrar el turno #1: tiene 2 mesa(s)") {
+		t.Errorf("baja con mesas: status %d, want 409 con el motivo", res.StatusCode)
+	}
+
+	// Las mesas sembradas son del 18/02/2099: el nuevo rango las dejaría afuera
+	res = c.post("/admin/turnos/update/1", url.Values{"nombre": {"1° Turno"}, "fecha_inicio": {"2099-02-19"}, "fecha_fin": {"2099-02-25"}})
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(res.Body, "Hay 2 mesa(s) del turno antes de esta fecha") {
+		t.Errorf("edición con mesas afuera: status %d, want 400 con el motivo", res.StatusCode)
+	}
+	if turno, _ := repository.NewParamsRepository(srv.DB).GetTurnoConfig(1); turno.FechaInicio.String() != "2025-02-17" {
+		t.Errorf("el turno cambió a %+v", turno)
+	}
+}
+
+func TestGetAulas(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, lecturaEmail)
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{"sede_id=1", http.StatusOK, []string{"Aula 1 - PB", "Aula 2 - PB", "Aula Magna"}},
+		{"sede_id=3", http.StatusOK, []string{"Sala de Conferencias"}},
+		{"sede_id=99", http.StatusOK, nil},
+		{"sede_id=abc", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		res := c.get("/admin/api/aulas?" + tt.query)
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d", tt.query, res.StatusCode, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		var aulas []models.Aula
+		if err := json.Unmarshal([]byte(res.Body), &aulas); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		var got []string
+		for _, a := range aulas {
+			got = append(got, a.Nombre)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: aulas = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExportMesas(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	c := srv.login(t, lecturaEmail)
+
+	tests := []struct {
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{"/admin/exportar/csv", http.StatusOK, "text/csv", "id,materia,carrera,turno,fecha,hora,aula,sede,fecha_edicion"},
+		{"/admin/exportar/csv?carrera_id=2", http.StatusOK, "text/csv", "Licenciatura en Matemática"},
+		{"/admin/exportar/json", http.StatusOK, "application/json", `"materia":"Álgebra I"`},
+		{"/admin/exportar/xlsx", http.StatusOK, "spreadsheetml", "PK"},
+		{"/admin/exportar/pdf", http.StatusBadRequest, "", "Formato inválido"},
+		{"/admin/exportar/csv?turno_id=uno", http.StatusBadRequest, "", "turno_id inválido"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		res, err := c.http.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		body.ReadFrom(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d", tt.path, res.StatusCode, tt.wantStatus)
+		}
+		if !strings.Contains(res.Header.Get("Content-Type"), tt.wantContentType) {
+			t.Errorf("%s: Content-Type %q, want %q", tt.path, res.Header.Get("Content-Type"), tt.wantContentType)
+		}
+		if !strings.Contains(body.String(), tt.wantBody) {
+			t.Errorf("%s: el cuerpo no contiene %q", tt.path, tt.wantBody)
+		}
+	}
+
+	// El filtro por carrera deja afuera la mesa de Sistemas
+	res := c.get("/admin/exportar/csv?carrera_id=2")
+	if strings.Contains(res.Body, "Ingeniería en Sistemas") {
+		t.Error("la exportación filtrada por carrera incluye otra carrera")
+	}
+}
+
+// upload sube el CSV a la vista previa de la importación
+func (c *testClient) upload(filename, csv string) response {
+	c.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField(csrfFormField, c.csrf())
+	part, _ := w.CreateFormFile("archivo", filename)
+	part.Write([]byte(csv))
+	w.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, c.srv.URL+"/admin/importar", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return c.do(req)
+}
+
+var importTokenRe = regexp.MustCompile(`name="token" value="([0-9a-f]+)"`)
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name       string
+		as         string
+		filename   string
+		csv        string
+		wantStatus int
+		wantBody   string
+		wantMesas  int // después de confirmar; -1 si no hay nada para confirmar
+	}{
+		{
+			name:     "válido",
+			as:       secretariaEmail,
+			filename: "mesas.csv",
+			csv: "materia;carrera;turno;fecha;hora;aula\n" +
+				"Álgebra I;Ingeniería en Sistemas;5;01/07/2025;08:00;Aula 1 - PB\n" +
+				"sistemas operativos;Ingeniería en Sistemas;5° Turno;2025-07-03;14:30;Laboratorio 1\n",
+			wantStatus: http.StatusOK,
+			wantBody:   "Las 2 filas son válidas",
+			wantMesas:  2,
+		},
+		{
+			name:     "carrera ajena",
+			as:       secretariaEmail,
+			filename: "mesas.csv",
+			csv: "materia,carrera,turno,fecha,hora,aula\n" +
+				"Álgebra I,Licenciatura en Matemática,5,01/07/2025,08:00,Aula 1 - PB\n",
+			wantStatus: http.StatusOK,
+			wantBody:   "sin permisos sobre la carrera Licenciatura en Matemática",
+			wantMesas:  -1,
+		},
+		{
+			name:     "fuera del turno",
+			as:       superEmail,
+			filename: "mesas.csv",
+			csv: "materia,carrera,turno,fecha,hora,aula\n" +
+				"Álgebra I,Ingeniería en Sistemas,5,01/07/2025,08:00,Aula 1 - PB\n" +
+				"Física I,Profesorado en Física,5,18/07/2099,08:00,Aula 1 - PB\n",
+			wantStatus: http.StatusOK,
+			wantBody:   "Fuera del 5° Turno (del 30/06/2025 al 04/07/2025)",
+			wantMesas:  -1,
+		},
+		{
+			name:     "valores desconocidos",
+			as:       superEmail,
+			filename: "mesas.csv",
+			csv: "materia,carrera,turno,fecha,hora,aula\n" +
+				"Química,Ingeniería en Sistemas,5,31/02/2099,8hs,Aula 9\n",
+			wantStatus: http.StatusOK,
+			wantBody:   "materia &#34;Química&#34; no existe",
+			wantMesas:  -1,
+		},
+		{
+			name:       "faltan columnas",
+			as:         superEmail,
+			filename:   "mesas.csv",
+			csv:        "materia,carrera\nÁlgebra I,Ingeniería en Sistemas\n",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "faltan columnas: turno, fecha, hora, aula",
+			wantMesas:  -1,
+		},
+		{
+			name:       "formato no soportado",
+			as:         superEmail,
+			filename:   "mesas.pdf",
+			csv:        "materia",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "formato no soportado",
+			wantMesas:  -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			c := srv.login(t, tt.as)
+
+			res := c.upload(tt.filename, tt.csv)
+			if res.StatusCode != tt.wantStatus || !strings.Contains(res.Body, tt.wantBody) {
+				t.Fatalf("vista previa: status %d, want %d con %q", res.StatusCode, tt.wantStatus, tt.wantBody)
+			}
+			m := importTokenRe.FindStringSubmatch(res.Body)
+			if tt.wantMesas < 0 {
+				if m != nil {
+					t.Error("se ofreció confirmar un lote con errores")
+				}
+				return
+			}
+			if m == nil {
+				t.Fatal("la vista previa no ofrece confirmar")
+			}
+
+			if res := c.post("/admin/importar/confirmar", url.Values{"token": {m[1]}}); res.StatusCode != http.StatusFound {
+				t.Fatalf("confirmar = %d", res.StatusCode)
+			}
+			mesas, err := repository.NewMesaRepository(srv.DB).GetAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(mesas) != tt.wantMesas {
+				t.Errorf("mesas importadas = %d, want %d", len(mesas), tt.wantMesas)
+			}
+			// El token se usa una sola vez
+			if res := c.post("/admin/importar/confirmar", url.Values{"token": {m[1]}}); res.StatusCode != http.StatusBadRequest {
+				t.Errorf("confirmar dos veces = %d, want 400", res.StatusCode)
+			}
+		})
+	}
+}
+
+// Si los parámetros cambian entre la vista previa y la confirmación no se importa nada
+func TestConfirmImportRevalidates(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, superEmail)
+
+	res := c.upload("mesas.csv", "materia,carrera,turno,fecha,hora,aula\n"+
+		"Álgebra I,Ingeniería en Sistemas,5,01/07/2025,08:00,Aula 1 - PB\n")
+	m := importTokenRe.FindStringSubmatch(res.Body)
+	if m == nil {
+		t.Fatalf("la vista previa no ofrece confirmar: status %d", res.StatusCode)
+	}
+
+	params := repository.NewParamsRepository(srv.DB)
+	turno, err := params.GetTurnoConfig(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turno.FechaInicio, _ = models.ParseDate("2025-07-02")
+	if err := params.UpdateTurnoConfig(turno); err != nil {
+		t.Fatal(err)
+	}
+
+	res = c.post("/admin/importar/confirmar", url.Values{"token": {m[1]}})
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(res.Body, "Fuera del 5° Turno (del 02/07/2025 al 04/07/2025)") {
+		t.Errorf("confirmar: status %d, want 400 con la fila fuera del turno", res.StatusCode)
+	}
+	if importTokenRe.MatchString(res.Body) {
+		t.Error("se volvió a ofrecer confirmar un lote con errores")
+	}
+	if mesas, _ := repository.NewMesaRepository(srv.DB).GetAll(); len(mesas) != 0 {
+		t.Errorf("se importaron %d mesas", len(mesas))
+	}
+}
+
+// Los cuerpos grandes se cortan antes de leerlos, incluso antes de revisar el token CSRF
+func TestImportSizeLimit(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, superEmail)
+
+	header := "materia,carrera,turno,fecha,hora,aula\n"
+	if res := c.upload("mesas.csv", header+strings.Repeat("x", maxImportSize+1<<20)); res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("planilla de 11 MB: status %d, want 413", res.StatusCode)
+	}
+	// Dentro del margen del formulario pero más grande que 10 MB: lo rechaza la vista previa
+	if res := c.upload("mesas.csv", header+strings.Repeat("x", maxImportSize)); res.StatusCode != http.StatusBadRequest || !strings.Contains(res.Body, "supera los 10 MB") {
+		t.Errorf("planilla de 10 MB: status %d, want 400", res.StatusCode)
+	}
+	// Los demás formularios tienen un límite menor
+	if res := c.post("/admin/materias", url.Values{"nombre": {strings.Repeat("x", maxFormBody)}}); res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("formulario de 1 MB: status %d, want 413", res.StatusCode)
+	}
+}
+
+// Solo quien subió la planilla puede confirmarla, aunque otro usuario tenga el token
+func TestConfirmImportOwner(t *testing.T) {
+	srv := newTestServer(t)
+	owner := srv.login(t, secretariaEmail)
+
+	res := owner.upload("mesas.csv", "materia,carrera,turno,fecha,hora,aula\n"+
+		"Álgebra I,Ingeniería en Sistemas,5,01/07/2025,08:00,Aula 1 - PB\n")
+	m := importTokenRe.FindStringSubmatch(res.Body)
+	if m == nil {
+		t.Fatalf("la vista previa no ofrece confirmar: status %d", res.StatusCode)
+	}
+
+	res = srv.login(t, superEmail).post("/admin/importar/confirmar", url.Values{"token": {m[1]}})
+	if res.StatusCode != http.StatusForbidden || !strings.Contains(res.Body, "la generó otro usuario") {
+		t.Errorf("confirmar con el token de otro: status %d, want 403", res.StatusCode)
+	}
+	if mesas, _ := repository.NewMesaRepository(srv.DB).GetAll(); len(mesas) != 0 {
+		t.Errorf("se importaron %d mesas", len(mesas))
+	}
+
+	// El intento ajeno no consume el lote
+	if res := owner.post("/admin/importar/confirmar", url.Values{"token": {m[1]}}); res.StatusCode != http.StatusFound {
+		t.Errorf("confirmar como dueño: status %d, want 302", res.StatusCode)
+	}
+}
+
+// Dos confirmaciones del mismo lote a la vez (doble clic, dos pestañas) importan una sola vez
+func TestConfirmImportOnce(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, superEmail)
+
+	res := c.upload("mesas.csv", "materia,carrera,turno,fecha,hora,aula\n"+
+		"Álgebra I,Ingeniería en Sistemas,5,01/07/2025,08:00,Aula 1 - PB\n"+
+		"Física I,Profesorado en Física,5,02/07/2025,08:00,Aula 1 - PB\n")
+	m := importTokenRe.FindStringSubmatch(res.Body)
+	if m == nil {
+		t.Fatalf("la vista previa no ofrece confirmar: status %d", res.StatusCode)
+	}
+
+	const confirms = 8
+	form := url.Values{"token": {m[1]}, csrfFormField: {c.csrf()}}
+	codes := make(chan int, confirms)
+	var wg sync.WaitGroup
+	for range confirms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.http.PostForm(srv.URL+"/admin/importar/confirmar", form)
+			if err != nil {
+				codes <- 0
+				return
+			}
+			res.Body.Close()
+			codes <- res.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	imported := 0
+	for code := range codes {
+		switch code {
+		case http.StatusFound:
+			imported++
+		case http.StatusBadRequest:
+		default:
+			t.Errorf("confirmar: status %d", code)
+		}
+	}
+	if imported != 1 {
+		t.Errorf("confirmaciones aceptadas = %d, want 1", imported)
+	}
+	if mesas, _ := repository.NewMesaRepository(srv.DB).GetAll(); len(mesas) != 2 {
+		t.Errorf("mesas importadas = %d, want 2", len(mesas))
+	}
+}
+
+func TestDeleteParamPage(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	c := srv.login(t, superEmail)
+
+	tests := []struct {
+		path     string
+		wantBody []string
+	}{
+		{"/admin/config/delete/materia/1", []string{"Álgebra I", "No se puede borrar"}},
+		{"/admin/config/delete/sede/2", []string{"Campus Corrientes", "Borrar también las aulas de la sede (2)"}},
+		{"/admin/config/delete/carrera/3", []string{"Profesorado en Física", "Archivar"}},
+	}
+	for _, tt := range tests {
+		res := c.get(tt.path)
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s = %d", tt.path, res.StatusCode)
+			continue
+		}
+		for _, want := range tt.wantBody {
+			if !strings.Contains(res.Body, want) {
+				t.Errorf("%s no muestra %q", tt.path, want)
+			}
+		}
+	}
+}
+
+func TestArchivedParamLeavesLists(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	c := srv.login(t, superEmail)
+
+	if res := c.post("/admin/config/archive/aula/4", nil); res.StatusCode != http.StatusFound {
+		t.Fatalf("archivar = %d", res.StatusCode)
+	}
+	res := c.get("/admin/api/aulas?sede_id=1")
+	if strings.Contains(res.Body, "Aula Magna") {
+		t.Error("el aula archivada se sigue ofreciendo")
+	}
+	// La mesa de Matemática usa el aula archivada: la edición la conserva marcada
+	res = c.get("/admin/mesas/" + mesaMatematica + "/edit")
+	if !strings.Contains(res.Body, "Aula Magna (archivada)") {
+		t.Error("la edición de la mesa no muestra el aula archivada")
+	}
+	res = c.get("/admin/config")
+	if !strings.Contains(res.Body, "Restaurar") {
+		t.Error("la configuración no lista el aula archivada")
+	}
+}
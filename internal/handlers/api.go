@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIConfig ajusta la API pública de solo lectura
+type APIConfig struct {
+	// CORSOrigins son los orígenes que pueden llamar a la API desde el navegador.
+	// Vacío no agrega encabezados CORS; "*" acepta cualquiera.
+	CORSOrigins []string
+}
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+//go:embed openapi.json
+var openAPIDoc []byte
+
+// APIHandler sirve /api/v1: el calendario en JSON para otros sistemas
+type APIHandler struct {
+	Repo       *repository.MesaRepository
+	ParamsRepo *repository.ParamsRepository
+}
+
+func NewAPIHandler(repo *repository.MesaRepository, paramsRepo *repository.ParamsRepository) *APIHandler {
+	return &APIHandler{Repo: repo, ParamsRepo: paramsRepo}
+}
+
+// pageParams son los parámetros de paginación de un listado
+type pageParams struct {
+	Page    int
+	PerPage int
+}
+
+func (p pageParams) offset() int { return (p.Page - 1) * p.PerPage }
+
+// pagination acompaña a cada listado
+type pagination struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+}
+
+// listResponse es la forma de todos los listados de la API
+type listResponse struct {
+	Data       any        `json:"data"`
+	Pagination pagination `json:"pagination"`
+}
+
+// ListMesas sirve GET /api/v1/mesas con los filtros materia (texto, como el chat),
+// materia_id, carrera_id, turno_id, sede_id, desde y hasta
+func (h *APIHandler) ListMesas(c *gin.Context) {
+	p, err := parsePage(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	f, none, ok := mesaFilterFromQuery(c, h.ParamsRepo)
+	if !ok {
+		return
+	}
+	if none {
+		respondJSON(c, listResponse{Data: []models.Mesa{}, Pagination: pagination{Page: p.Page, PerPage: p.PerPage}})
+		return
+	}
+
+	mesas, total, err := h.Repo.List(f, p.PerPage, p.offset())
+	if err != nil {
+		internalError(c, "listando mesas", err)
+		return
+	}
+	if mesas == nil {
+		mesas = []models.Mesa{}
+	}
+	respondJSON(c, listResponse{Data: mesas, Pagination: pagination{Page: p.Page, PerPage: p.PerPage, Total: total}})
+}
+
+// mesaFilterFromQuery arma el filtro de mesas de la query. none indica que la materia
+// buscada por texto no existe, así que no hay mesas; si !ok ya se respondió el error.
+func mesaFilterFromQuery(c *gin.Context, params *repository.ParamsRepository) (f repository.MesaFilter, none, ok bool) {
+	var err error
+	for _, q := range []struct {
+		name string
+		dst  *int
+	}{
+		{"carrera_id", &f.CarreraID}, {"turno_id", &f.TurnoID}, {"sede_id", &f.SedeID},
+	} {
+		if *q.dst, err = intQuery(c, q.name); err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return f, false, false
+		}
+	}
+	for _, q := range []struct {
+		name string
+		dst  *models.Date
+	}{
+		{"desde", &f.Desde}, {"hasta", &f.Hasta},
+	} {
+		if v := c.Query(q.name); v != "" {
+			if *q.dst, err = models.ParseDate(v); err != nil {
+				apiError(c, http.StatusBadRequest, q.name+" debe ser una fecha AAAA-MM-DD")
+				return f, false, false
+			}
+		}
+	}
+
+	materiaID, err := intQuery(c, "materia_id")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return f, false, false
+	}
+	if materiaID != 0 {
+		f.MateriaIDs = []int{materiaID}
+	}
+	if q := c.Query("materia"); q != "" {
+		materias, err := params.SearchMaterias(q)
+		if err != nil {
+			internalError(c, "buscando materias", err)
+			return f, false, false
+		}
+		// Con materia_id y materia a la vez quedan las que cumplen los dos
+		f.MateriaIDs = nil
+		for _, m := range materias {
+			if materiaID == 0 || m.ID == materiaID {
+				f.MateriaIDs = append(f.MateriaIDs, m.ID)
+			}
+		}
+		if len(f.MateriaIDs) == 0 {
+			return f, true, true
+		}
+	}
+	return f, false, true
+}
+
+// GetMesa sirve GET /api/v1/mesas/:id
+func (h *APIHandler) GetMesa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "id inválido")
+		return
+	}
+	mesa, err := h.Repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(c, http.StatusNotFound, "mesa no encontrada")
+		return
+	}
+	if err != nil {
+		internalError(c, "leyendo mesa", err)
+		return
+	}
+	respondJSON(c, mesa)
+}
+
+// ListMaterias sirve GET /api/v1/materias?q=, con la misma búsqueda que el chat
+func (h *APIHandler) ListMaterias(c *gin.Context) {
+	materias, err := h.ParamsRepo.SearchMaterias(c.Query("q"))
+	if err != nil {
+		internalError(c, "buscando materias", err)
+		return
+	}
+	respondPage(c, materias)
+}
+
+// ListCarreras sirve GET /api/v1/carreras
+func (h *APIHandler) ListCarreras(c *gin.Context) {
+	carreras, err := h.ParamsRepo.GetAllCarreras()
+	if err != nil {
+		internalError(c, "listando carreras", err)
+		return
+	}
+	respondPage(c, carreras)
+}
+
+// ListSedes sirve GET /api/v1/sedes
+func (h *APIHandler) ListSedes(c *gin.Context) {
+	sedes, err := h.ParamsRepo.GetAllSedes()
+	if err != nil {
+		internalError(c, "listando sedes", err)
+		return
+	}
+	respondPage(c, sedes)
+}
+
+// ListAulas sirve GET /api/v1/aulas, opcionalmente de una sede (?sede_id=)
+func (h *APIHandler) ListAulas(c *gin.Context) {
+	sedeID, err := intQuery(c, "sede_id")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var aulas []models.Aula
+	if sedeID != 0 {
+		aulas, err = h.ParamsRepo.GetAulasBySede(sedeID)
+	} else {
+		aulas, err = h.ParamsRepo.GetAllAulas()
+	}
+	if err != nil {
+		internalError(c, "listando aulas", err)
+		return
+	}
+	respondPage(c, aulas)
+}
+
+// ListTurnos sirve GET /api/v1/turnos: los turnos que todavía no empezaron
+func (h *APIHandler) ListTurnos(c *gin.Context) {
+	turnos, err := h.ParamsRepo.GetFutureTurnos()
+	if err != nil {
+		internalError(c, "listando turnos", err)
+		return
+	}
+	respondPage(c, turnos)
+}
+
+// OpenAPI sirve la descripción OpenAPI 3 de la API
+func (h *APIHandler) OpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPIDoc)
+}
+
+// respondPage pagina en memoria un listado corto y lo responde
+func respondPage[T any](c *gin.Context, items []T) {
+	p, err := parsePage(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	page := []T{}
+	if start := p.offset(); start < len(items) {
+		page = items[start:min(start+p.PerPage, len(items))]
+	}
+	respondJSON(c, listResponse{Data: page, Pagination: pagination{Page: p.Page, PerPage: p.PerPage, Total: len(items)}})
+}
+
+// respondJSON escribe v como JSON con un ETag del contenido. Si el cliente ya tiene esa
+// versión (If-None-Match) responde 304 sin cuerpo.
+func respondJSON(c *gin.Context, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		internalError(c, "armando la respuesta", err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	// Los clientes pueden guardar la respuesta, pero la revalidan siempre
+	c.Header("Cache-Control", "no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func internalError(c *gin.Context, msg string, err error) {
+	logger(c).Error(msg, "error", err)
+	apiError(c, http.StatusInternalServerError, "error interno")
+}
+
+func apiError(c *gin.Context, status int, msg string) {
+	c.JSON(status, gin.H{"error": msg})
+}
+
+// etagMatches compara un If-None-Match, que puede traer varias etiquetas o "*"
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePage(c *gin.Context) (pageParams, error) {
+	p := pageParams{Page: 1, PerPage: defaultPerPage}
+	var err error
+	if v := c.Query("page"); v != "" {
+		if p.Page, err = strconv.Atoi(v); err != nil || p.Page < 1 {
+			return p, fmt.Errorf("page debe ser un número desde 1")
+		}
+	}
+	if v := c.Query("per_page"); v != "" {
+		if p.PerPage, err = strconv.Atoi(v); err != nil || p.PerPage < 1 || p.PerPage > maxPerPage {
+			return p, fmt.Errorf("per_page debe estar entre 1 y %d", maxPerPage)
+		}
+	}
+	return p, nil
+}
+
+// intQuery lee un id de la query; ausente es 0
+func intQuery(c *gin.Context, name string) (int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s debe ser un id numérico", name)
+	}
+	return n, nil
+}
+
+// CORS agrega los encabezados para que los orígenes permitidos llamen a la API desde
+// el navegador, y contesta los preflight
+func CORS(origins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if allowed := corsOrigin(origins, origin); allowed != "" {
+			h := c.Writer.Header()
+			h.Set("Access-Control-Allow-Origin", allowed)
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Expose-Headers", "ETag")
+			if c.Request.Method == http.MethodOptions {
+				h.Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+				h.Set("Access-Control-Allow-Headers", "If-None-Match")
+				h.Set("Access-Control-Max-Age", "600")
+			}
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// corsOrigin devuelve el valor de Access-Control-Allow-Origin para origin, o "" si no se permite
+func corsOrigin(allowed []string, origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, o := range allowed {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return origin
+		}
+	}
+	return ""
+}
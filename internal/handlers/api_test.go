@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"mi-bot-unne/internal/models"
+)
+
+// apiGet hace un GET a la API con los encabezados dados y devuelve la respuesta completa
+func apiGet(t *testing.T, srv *testServer, path string, header map[string]string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+type mesasPage struct {
+	Data       []models.Mesa `json:"data"`
+	Pagination pagination    `json:"pagination"`
+}
+
+func TestAPIListMesas(t *testing.T) {
+	srv := newTestServer(t)
+	seedChat(t, srv)
+
+	tests := []struct {
+		query     string
+		wantIDs   []int
+		wantTotal int
+	}{
+		// Por fecha; la mesa a confirmar va al final
+		{"", []int{1, 2, 3, 4, 5}, 5},
+		{"?carrera_id=1", []int{1, 3, 5}, 3},
+		{"?turno_id=1&carrera_id=1", []int{1, 3}, 2},
+		{"?sede_id=2", []int{3, 4}, 2},
+		{"?desde=2099-02-19&hasta=2099-02-28", []int{3, 4}, 2},
+		{"?materia_id=1", []int{1, 2}, 2},
+		// Sin mayúsculas ni acentos, como el chat
+		{"?materia=ALGEBRA", []int{1, 2}, 2},
+		{"?materia=fisica&carrera_id=3", []int{4}, 1},
+		{"?materia=algebra&materia_id=2", []int{}, 0},
+		{"?materia=inexistente", []int{}, 0},
+		{"?per_page=2&page=2", []int{3, 4}, 5},
+		{"?per_page=2&page=4", []int{}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			res := apiGet(t, srv, "/api/v1/mesas"+tt.query, nil)
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("status %d", res.StatusCode)
+			}
+			var page mesasPage
+			if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+				t.Fatal(err)
+			}
+			ids := []int{}
+			for _, m := range page.Data {
+				ids = append(ids, m.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) || page.Pagination.Total != tt.wantTotal {
+				t.Errorf("ids = %v total %d, want %v total %d", ids, page.Pagination.Total, tt.wantIDs, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestAPIBadRequests(t *testing.T) {
+	srv := newTestServer(t)
+	for _, path := range []string{
+		"/api/v1/mesas?carrera_id=sistemas",
+		"/api/v1/mesas?desde=20/02/2099",
+		"/api/v1/mesas?per_page=1000",
+		"/api/v1/materias?page=0",
+		"/api/v1/aulas?sede_id=-1",
+		"/api/v1/mesas/abc",
+	} {
+		res := apiGet(t, srv, path, nil)
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&body)
+		if res.StatusCode != http.StatusBadRequest || body.Error == "" {
+			t.Errorf("%s: status %d error %q, want 400 con mensaje", path, res.StatusCode, body.Error)
+		}
+	}
+}
+
+func TestAPIGetMesa(t *testing.T) {
+	srv := newTestServer(t)
+	seedChat(t, srv)
+
+	res := apiGet(t, srv, "/api/v1/mesas/3", nil)
+	var m models.Mesa
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || m.Materia != "Análisis Matemático I" || m.Aula != "Laboratorio 1" || m.Fecha.String() != "2099-02-20" {
+		t.Errorf("status %d mesa %+v", res.StatusCode, m)
+	}
+
+	if res := apiGet(t, srv, "/api/v1/mesas/999", nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("mesa inexistente: status %d, want 404", res.StatusCode)
+	}
+}
+
+func TestAPIListings(t *testing.T) {
+	srv := newTestServer(t)
+	seedChat(t, srv)
+
+	tests := []struct {
+		path      string
+		wantNames []string
+	}{
+		{"/api/v1/materias?q=al", []string{"Álgebra I", "Algoritmos y Estructuras de Datos", "Análisis Matemático I"}},
+		{"/api/v1/materias?q=al&per_page=1&page=3", []string{"Análisis Matemático I"}},
+		{"/api/v1/carreras", []string{"Ingeniería en Sistemas", "Licenciatura en Matemática", "Profesorado en Física"}},
+		{"/api/v1/aulas?sede_id=2", []string{"Laboratorio 1", "Laboratorio 2"}},
+		{"/api/v1/turnos", []string{"Turno de invierno"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res := apiGet(t, srv, tt.path, nil)
+			var page struct {
+				Data []struct {
+					Nombre string `json:"nombre"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, d := range page.Data {
+				names = append(names, d.Nombre)
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("nombres = %q, want %q", names, tt.wantNames)
+			}
+		})
+	}
+
+	res := apiGet(t, srv, "/api/v1/sedes", nil)
+	var sedes struct {
+		Data       []models.Sede `json:"data"`
+		Pagination pagination    `json:"pagination"`
+	}
+	json.NewDecoder(res.Body).Decode(&sedes)
+	if len(sedes.Data) == 0 || sedes.Pagination.Total != len(sedes.Data) {
+		t.Errorf("sedes = %+v", sedes)
+	}
+}
+
+func TestAPIETag(t *testing.T) {
+	srv := newTestServer(t)
+	seedChat(t, srv)
+
+	res := apiGet(t, srv, "/api/v1/mesas?carrera_id=1", nil)
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("sin ETag")
+	}
+	res = apiGet(t, srv, "/api/v1/mesas?carrera_id=1", map[string]string{"If-None-Match": etag})
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("con el mismo ETag: status %d, want 304", res.StatusCode)
+	}
+
+	// Un cambio en las mesas cambia el ETag
+	if _, err := srv.DB.Exec("UPDATE mesas SET hora = '11:00' WHERE id = 3"); err != nil {
+		t.Fatal(err)
+	}
+	res = apiGet(t, srv, "/api/v1/mesas?carrera_id=1", map[string]string{"If-None-Match": etag})
+	if res.StatusCode != http.StatusOK || res.Header.Get("ETag") == etag {
+		t.Errorf("después del cambio: status %d etag %s", res.StatusCode, res.Header.Get("ETag"))
+	}
+}
+
+func TestAPICORS(t *testing.T) {
+	srv := newTestServer(t, func(cfg *RouterConfig) {
+		cfg.API.CORSOrigins = []string{"https://app.unne.edu.ar"}
+	})
+
+	tests := []struct {
+		name      string
+		method    string
+		origin    string
+		wantAllow string
+	}{
+		{"origen permitido", http.MethodGet, "https://app.unne.edu.ar", "https://app.unne.edu.ar"},
+		{"otro origen", http.MethodGet, "https://otro.com", ""},
+		{"preflight permitido", http.MethodOptions, "https://app.unne.edu.ar", "https://app.unne.edu.ar"},
+		{"preflight de otro origen", http.MethodOptions, "https://otro.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, srv.URL+"/api/v1/carreras", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", "GET")
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if got := res.Header.Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantAllow)
+			}
+			if tt.method == http.MethodOptions {
+				if res.StatusCode != http.StatusNoContent {
+					t.Errorf("preflight: status %d, want 204", res.StatusCode)
+				}
+				if tt.wantAllow != "" && !strings.Contains(res.Header.Get("Access-Control-Allow-Methods"), "GET") {
+					t.Errorf("Allow-Methods = %q", res.Header.Get("Access-Control-Allow-Methods"))
+				}
+			}
+		})
+	}
+
+	// Sin orígenes configurados no hay CORS
+	res := apiGet(t, newTestServer(t), "/api/v1/carreras", map[string]string{"Origin": "https://app.unne.edu.ar"})
+	if got := res.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("sin configurar: Allow-Origin = %q", got)
+	}
+}
+
+// El documento OpenAPI describe cada ruta GET de /api/v1
+func TestAPIOpenAPICoversRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	res := apiGet(t, srv, "/api/v1/openapi.json", nil)
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range srv.Router.Routes() {
+		path, ok := strings.CutPrefix(route.Path, "/api/v1")
+		if !ok || route.Method != http.MethodGet {
+			continue
+		}
+		path = strings.ReplaceAll(path, ":id", "{id}")
+		if _, ok := doc.Paths[path]["get"]; !ok {
+			t.Errorf("GET %s no está en openapi.json", path)
+		}
+	}
+}
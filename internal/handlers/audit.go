@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const auditPageSize = 50
+
+// AuditHandler muestra el registro de cambios hechos desde el panel
+type AuditHandler struct {
+	Repo *repository.AuditRepository
+}
+
+func NewAuditHandler(repo *repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{Repo: repo}
+}
+
+var auditEntities = []string{"mesa", "materia", "carrera", "sede", "aula", "turno", "plan_materia"}
+
+var auditActions = []string{models.AuditCreate, models.AuditUpdate, models.AuditDelete}
+
+func (h *AuditHandler) ShowAudit(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	f := repository.AuditFilter{
+		Actor:  strings.TrimSpace(c.Query("actor")),
+		Action: c.Query("action"),
+		Entity: c.Query("entity"),
+		Desde:  c.Query("desde"),
+		Hasta:  c.Query("hasta"),
+		// Pedimos uno de más para saber si hay página siguiente
+		Limit:  auditPageSize + 1,
+		Offset: (page - 1) * auditPageSize,
+	}
+
+	entries, err := h.Repo.List(f)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error leyendo auditoría")
+		return
+	}
+	hasNext := len(entries) > auditPageSize
+	if hasNext {
+		entries = entries[:auditPageSize]
+	}
+
+	// Los links de paginación conservan los filtros
+	q := c.Request.URL.Query()
+	q.Set("page", strconv.Itoa(page+1))
+	next := "/admin/auditoria?" + q.Encode()
+	q.Set("page", strconv.Itoa(page-1))
+	prev := "/admin/auditoria?" + q.Encode()
+
+	renderHTML(c, http.StatusOK, "admin_audit.html", gin.H{
+		"entries":  entries,
+		"filter":   f,
+		"entities": auditEntities,
+		"actions":  auditActions,
+		"page":     page,
+		"has_next": hasNext,
+		"next_url": next,
+		"prev_url": prev,
+	})
+}
@@ -1,16 +1,70 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/ratelimit"
+	"mi-bot-unne/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-type AuthHandler struct{}
+const (
+	sessionCookie = "admin_session"
+
+	// DefaultSessionTTL es la duración de la sesión si no se configura otra
+	DefaultSessionTTL = time.Hour
+)
+
+// CookieConfig controla los atributos de la cookie de sesión.
+// Secure debe activarse cuando el panel se sirve por HTTPS.
+type CookieConfig struct {
+	Secure   bool
+	SameSite http.SameSite
+	TTL      time.Duration
+	// Secret firma los tokens CSRF; vacío se genera uno al azar al crear el handler
+	Secret []byte
+}
 
-func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{}
+type AuthHandler struct {
+	Users    *repository.UserRepository
+	Sessions *repository.SessionRepository
+	Tokens   *repository.TokenRepository // Tokens de la API de administración
+	Cookie   CookieConfig
+	// Bloqueo progresivo de intentos fallidos, por IP y por cuenta
+	IPGuard      *ratelimit.LoginGuard
+	AccountGuard *ratelimit.LoginGuard
+}
+
+func NewAuthHandler(users *repository.UserRepository, sessions *repository.SessionRepository, tokens *repository.TokenRepository, cookie CookieConfig) *AuthHandler {
+	if cookie.TTL <= 0 {
+		cookie.TTL = DefaultSessionTTL
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	if len(cookie.Secret) == 0 {
+		cookie.Secret = make([]byte, 32)
+		rand.Read(cookie.Secret)
+	}
+	return &AuthHandler{
+		Users:    users,
+		Sessions: sessions,
+		Tokens:   tokens,
+		Cookie:   cookie,
+		// Una IP puede ser una oficina entera, así que tolera más fallos que una cuenta
+		IPGuard:      ratelimit.NewLoginGuard(20, time.Minute, time.Hour, time.Hour),
+		AccountGuard: ratelimit.NewLoginGuard(5, time.Minute, time.Hour, time.Hour),
+	}
 }
 
 func (h *AuthHandler) ShowLogin(c *gin.Context) {
@@ -18,34 +72,222 @@ func (h *AuthHandler) ShowLogin(c *gin.Context) {
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
-	email := c.PostForm("email")
+	email := strings.TrimSpace(c.PostForm("email"))
 	password := c.PostForm("password")
+	ip := c.ClientIP()
+	account := strings.ToLower(email)
 
-	envEmail := os.Getenv("ADMIN_EMAIL")
-	envPass := os.Getenv("ADMIN_PASSWORD")
+	if wait := max(h.IPGuard.Locked(ip), h.AccountGuard.Locked(account)); wait > 0 {
+		h.renderLocked(c, wait)
+		return
+	}
 
-	if email == envEmail && password == envPass {
-		// Set cookie
-		c.SetCookie("admin_session", "logged_in", 3600, "/", "", false, true)
-		c.Redirect(http.StatusFound, "/admin")
-	} else {
+	user, err := h.Users.Authenticate(email, password)
+	if err != nil {
+		if !errors.Is(err, repository.ErrInvalidCredentials) {
+			logger(c).Error("autenticando", "error", err)
+			c.HTML(http.StatusInternalServerError, "login.html", gin.H{"error": "No se pudo iniciar sesión"})
+			return
+		}
+		if wait := max(h.IPGuard.Fail(ip), h.AccountGuard.Fail(account)); wait > 0 {
+			logger(c).Warn("login bloqueado", "ip", ip, "account", account, "wait", wait.String())
+			h.renderLocked(c, wait)
+			return
+		}
 		c.HTML(http.StatusUnauthorized, "login.html", gin.H{"error": "Credenciales incorrectas"})
+		return
+	}
+	h.AccountGuard.Reset(account)
+
+	// Rotación: la sesión previa de este navegador (si había) deja de valer
+	if old, err := c.Cookie(sessionCookie); err == nil {
+		h.Sessions.Delete(old)
+	}
+	if err := h.startSession(c, user.ID); err != nil {
+		logger(c).Error("iniciando la sesión", "error", err)
+		c.HTML(http.StatusInternalServerError, "login.html", gin.H{"error": "No se pudo iniciar sesión"})
+		return
 	}
+
+	if user.MustChangePassword {
+		c.Redirect(http.StatusFound, "/admin/cuenta")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin")
+}
+
+// renderLocked responde 429 indicando cuándo se puede volver a intentar
+func (h *AuthHandler) renderLocked(c *gin.Context, wait time.Duration) {
+	minutes := int(math.Ceil(wait.Minutes()))
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.HTML(http.StatusTooManyRequests, "login.html", gin.H{
+		"error": fmt.Sprintf("Demasiados intentos fallidos. Probá de nuevo en %d minuto(s).", minutes),
+	})
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
-	c.SetCookie("admin_session", "", -1, "/", "", false, true)
+	if token, err := c.Cookie(sessionCookie); err == nil {
+		h.Sessions.Delete(token)
+	}
+	h.setCookie(c, "", -1)
 	c.Redirect(http.StatusFound, "/login")
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+func (h *AuthHandler) startSession(c *gin.Context, userID int) error {
+	token, err := h.Sessions.Create(userID, h.Cookie.TTL)
+	if err != nil {
+		return err
+	}
+	h.setCookie(c, token, int(h.Cookie.TTL.Seconds()))
+	// Si la respuesta renderiza un formulario, que ya lleve el CSRF de la sesión nueva
+	c.Set("session_token", token)
+	c.Set(csrfFormField, csrfToken(h.Cookie.Secret, token))
+	return nil
+}
+
+func (h *AuthHandler) setCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   h.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: h.Cookie.SameSite,
+	})
+}
+
+// AuthMiddleware exige una sesión válida y deja el usuario en el contexto
+func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		cookie, err := c.Cookie("admin_session")
-		if err != nil || cookie != "logged_in" {
+		token, err := c.Cookie(sessionCookie)
+		if err != nil {
+			c.Redirect(http.StatusFound, "/login")
+			c.Abort()
+			return
+		}
+		session, err := h.Sessions.Get(token)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger(c).Error("leyendo la sesión", "error", err)
+			}
+			h.setCookie(c, "", -1)
+			c.Redirect(http.StatusFound, "/login")
+			c.Abort()
+			return
+		}
+		user, err := h.Users.GetByID(session.UserID)
+		if err != nil {
+			// El usuario fue eliminado mientras tenía la sesión abierta
+			h.Sessions.Delete(token)
 			c.Redirect(http.StatusFound, "/login")
 			c.Abort()
 			return
 		}
+
+		// Con contraseña reseteada solo puede entrar a cambiarla
+		path := c.Request.URL.Path
+		if user.MustChangePassword && !strings.HasPrefix(path, "/admin/cuenta") && path != "/logout" {
+			c.Redirect(http.StatusFound, "/admin/cuenta")
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("session_token", token)
+		c.Set(loggerKey, logger(c).With("user", user.Email))
 		c.Next()
 	}
 }
+
+// RequireRole corta la request si el usuario logueado no tiene alguno de los roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		for _, r := range roles {
+			if user.Role == r {
+				c.Next()
+				return
+			}
+		}
+		c.String(http.StatusForbidden, "No tenés permisos para esta acción")
+		c.Abort()
+	}
+}
+
+func currentUser(c *gin.Context) models.User {
+	if u, ok := c.Get("user"); ok {
+		return u.(models.User)
+	}
+	return models.User{}
+}
+
+// ShowAccount muestra el formulario de cambio de contraseña y los tokens de la API del usuario logueado
+func (h *AuthHandler) ShowAccount(c *gin.Context) {
+	h.renderAccount(c, http.StatusOK, gin.H{})
+}
+
+// renderAccount agrega a data los tokens del usuario y los permisos que puede darles
+func (h *AuthHandler) renderAccount(c *gin.Context, status int, data gin.H) {
+	user, ok := data["user"].(models.User)
+	if !ok {
+		user = currentUser(c)
+		data["user"] = user
+	}
+	tokens, err := h.Tokens.ListForUser(user.ID)
+	if err != nil {
+		logger(c).Error("listando los tokens", "error", err)
+	}
+	var scopes []string
+	for _, s := range models.Scopes {
+		if user.CanUseScope(s) {
+			scopes = append(scopes, s)
+		}
+	}
+	data["tokens"] = tokens
+	data["scopes"] = scopes
+	renderHTML(c, status, "admin_account.html", data)
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	user := currentUser(c)
+	current := c.PostForm("current_password")
+	nueva := c.PostForm("new_password")
+
+	render := func(status int, data gin.H) {
+		data["user"] = user
+		h.renderAccount(c, status, data)
+	}
+
+	if _, err := h.Users.Authenticate(user.Email, current); err != nil {
+		render(http.StatusBadRequest, gin.H{"error": "La contraseña actual no es correcta"})
+		return
+	}
+	if nueva != c.PostForm("confirm_password") {
+		render(http.StatusBadRequest, gin.H{"error": "Las contraseñas nuevas no coinciden"})
+		return
+	}
+	if nueva == current {
+		render(http.StatusBadRequest, gin.H{"error": "La contraseña nueva debe ser distinta de la actual"})
+		return
+	}
+	if err := h.Users.SetPassword(user.ID, nueva, false); err != nil {
+		if errors.Is(err, repository.ErrPasswordTooShort) {
+			render(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.String(http.StatusInternalServerError, "Error actualizando contraseña")
+		return
+	}
+
+	// Cerramos las sesiones abiertas en otros dispositivos y rotamos la actual
+	if err := h.Sessions.DeleteForUser(user.ID); err != nil {
+		logger(c).Error("cerrando las otras sesiones", "error", err)
+	}
+	if err := h.startSession(c, user.ID); err != nil {
+		logger(c).Error("rotando la sesión", "error", err)
+	}
+
+	user.MustChangePassword = false
+	render(http.StatusOK, gin.H{"success": "Contraseña actualizada"})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+)
+
+func TestShowLogin(t *testing.T) {
+	srv := newTestServer(t)
+	res := srv.client(t).get("/login")
+	if res.StatusCode != http.StatusOK || !strings.Contains(res.Body, `action="/do-login"`) {
+		t.Errorf("GET /login = %d", res.StatusCode)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	srv := newTestServer(t)
+	users := repository.NewUserRepository(srv.DB)
+	if err := users.Create(models.User{Email: "nuevo@unne.edu.ar", Role: models.RoleLectura, MustChangePassword: true}, testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		email        string
+		password     string
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{"credenciales correctas", superEmail, testPassword, http.StatusFound, "/admin", ""},
+		{"email con mayúsculas y espacios", "  Admin@UNNE.edu.ar ", testPassword, http.StatusFound, "/admin", ""},
+		{"contraseña incorrecta", superEmail, "otra-cosa", http.StatusUnauthorized, "", "Credenciales incorrectas"},
+		{"usuario inexistente", "nadie@unne.edu.ar", testPassword, http.StatusUnauthorized, "", "Credenciales incorrectas"},
+		{"debe cambiar la contraseña", "nuevo@unne.edu.ar", testPassword, http.StatusFound, "/admin/cuenta", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := srv.client(t).postForm("/do-login", url.Values{"email": {tt.email}, "password": {tt.password}})
+			if res.StatusCode != tt.wantStatus || res.Location != tt.wantLocation {
+				t.Errorf("status %d → %q, want %d → %q", res.StatusCode, res.Location, tt.wantStatus, tt.wantLocation)
+			}
+			if !strings.Contains(res.Body, tt.wantBody) {
+				t.Errorf("body no contiene %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.client(t)
+	form := url.Values{"email": {lecturaEmail}, "password": {"incorrecta"}}
+
+	for i := 0; i < 4; i++ {
+		if res := c.postForm("/do-login", form); res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("intento %d: status %d, want 401", i+1, res.StatusCode)
+		}
+	}
+	if res := c.postForm("/do-login", form); res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("quinto intento: status %d, want 429", res.StatusCode)
+	}
+	// Bloqueada la cuenta, ni la contraseña correcta entra
+	form.Set("password", testPassword)
+	if res := c.postForm("/do-login", form); res.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("con la contraseña correcta durante el bloqueo: status %d, want 429", res.StatusCode)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		cookie string
+	}{
+		{"sin cookie", ""},
+		{"sesión inexistente", "token-inventado"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := srv.client(t)
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/admin", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.cookie})
+			}
+			res := c.do(req)
+			if res.StatusCode != http.StatusFound || res.Location != "/login" {
+				t.Errorf("status %d → %q, want 302 → /login", res.StatusCode, res.Location)
+			}
+		})
+	}
+}
+
+func TestMustChangePasswordOnlyAllowsAccount(t *testing.T) {
+	srv := newTestServer(t)
+	users := repository.NewUserRepository(srv.DB)
+	if err := users.Create(models.User{Email: "nuevo@unne.edu.ar", Role: models.RoleSuperadmin, MustChangePassword: true}, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	c := srv.login(t, "nuevo@unne.edu.ar")
+
+	if res := c.get("/admin/config"); res.StatusCode != http.StatusFound || res.Location != "/admin/cuenta" {
+		t.Errorf("GET /admin/config = %d → %q, want 302 → /admin/cuenta", res.StatusCode, res.Location)
+	}
+	if res := c.get("/admin/cuenta"); res.StatusCode != http.StatusOK {
+		t.Errorf("GET /admin/cuenta = %d", res.StatusCode)
+	}
+
+	res := c.post("/admin/cuenta/password", url.Values{
+		"current_password": {testPassword}, "new_password": {"nueva-clave"}, "confirm_password": {"nueva-clave"},
+	})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("cambio de contraseña = %d", res.StatusCode)
+	}
+	if res := c.get("/admin/config"); res.StatusCode != http.StatusOK {
+		t.Errorf("después de cambiarla, GET /admin/config = %d", res.StatusCode)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, lecturaEmail)
+
+	if res := c.postForm("/logout", nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("logout sin CSRF = %d, want 403", res.StatusCode)
+	}
+	if res := c.post("/logout", nil); res.StatusCode != http.StatusFound || res.Location != "/login" {
+		t.Errorf("logout = %d → %q", res.StatusCode, res.Location)
+	}
+	if res := c.get("/admin"); res.StatusCode != http.StatusFound || res.Location != "/login" {
+		t.Errorf("después del logout, GET /admin = %d → %q", res.StatusCode, res.Location)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    string
+		nueva      string
+		confirm    string
+		wantStatus int
+		wantBody   string
+	}{
+		{"actual incorrecta", "otra-cosa", "nueva-clave", "nueva-clave", http.StatusBadRequest, "La contraseña actual no es correcta"},
+		{"no coinciden", testPassword, "nueva-clave", "nueva-klave", http.StatusBadRequest, "no coinciden"},
+		{"igual a la actual", testPassword, testPassword, testPassword, http.StatusBadRequest, "debe ser distinta"},
+		{"demasiado corta", testPassword, "corta", "corta", http.StatusBadRequest, "al menos 8 caracteres"},
+		{"correcta", testPassword, "nueva-clave", "nueva-clave", http.StatusOK, "Contraseña actualizada"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			c := srv.login(t, lecturaEmail)
+			other := srv.login(t, lecturaEmail)
+
+			res := c.post("/admin/cuenta/password", url.Values{
+				"current_password": {tt.current}, "new_password": {tt.nueva}, "confirm_password": {tt.confirm},
+			})
+			if res.StatusCode != tt.wantStatus || !strings.Contains(res.Body, tt.wantBody) {
+				t.Fatalf("status %d, want %d con %q", res.StatusCode, tt.wantStatus, tt.wantBody)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			// La sesión actual se rota y sigue valiendo; las demás se cierran
+			if res := c.get("/admin"); res.StatusCode != http.StatusOK {
+				t.Errorf("sesión propia después del cambio: GET /admin = %d", res.StatusCode)
+			}
+			if res := other.get("/admin"); res.StatusCode != http.StatusFound {
+				t.Errorf("otra sesión después del cambio: GET /admin = %d, want 302", res.StatusCode)
+			}
+			res = srv.client(t).postForm("/do-login", url.Values{"email": {lecturaEmail}, "password": {tt.nueva}})
+			if res.StatusCode != http.StatusFound {
+				t.Errorf("login con la contraseña nueva = %d", res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestCSRFRequired(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.login(t, superEmail)
+
+	res := c.postForm("/admin/materias", url.Values{"nombre": {"Química"}, csrfFormField: {"token-de-otro-sitio"}})
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("POST con token inválido = %d, want 403", res.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/admin/materias", strings.NewReader("nombre=Química"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(csrfHeader, c.csrf())
+	if res := c.do(req); res.StatusCode != http.StatusSeeOther && res.StatusCode != http.StatusFound {
+		t.Errorf("POST con el token en el header = %d, want redirect", res.StatusCode)
+	}
+}
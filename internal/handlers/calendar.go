@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"mi-bot-unne/internal/ical"
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarHandler publica feeds .ics para suscribirse desde Google Calendar/Outlook
+type CalendarHandler struct {
+	Repo       *repository.MesaRepository
+	ParamsRepo *repository.ParamsRepository
+}
+
+func NewCalendarHandler(repo *repository.MesaRepository, paramsRepo *repository.ParamsRepository) *CalendarHandler {
+	return &CalendarHandler{Repo: repo, ParamsRepo: paramsRepo}
+}
+
+// MateriaFeed sirve /cal/materia/:name.ics (opcionalmente ?turno= para una sola mesa)
+func (h *CalendarHandler) MateriaFeed(c *gin.Context) {
+	materia, ok := icsParam(c)
+	if !ok {
+		return
+	}
+	filter := repository.MesaFilter{Materia: materia, Turno: c.Query("turno")}
+	h.serveFeed(c, materia, filter)
+}
+
+// CarreraFeed sirve /cal/carrera/:id.ics
+func (h *CalendarHandler) CarreraFeed(c *gin.Context) {
+	raw, ok := icsParam(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		c.String(http.StatusBadRequest, "ID inválido")
+		return
+	}
+	carrera, err := h.ParamsRepo.GetCarrera(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Carrera no encontrada")
+		return
+	}
+	h.serveFeed(c, carrera.Nombre, repository.MesaFilter{CarreraID: carrera.ID, Turno: c.Query("turno")})
+}
+
+// TurnoFeed sirve /cal/turno/:name.ics
+func (h *CalendarHandler) TurnoFeed(c *gin.Context) {
+	turno, ok := icsParam(c)
+	if !ok {
+		return
+	}
+	h.serveFeed(c, turno, repository.MesaFilter{Turno: turno})
+}
+
+func (h *CalendarHandler) serveFeed(c *gin.Context, name string, filter repository.MesaFilter) {
+	var mesas []models.Mesa
+	err := h.Repo.Each(filter, func(m models.Mesa) error {
+		mesas = append(mesas, m)
+		return nil
+	})
+	if err != nil {
+		logger(c).Error("armando el calendario", "error", err)
+		c.String(http.StatusInternalServerError, "Error leyendo DB")
+		return
+	}
+	if len(mesas) == 0 {
+		c.String(http.StatusNotFound, "No hay mesas para este calendario")
+		return
+	}
+
+	cal := ical.Calendar{
+		Name:   "Mesas UNNE - " + name,
+		Domain: hostname(c.Request.Host),
+		Mesas:  mesas,
+	}
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="mesas.ics"`)
+	c.Status(http.StatusOK)
+	if err := cal.Write(c.Writer); err != nil {
+		logger(c).Error("escribiendo el calendario", "error", err)
+	}
+}
+
+// icsParam toma el último segmento de la ruta y exige la extensión .ics
+func icsParam(c *gin.Context) (string, bool) {
+	value, ok := strings.CutSuffix(c.Param("name"), ".ics")
+	if !ok || value == "" {
+		c.String(http.StatusNotFound, "Calendario no encontrado")
+		return "", false
+	}
+	return value, true
+}
+
+func hostname(host string) string {
+	if h, _, found := strings.Cut(host, ":"); found {
+		return h
+	}
+	return host
+}
+
+// materiaCalendarURL arma el link al feed de una materia (y opcionalmente de un solo turno)
+func materiaCalendarURL(materia, turno string) string {
+	u := "/cal/materia/" + url.PathEscape(materia) + ".ics"
+	if turno != "" {
+		u += "?turno=" + url.QueryEscape(turno)
+	}
+	return u
+}
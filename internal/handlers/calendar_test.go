@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCalendarFeeds(t *testing.T) {
+	srv := newTestServer(t)
+	seedPanel(t, srv)
+	c := srv.client(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantUIDs []string // mesas que tiene que traer el feed
+	}{
+		{"materia", materiaCalendarURL("Álgebra I", ""), http.StatusOK, []string{"mesa-1@", "mesa-2@"}},
+		{"materia en un turno", materiaCalendarURL("Álgebra I", "1° Turno"), http.StatusOK, []string{"mesa-1@", "mesa-2@"}},
+		{"materia sin mesas en el turno", materiaCalendarURL("Álgebra I", "2° Turno"), http.StatusNotFound, nil},
+		{"materia inexistente", materiaCalendarURL("Química", ""), http.StatusNotFound, nil},
+		{"materia sin .ics", "/cal/materia/" + url.PathEscape("Álgebra I"), http.StatusNotFound, nil},
+		{"carrera", "/cal/carrera/1.ics", http.StatusOK, []string{"mesa-1@"}},
+		{"carrera sin mesas", "/cal/carrera/3.ics", http.StatusNotFound, nil},
+		{"carrera inexistente", "/cal/carrera/99.ics", http.StatusNotFound, nil},
+		{"carrera con id inválido", "/cal/carrera/sistemas.ics", http.StatusBadRequest, nil},
+		{"turno", "/cal/turno/" + url.PathEscape("1° Turno") + ".ics", http.StatusOK, []string{"mesa-1@", "mesa-2@"}},
+		{"turno sin mesas", "/cal/turno/" + url.PathEscape("2° Turno") + ".ics", http.StatusNotFound, nil},
+		{"turno inexistente", "/cal/turno/" + url.PathEscape("11° Turno") + ".ics", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := c.get(tt.path)
+			if res.StatusCode != tt.wantCode {
+				t.Fatalf("GET %s: status %d, want %d", tt.path, res.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if !strings.HasPrefix(res.Body, "BEGIN:VCALENDAR\r\n") {
+				t.Errorf("no es un calendario: %q", res.Body)
+			}
+			if n := strings.Count(res.Body, "BEGIN:VEVENT"); n != len(tt.wantUIDs) {
+				t.Errorf("eventos = %d, want %d", n, len(tt.wantUIDs))
+			}
+			for _, uid := range tt.wantUIDs {
+				if !strings.Contains(res.Body, "UID:"+uid) {
+					t.Errorf("falta UID:%s", uid)
+				}
+			}
+		})
+	}
+}
@@ -2,51 +2,143 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"mi-bot-unne/internal/chatproto"
+	"mi-bot-unne/internal/chatview"
+	"mi-bot-unne/internal/metrics"
 	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/ratelimit"
 	"mi-bot-unne/internal/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/looplab/fsm"
-	"github.com/patrickmn/go-cache"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // NOTA: En producción, validar el dominio aquí por seguridad
-	},
+// ChatConfig ajusta los tiempos y límites de la conversación. Los campos en cero toman
+// los valores por defecto.
+type ChatConfig struct {
+	// ResultsPause es la espera antes de ofrecer la descarga, para que el usuario lea la tabla
+	ResultsPause time.Duration
+	// MaxTurno es el último número de turno que se puede pedir
+	MaxTurno int
+	// MessageLimit mensajes por IP en cada MessageWindow
+	MessageLimit  int
+	MessageWindow time.Duration
+	// AllowedOrigins son los orígenes que pueden abrir el WebSocket. Vacío acepta solo
+	// el mismo host que sirve la página; "*" acepta cualquiera.
+	AllowedOrigins []string
+	// MaxMessageSize es el tamaño máximo en bytes de un mensaje del alumno; uno más
+	// grande cierra la conexión
+	MaxMessageSize int64
+	// PingInterval es cada cuánto se manda un ping; la conexión que no contesta dos
+	// seguidos se da por muerta
+	PingInterval time.Duration
+	// IdleTimeout cierra el chat si el alumno no escribe nada en ese tiempo
+	IdleTimeout time.Duration
+	// MaxConnsPerIP es la cantidad de chats abiertos a la vez desde una misma IP
+	MaxConnsPerIP int
 }
 
 // ChatSession almacena el estado de cada usuario
 type ChatSession struct {
+	// ID identifica la conversación en todas las líneas de log
+	ID            string
 	FSM           *fsm.FSM
 	Conn          *websocket.Conn
 	Handler       *ChatHandler
 	CurrentOption string
 	CurrentTurn   string
+	// CarreraID filtra las materias por plan de estudios; 0 busca en todas
+	CarreraID     int
+	Carrera       string
+	carreras      []models.Carrera
 	PendingCardID string
-	LastInput     string
-	mu            sync.Mutex
+	// cards cuenta las tarjetas enviadas, para darle a cada una un id distinto
+	cards     int
+	LastInput string
+	// encode y decode traducen los mensajes al formato del cliente, según el
+	// subprotocolo negociado
+	encode func(chatproto.Message) ([]byte, error)
+	decode func([]byte) (string, error)
+	log    *slog.Logger
+	mu     sync.Mutex
+	// writeMu ordena las escrituras: la conexión admite un solo escritor a la vez
+	writeMu sync.Mutex
 }
 
 type ChatHandler struct {
-	Repo         *repository.MesaRepository
-	ParamsRepo   *repository.ParamsRepository
-	SessionCache *cache.Cache
+	Repo       *repository.MesaRepository
+	ParamsRepo *repository.ParamsRepository
+	Config     ChatConfig
+	// MessageLimiter acota los mensajes por IP, sumando todas sus conexiones
+	MessageLimiter *ratelimit.Limiter
+	// Sessions son los chats abiertos, que se cierran al apagar el servidor
+	Sessions *ChatSessions
+	upgrader websocket.Upgrader
+}
+
+func NewChatHandler(repo *repository.MesaRepository, paramsRepo *repository.ParamsRepository, cfg ChatConfig) *ChatHandler {
+	if cfg.ResultsPause == 0 {
+		cfg.ResultsPause = 600 * time.Millisecond
+	}
+	if cfg.MaxTurno <= 0 {
+		cfg.MaxTurno = 10
+	}
+	if cfg.MessageLimit <= 0 {
+		cfg.MessageLimit = 20
+	}
+	if cfg.MessageWindow <= 0 {
+		cfg.MessageWindow = 10 * time.Second
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = 1024
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 30 * time.Second
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 15 * time.Minute
+	}
+	if cfg.MaxConnsPerIP <= 0 {
+		cfg.MaxConnsPerIP = 10
+	}
+	return &ChatHandler{
+		Repo:           repo,
+		ParamsRepo:     paramsRepo,
+		Config:         cfg,
+		MessageLimiter: ratelimit.New(cfg.MessageLimit, cfg.MessageWindow),
+		Sessions:       NewChatSessions(),
+		upgrader: websocket.Upgrader{
+			CheckOrigin:      originChecker(cfg.AllowedOrigins),
+			Subprotocols:     chatproto.Subprotocols,
+			HandshakeTimeout: writeWait,
+		},
+	}
 }
 
-func NewChatHandler(repo *repository.MesaRepository, paramsRepo *repository.ParamsRepository) *ChatHandler {
-	return &ChatHandler{
-		Repo:         repo,
-		ParamsRepo:   paramsRepo,
-		SessionCache: cache.New(cache.NoExpiration, 10*time.Minute),
+// originChecker acepta los orígenes listados. Sin lista devuelve nil, y gorilla
+// exige que el Origin coincida con el Host de la request.
+func originChecker(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		for _, o := range allowed {
+			if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+				return true
+			}
+		}
+		return false
 	}
 }
 
@@ -54,11 +146,21 @@ func (h *ChatHandler) ShowChat(c *gin.Context) {
 	c.HTML(http.StatusOK, "chat.html", nil)
 }
 
-// NewChatSession crea una nueva sesión con su máquina de estados
-func NewChatSession(conn *websocket.Conn, handler *ChatHandler) *ChatSession {
+// NewChatSession crea una nueva sesión con su máquina de estados. Sus logs salen por
+// logger con el id de la sesión.
+func NewChatSession(conn *websocket.Conn, handler *ChatHandler, logger *slog.Logger) *ChatSession {
+	id := newID()
 	session := &ChatSession{
+		ID:      id,
 		Conn:    conn,
 		Handler: handler,
+		log:     logger.With("session_id", id),
+	}
+	if conn.Subprotocol() == chatproto.JSON {
+		session.encode, session.decode = chatproto.EncodeJSON, chatproto.DecodeJSON
+	} else {
+		// chat.html, pida o no el subprotocolo HTML, manda texto plano
+		session.encode, session.decode = chatview.Encode, decodeText
 	}
 
 	// Definir la máquina de estados
@@ -66,7 +168,9 @@ func NewChatSession(conn *websocket.Conn, handler *ChatHandler) *ChatSession {
 		"idle", // Estado inicial
 		fsm.Events{
 			// --- Flujo principal ---
-			{Name: "start", Src: []string{"idle"}, Dst: "menu"},
+			{Name: "start", Src: []string{"idle"}, Dst: "awaiting_carrera"},
+			{Name: "select_carrera", Src: []string{"awaiting_carrera"}, Dst: "menu"},
+			{Name: "change_carrera", Src: []string{"menu", "awaiting_materia_all", "awaiting_materia_turn", "awaiting_turn", "showing_results", "awaiting_download", "disambiguating", "showing_turns"}, Dst: "awaiting_carrera"},
 			{Name: "select_all_dates", Src: []string{"menu"}, Dst: "awaiting_materia_all"},
 			{Name: "select_by_turn", Src: []string{"menu"}, Dst: "awaiting_turn"},
 			{Name: "show_future_turns", Src: []string{"menu"}, Dst: "showing_turns"},
@@ -88,12 +192,13 @@ func NewChatSession(conn *websocket.Conn, handler *ChatHandler) *ChatSession {
 			{Name: "download_no", Src: []string{"awaiting_download"}, Dst: "menu"},
 
 			// --- Reset y Ayuda ---
-			{Name: "reset", Src: []string{"awaiting_materia_all", "awaiting_materia_turn", "awaiting_turn", "showing_results", "awaiting_download", "disambiguating", "showing_turns"}, Dst: "menu"},
-			{Name: "help", Src: []string{"menu", "awaiting_materia_all", "awaiting_turn", "awaiting_materia_turn", "disambiguating"}, Dst: "menu"},
+			{Name: "reset", Src: []string{"awaiting_carrera", "awaiting_materia_all", "awaiting_materia_turn", "awaiting_turn", "showing_results", "awaiting_download", "disambiguating", "showing_turns"}, Dst: "menu"},
+			{Name: "help", Src: []string{"menu", "awaiting_carrera", "awaiting_materia_all", "awaiting_turn", "awaiting_materia_turn", "disambiguating"}, Dst: "menu"},
 		},
 		fsm.Callbacks{
 			// Callbacks de entrada a estados
 			"enter_menu":                  session.onEnterMenu,
+			"enter_awaiting_carrera":      session.onEnterAwaitingCarrera,
 			"enter_awaiting_materia_all":  session.onEnterAwaitingMateriaAll,
 			"enter_awaiting_turn":         session.onEnterAwaitingTurn,
 			"enter_awaiting_materia_turn": session.onEnterAwaitingMateriaTurn,
@@ -125,7 +230,9 @@ func (s *ChatSession) ProcessMessage(input string) {
 	}
 
 	currentState := s.FSM.Current()
-	log.Printf("State: %s, Input: %s", currentState, input)
+	// Lo que escribe el alumno solo se registra en debug
+	s.log.Debug("mensaje del chat", "state", currentState, "input", input)
+	metrics.ChatMessages.WithLabelValues(currentState).Inc()
 
 	ctx := context.Background()
 
@@ -140,11 +247,19 @@ func (s *ChatSession) ProcessMessage(input string) {
 		return
 	}
 
+	if input == "carrera" || input == "cambiar carrera" {
+		s.FSM.Event(ctx, "change_carrera")
+		return
+	}
+
 	// Lógica específica por estado
 	switch currentState {
 	case "idle":
 		s.FSM.Event(ctx, "start")
 
+	case "awaiting_carrera":
+		s.handleCarreraInput(ctx, input)
+
 	case "menu":
 		s.handleMenuInput(ctx, input)
 
@@ -179,10 +294,38 @@ func (s *ChatSession) handleMenuInput(ctx context.Context, input string) {
 	}
 }
 
+func (s *ChatSession) handleCarreraInput(ctx context.Context, input string) {
+	if input == "0" || input == "todas" {
+		s.CarreraID, s.Carrera = 0, ""
+		s.FSM.Event(ctx, "select_carrera")
+		return
+	}
+
+	var matches []models.Carrera
+	if n, err := strconv.Atoi(input); err == nil {
+		if n >= 1 && n <= len(s.carreras) {
+			matches = append(matches, s.carreras[n-1])
+		}
+	} else {
+		for _, c := range s.carreras {
+			if strings.Contains(repository.Normalize(c.Nombre), repository.Normalize(input)) {
+				matches = append(matches, c)
+			}
+		}
+	}
+
+	if len(matches) != 1 {
+		s.send(chatproto.Error("invalid_carrera", "⚠️ No pude identificar la carrera. Escribí el número de la lista, o 0 para buscar en todas.", "0"))
+		return
+	}
+	s.CarreraID, s.Carrera = matches[0].ID, matches[0].Nombre
+	s.FSM.Event(ctx, "select_carrera")
+}
+
 func (s *ChatSession) handleTurnInput(ctx context.Context, input string) {
 	turnNum, err := strconv.Atoi(input)
-	if err != nil || turnNum < 1 || turnNum > 10 {
-		s.sendMessage(botMsg("⚠️ Por favor ingresá un número de turno válido (1 al 10)."))
+	if err != nil || turnNum < 1 || turnNum > s.Handler.Config.MaxTurno {
+		s.send(chatproto.Error("invalid_turn", "⚠️ Por favor ingresá un número de turno válido ("+s.turnRange()+")."))
 		return
 	}
 	s.CurrentTurn = strconv.Itoa(turnNum) + "° Turno"
@@ -192,19 +335,25 @@ func (s *ChatSession) handleTurnInput(ctx context.Context, input string) {
 func (s *ChatSession) handleMateriaInput(ctx context.Context, input string) {
 	// Solo avisamos que buscamos si no venimos de desambiguar (clic en botón)
 	if s.FSM.Current() != "disambiguating" {
-		s.sendMessage(botMsg("🔍 Buscando <strong>" + input + "</strong>..."))
+		s.send(chatproto.Text("searching", "🔍 Buscando "+input+"...", input))
 	}
 
-	matches, err := s.Handler.Repo.GetUniqueMaterias(input)
+	matches, err := s.Handler.Repo.GetUniqueMaterias(input, s.CarreraID)
 	if err != nil {
-		log.Println("Error:", err)
-		s.sendMessage(botMsg("❌ Ocurrió un error al buscar. Por favor intentá de nuevo."))
+		s.log.Error("buscando materias", "error", err)
+		metrics.ChatSearches.WithLabelValues(metrics.SearchError).Inc()
+		s.send(chatproto.Error("search_failed", "❌ Ocurrió un error al buscar. Por favor intentá de nuevo."))
 		s.FSM.Event(ctx, "reset")
 		return
 	}
 
 	if len(matches) == 0 {
-		s.sendMessage(botMsg("❌ No encontré ninguna materia con ese nombre."))
+		metrics.ChatSearches.WithLabelValues(metrics.SearchMiss).Inc()
+		if s.CarreraID != 0 {
+			s.send(chatproto.Text("not_found", "❌ No encontré ninguna materia con ese nombre en el plan de "+s.Carrera+". Escribí carrera para buscar en otra.", s.Carrera, "carrera"))
+		} else {
+			s.send(chatproto.Text("not_found", "❌ No encontré ninguna materia con ese nombre."))
+		}
 		s.FSM.Event(ctx, "reset")
 		return
 	}
@@ -221,6 +370,7 @@ func (s *ChatSession) handleMateriaInput(ctx context.Context, input string) {
 		}
 
 		if !exactMatch {
+			metrics.ChatSearches.WithLabelValues(metrics.SearchAmbiguous).Inc()
 			s.showDisambiguation(matches)
 			s.FSM.Event(ctx, "disambiguate")
 			return
@@ -230,6 +380,7 @@ func (s *ChatSession) handleMateriaInput(ctx context.Context, input string) {
 	}
 
 	// Ejecutar la búsqueda final
+	metrics.ChatSearches.WithLabelValues(metrics.SearchHit).Inc()
 	s.performSearch(ctx, input)
 }
 
@@ -250,22 +401,39 @@ func (s *ChatSession) onEnterMenu(_ context.Context, e *fsm.Event) {
 	s.sendMenuOptions()
 }
 
+func (s *ChatSession) onEnterAwaitingCarrera(ctx context.Context, e *fsm.Event) {
+	carreras, err := s.Handler.ParamsRepo.GetCarrerasWithPlan()
+	if err != nil || len(carreras) == 0 {
+		// Sin planes de estudio cargados no hay nada que elegir: se busca en todas las carreras
+		s.CarreraID, s.Carrera = 0, ""
+		s.FSM.Event(ctx, "select_carrera")
+		return
+	}
+	s.carreras = carreras
+	s.showCarreras(carreras)
+}
+
 func (s *ChatSession) onEnterAwaitingMateriaAll(ctx context.Context, e *fsm.Event) {
 	s.CurrentOption = "all"
 	if e.Event == "direct_search" {
 		// Si vino por búsqueda directa, procesamos el input inmediatamente
 		s.handleMateriaInput(ctx, s.LastInput)
 	} else {
-		s.sendMessage(botMsg("Perfecto. ¿Qué materia estás buscando?"))
+		s.send(chatproto.Text("ask_materia", "Perfecto. ¿Qué materia estás buscando?"))
 	}
 }
 
 func (s *ChatSession) onEnterAwaitingTurn(_ context.Context, e *fsm.Event) {
-	s.sendMessage(botMsg("Dale. ¿Qué número de turno te interesa? (1 al 10)"))
+	s.send(chatproto.Text("ask_turn", "Dale. ¿Qué número de turno te interesa? ("+s.turnRange()+")"))
+}
+
+func (s *ChatSession) turnRange() string {
+	return "1 al " + strconv.Itoa(s.Handler.Config.MaxTurno)
 }
 
 func (s *ChatSession) onEnterAwaitingMateriaTurn(_ context.Context, e *fsm.Event) {
-	s.sendMessage(botMsg("Perfecto, <strong>Turno " + strings.TrimSuffix(s.CurrentTurn, "° Turno") + "</strong>. ¿Qué materia buscás?"))
+	turno := "Turno " + strings.TrimSuffix(s.CurrentTurn, "° Turno")
+	s.send(chatproto.Text("ask_materia", "Perfecto, "+turno+". ¿Qué materia buscás?", turno))
 }
 
 // CORRECCIÓN PRINCIPAL AQUÍ:
@@ -273,11 +441,11 @@ func (s *ChatSession) onEnterShowingResults(ctx context.Context, e *fsm.Event) {
 	// El resultado ya se renderizó en performSearch.
 	// Usamos una goroutine para esperar un poco y luego avanzar automáticamente.
 	go func() {
-		time.Sleep(600 * time.Millisecond) // Pausa para que el usuario lea la tabla
+		time.Sleep(s.Handler.Config.ResultsPause)
 
 		// Usamos context.Background() porque la goroutine se ejecuta desacoplada
 		if err := s.FSM.Event(context.Background(), "ask_download"); err != nil {
-			log.Printf("Error avanzando a descarga: %v", err)
+			s.log.Error("avanzando a la descarga", "error", err)
 		}
 	}()
 }
@@ -287,9 +455,9 @@ func (s *ChatSession) onEnterShowingTurns(ctx context.Context, e *fsm.Event) {
 	// Si hay turnos, preguntamos si quiere descargar, con el mismo delay
 	if s.PendingCardID != "" {
 		go func() {
-			time.Sleep(600 * time.Millisecond)
+			time.Sleep(s.Handler.Config.ResultsPause)
 			if err := s.FSM.Event(context.Background(), "ask_download"); err != nil {
-				log.Printf("Error avanzando a descarga (turnos): %v", err)
+				s.log.Error("avanzando a la descarga de turnos", "error", err)
 			}
 		}()
 	} else {
@@ -300,7 +468,12 @@ func (s *ChatSession) onEnterShowingTurns(ctx context.Context, e *fsm.Event) {
 
 func (s *ChatSession) onEnterAwaitingDownload(_ context.Context, e *fsm.Event) {
 	// Pregunta simple de texto
-	s.sendMessage(botMsg("¿Querés guardar esta información como imagen? Escribí <strong>sí</strong> o <strong>no</strong>"))
+	s.send(chatproto.Message{
+		Type:     chatproto.TypeDownloadPrompt,
+		Text:     "¿Querés guardar esta información como imagen? Escribí sí o no",
+		Emphasis: []string{"sí", "no"},
+		CardID:   s.PendingCardID,
+	})
 }
 
 func (s *ChatSession) onEnterDisambiguating(_ context.Context, e *fsm.Event) {
@@ -311,11 +484,8 @@ func (s *ChatSession) onEnterDisambiguating(_ context.Context, e *fsm.Event) {
 // --- Acciones post-respuesta de descarga ---
 
 func (s *ChatSession) onDownloadYes(_ context.Context, e *fsm.Event) {
-	// 1. Enviamos script de descarga
-	downloadHTML := `<div style="display:none;"><button id="auto-download-btn" onclick="downloadCard('` + s.PendingCardID + `'); this.remove();">Download</button></div>`
-	downloadHTML += `<script>setTimeout(() => { const btn = document.getElementById('auto-download-btn'); if(btn) btn.click(); }, 100);</script>`
-	downloadHTML += botMsg("¡Listo! Descargando imagen... 📥")
-	s.sendMessage(downloadHTML)
+	// 1. El mensaje lleva el id de la tarjeta y el cliente la guarda como imagen
+	s.send(chatproto.Message{Type: chatproto.TypeDownload, Text: "¡Listo! Descargando imagen... 📥", CardID: s.PendingCardID})
 
 	// Limpiamos ID
 	s.PendingCardID = ""
@@ -330,14 +500,12 @@ func (s *ChatSession) onDownloadNo(_ context.Context, e *fsm.Event) {
 }
 
 func (s *ChatSession) onHelp(_ context.Context, e *fsm.Event) {
-	html := `<div class="message-container bot"><div class="avatar">🤖</div><div class="message-content">`
-	html += `<p><strong>💡 Ayuda rápida:</strong></p>`
-	html += `<p style="margin-top: 12px; color: var(--text-secondary); line-height: 1.8;">`
-	html += `• Escribí el nombre de una materia para buscarla.<br>`
-	html += `• <strong>1</strong>, <strong>2</strong> o <strong>3</strong> para usar las opciones del menú.<br>`
-	html += `• <strong>menu</strong> para volver al inicio.<br>`
-	html += `</p></div></div>`
-	s.sendMessage(html)
+	s.send(chatproto.Text("help", "💡 Ayuda rápida:\n"+
+		"• Escribí el nombre de una materia para buscarla.\n"+
+		"• 1, 2 o 3 para usar las opciones del menú.\n"+
+		"• carrera para cambiar de carrera.\n"+
+		"• menu para volver al inicio.",
+		"💡 Ayuda rápida:", "1", "2", "3", "carrera", "menu"))
 }
 
 // ============= Helpers de Renderizado y Búsqueda =============
@@ -346,23 +514,30 @@ func (s *ChatSession) performSearch(ctx context.Context, materia string) {
 	var cardID string
 
 	if s.CurrentOption == "all" {
-		mesas, err := s.Handler.Repo.GetFullSchedule(materia)
+		mesas, err := s.Handler.Repo.GetFullSchedule(materia, s.CarreraID)
+		if err == nil && len(mesas) == 0 && s.CarreraID != 0 {
+			// La materia está en el plan pero sus mesas se cargaron en otra carrera
+			mesas, err = s.Handler.Repo.GetFullSchedule(materia, 0)
+			if err == nil && len(mesas) > 0 {
+				s.send(chatproto.Text("other_carreras", "ℹ️ No hay mesas cargadas para "+s.Carrera+"; te muestro las de otras carreras.", s.Carrera))
+			}
+		}
 		if err != nil || len(mesas) == 0 {
-			s.sendMessage(botMsg("❌ No encontré información sobre esta materia."))
+			s.send(chatproto.Text("not_found", "❌ No encontré información sobre esta materia."))
 			s.FSM.Event(ctx, "reset") // Vuelve al menú si falla
 			return
 		}
 		cardID = s.renderFullSchedule(mesas, materia)
 	} else {
-		mesa, err := s.Handler.Repo.GetByTurn(materia, s.CurrentTurn)
+		mesa, err := s.Handler.Repo.GetByTurn(materia, s.CurrentTurn, s.CarreraID)
 		if err != nil {
-			s.sendMessage(botMsg("❌ No encontré esta materia en el turno seleccionado."))
+			s.send(chatproto.Text("not_found", "❌ No encontré esta materia en el turno seleccionado."))
 			s.FSM.Event(ctx, "reset")
 			return
 		}
 
 		if mesa.Turno != s.CurrentTurn {
-			s.sendMessage(botMsg("⚠️ La materia existe, pero no tiene mesa para ese turno."))
+			s.send(chatproto.Text("not_found", "⚠️ La materia existe, pero no tiene mesa para ese turno."))
 			s.FSM.Event(ctx, "reset")
 			return
 		}
@@ -383,158 +558,242 @@ func (s *ChatSession) performSearch(ctx context.Context, materia string) {
 	}
 
 	if err := s.FSM.Event(ctx, evt); err != nil {
-		log.Printf("Error al disparar evento FSM (%s): %v — estado actual: %s", evt, err, s.FSM.Current())
+		s.log.Error("disparando evento de la FSM", "event", evt, "state", s.FSM.Current(), "error", err)
 		// Como fallback, intentar resetear al menú para no dejar la sesión bloqueada
 		if resetErr := s.FSM.Event(context.Background(), "reset"); resetErr != nil {
-			log.Printf("Error al forzar reset FSM: %v", resetErr)
+			s.log.Error("forzando el reset de la FSM", "error", resetErr)
 		}
 	}
 }
 
 func (s *ChatSession) renderFullSchedule(mesas []models.Mesa, materia string) string {
-	s.sendMessage(botMsg("✅ Encontré <strong>" + strconv.Itoa(len(mesas)) + " fechas</strong>:"))
-
-	cardID := "card-full-" + strconv.Itoa(len(mesas))
-	html := `<div class="result-card" id="` + cardID + `">`
-	html += `<div style="font-size: 1.2em; font-weight: 600; color: #E3E3E3; margin-bottom: 4px;">` + materia + `</div>`
-
-	if len(mesas) > 0 {
-		html += `<div style="color: #A8C7FA; font-size: 0.9em; margin-bottom: 12px;">` + mesas[0].Carrera + `</div>`
+	found := strconv.Itoa(len(mesas)) + " fechas"
+	s.send(chatproto.Text("found", "✅ Encontré "+found+":", found))
+
+	schedule := &chatproto.Schedule{
+		CardID:      s.nextCardID(),
+		Materia:     materia,
+		Carrera:     mesas[0].Carrera,
+		CalendarURL: materiaCalendarURL(materia, ""),
 	}
-
-	html += `<div style="display:grid; grid-template-columns: 0.5fr 1.2fr 1fr 2fr 1.2fr; gap:8px; font-size:0.85em; color:#C4C7C5; border-top:1px solid #444; padding-top:8px;">`
-	html += `<div style="font-weight:bold;">#</div><div style="font-weight:bold;">Fecha</div><div style="font-weight:bold;">Hora</div><div style="font-weight:bold;">Aula</div><div style="font-weight:bold;">Act.</div>`
-
 	for _, m := range mesas {
-		html += `<div>` + m.Turno + `</div>`
-		html += `<div>` + m.Fecha + `</div>`
-		html += `<div>` + m.Hora + `</div>`
-		html += `<div>` + m.Aula + ` <span style="color:#888;font-size:0.8em">(` + m.Sede + `)</span></div>`
-		html += `<div style="font-size:0.8em; color:#888;">` + m.FechaEdicion + `</div>`
+		schedule.Mesas = append(schedule.Mesas, chatproto.NewMesa(m))
 	}
-	html += `</div></div>`
-
-	s.sendMessage(html)
-	return cardID
+	s.send(chatproto.Message{Type: chatproto.TypeSchedule, Schedule: schedule})
+	return schedule.CardID
 }
 
 func (s *ChatSession) renderSingleTurn(mesa models.Mesa) string {
-	cardID := "card-turn-" + mesa.Turno
-	html := `<div class="result-card" id="` + cardID + `">`
-	html += `<div style="font-size: 1.2em; font-weight: 600; color: #E3E3E3; margin-bottom: 4px;">` + mesa.Materia + `</div>`
-	html += `<div style="color: #A8C7FA; font-size: 0.9em; margin-bottom: 12px;">` + mesa.Carrera + `</div>`
-	html += `<div style="color: #8ab4f8; font-size: 1em; margin: 16px 0;"><strong>` + mesa.Turno + `</strong></div>`
-	html += `<div style="display:grid; grid-template-columns: 1fr 1fr 2fr; gap:12px; font-size:0.9em; padding: 12px; background:#2F3031; border-radius:8px;">`
-	html += `<div><span style="color:#888;">📅 Fecha:</span><br><strong>` + mesa.Fecha + `</strong></div>`
-	html += `<div><span style="color:#888;">🕐 Hora:</span><br><strong>` + mesa.Hora + `</strong></div>`
-	html += `<div><span style="color:#888;">🏫 Aula:</span><br><strong>` + mesa.Aula + `</strong><br><span style="font-size:0.85em; color:#888;">(` + mesa.Sede + `)</span></div>`
-	html += `</div></div>`
-
-	s.sendMessage(html)
-	return cardID
+	schedule := &chatproto.Schedule{
+		CardID:      s.nextCardID(),
+		Materia:     mesa.Materia,
+		Carrera:     mesa.Carrera,
+		Turno:       mesa.Turno,
+		Mesas:       []chatproto.Mesa{chatproto.NewMesa(mesa)},
+		CalendarURL: materiaCalendarURL(mesa.Materia, mesa.Turno),
+	}
+	s.send(chatproto.Message{Type: chatproto.TypeSchedule, Schedule: schedule})
+	return schedule.CardID
 }
 
 func (s *ChatSession) sendFutureTurnos() {
 	turnos, err := s.Handler.ParamsRepo.GetFutureTurnos()
 	if err != nil || len(turnos) == 0 {
-		s.sendMessage(botMsg("📅 No hay turnos disponibles por el momento."))
+		s.send(chatproto.Text("no_turnos", "📅 No hay turnos disponibles por el momento."))
 		s.PendingCardID = "" // Aseguramos que no haya ID pendiente
 		return
 	}
 
-	s.sendMessage(botMsg("✅ Turnos disponibles:"))
+	s.send(chatproto.Text("turnos", "✅ Turnos disponibles:"))
 
-	cardID := "card-turnos-" + strconv.Itoa(len(turnos))
-	html := `<div class="result-card" id="` + cardID + `">`
+	card := &chatproto.TurnosCard{CardID: s.nextCardID()}
+	for _, t := range turnos {
+		card.Turnos = append(card.Turnos, chatproto.NewTurno(t))
+	}
+	s.send(chatproto.Message{Type: chatproto.TypeTurnos, Turnos: card})
+	s.PendingCardID = card.CardID
+}
 
-	for i, t := range turnos {
-		icon := "📚"
-		if t.Receso {
-			icon = "🏖️"
-		}
-		borderStyle := ""
-		if i < len(turnos)-1 {
-			borderStyle = "border-bottom:1px solid #444;"
-		}
-		html += `<div style="padding:16px; ` + borderStyle + `">`
-		html += `<div style="font-weight:600; color:#E3E3E3;">` + icon + ` ` + t.Nombre + `</div>`
-		html += `<div style="color:#888; font-size:0.9em; margin-top:8px;">` + formatDate(t.FechaInicio) + ` - ` + formatDate(t.FechaFin) + `</div>`
-		html += `</div>`
+func (s *ChatSession) showCarreras(carreras []models.Carrera) {
+	options := make([]chatproto.Option, 0, len(carreras)+1)
+	for i, c := range carreras {
+		options = append(options, chatproto.Option{Value: strconv.Itoa(i + 1), Label: c.Nombre})
+	}
+	options = append(options, chatproto.Option{Value: "0", Label: "Todas las carreras"})
+	s.send(chatproto.Message{
+		Type:     chatproto.TypeOptions,
+		Code:     "carreras",
+		Text:     "¿Qué carrera estudiás? Así te muestro solo las materias de tu plan.",
+		Emphasis: []string{"¿Qué carrera estudiás?"},
+		Options:  options,
+	})
+}
+
+func (s *ChatSession) showDisambiguation(matches []string) {
+	// Aquí sí usamos botones porque es selección de materia, no flujo de descarga
+	options := make([]chatproto.Option, len(matches))
+	for i, m := range matches {
+		options[i] = chatproto.Option{Value: m, Label: m}
 	}
-	html += `</div>`
+	s.send(chatproto.Message{Type: chatproto.TypeOptions, Code: "materias", Text: "Encontré varias opciones. ¿Cuál buscás?", Options: options})
+}
 
-	s.sendMessage(html)
-	s.PendingCardID = cardID
+func (s *ChatSession) sendMenuOptions() {
+	s.send(chatproto.Message{
+		Type:    chatproto.TypeMenu,
+		Text:    "¿Qué necesitás saber?",
+		Carrera: s.Carrera,
+		Options: menuOptions,
+	})
 }
 
-func (s *ChatSession) showDisambiguation(options []string) {
-	html := `<div class="message-container bot"><div class="avatar">🤖</div><div class="message-content">`
-	html += `<p>Encontré varias opciones. ¿Cuál buscás?</p><div style="margin-top:12px;">`
-	for _, opt := range options {
-		// Aquí sí usamos botones porque es selección de materia, no flujo de descarga
-		html += `<button class="option-button" onclick="sendMessage('` + opt + `')">` + opt + `</button>`
+// menuOptions son las opciones del menú principal; handleMenuInput las interpreta
+var menuOptions = []chatproto.Option{
+	{Value: "1", Label: "Buscar todas las fechas de una materia"},
+	{Value: "2", Label: "Buscar fecha en un turno específico"},
+	{Value: "3", Label: "Ver qué turnos faltan este año"},
+}
+
+// nextCardID numera las tarjetas de resultados de la sesión, para que la descarga
+// encuentre la última aunque se repita la búsqueda
+func (s *ChatSession) nextCardID() string {
+	s.cards++
+	return "card-" + strconv.Itoa(s.cards)
+}
+
+// send le manda al alumno un mensaje del bot en el formato de su cliente
+func (s *ChatSession) send(m chatproto.Message) {
+	frame, err := s.encode(m)
+	if err != nil {
+		s.log.Error("armando mensaje del chat", "type", m.Type, "error", err)
+		return
 	}
-	html += `</div></div></div>`
-	s.sendMessage(html)
+	s.write(frame)
 }
 
-func (s *ChatSession) sendMenuOptions() {
-	html := `<div class="message-container bot"><div class="avatar">🤖</div>`
-	html += `<div class="message-content">`
-	html += `<p><strong>¿Qué necesitás saber?</strong></p>`
-	html += `<p style="margin-top: 16px; color: var(--text-secondary); line-height: 1.8;">`
-	html += `<strong style="color: var(--text-primary);">1</strong> - Buscar todas las fechas de una materia<br>`
-	html += `<strong style="color: var(--text-primary);">2</strong> - Buscar fecha en un turno específico<br>`
-	html += `<strong style="color: var(--text-primary);">3</strong> - Ver qué turnos faltan este año`
-	html += `</p>`
-	html += `<p style="margin-top: 12px; color: var(--text-tertiary); font-size: 13px;">`
-	html += `Escribí el número o el nombre de una materia para comenzar.`
-	html += `</p></div></div>`
+func (s *ChatSession) write(frame []byte) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	s.Conn.WriteMessage(websocket.TextMessage, frame)
+}
 
-	s.sendMessage(html)
+// closeWith le manda m al alumno y después el cierre de WebSocket con code.
+// La conexión termina cuando el navegador contesta el cierre.
+func (s *ChatSession) closeWith(code int, reason string, m chatproto.Message) {
+	s.send(m)
+	s.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
 }
 
-func (s *ChatSession) sendMessage(html string) {
-	s.Conn.WriteMessage(websocket.TextMessage, []byte(html))
+// closeIdle cierra el chat cuando pasa IdleTimeout sin mensajes del alumno
+func (s *ChatSession) closeIdle() {
+	s.log.Info("chat cerrado por inactividad")
+	s.closeWith(websocket.CloseNormalClosure, "inactividad",
+		chatproto.Error("idle_timeout", "💤 Cerré el chat por inactividad. Recargá la página para volver a consultar."))
+	// Si el navegador no contesta el cierre, el loop de lectura corta igual
+	s.Conn.SetReadDeadline(time.Now().Add(writeWait))
+}
+
+// keepAlive manda un ping cada PingInterval hasta que se cierre stop
+func (s *ChatSession) keepAlive(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.Handler.Config.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// decodeText lee los mensajes del cliente HTML, que son el texto tal cual
+func decodeText(data []byte) (string, error) {
+	return string(data), nil
 }
 
 // ============= WebSocket Handler =============
 
+// writeWait es el máximo que puede tardar una escritura o el handshake
+const writeWait = 10 * time.Second
+
 func (h *ChatHandler) HandleWebSocket(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Println("Failed to upgrade:", err)
+		// gorilla ya respondió el error (origen no permitido, handshake inválido)
+		logger(c).Warn("rechazando el WebSocket del chat", "origin", c.GetHeader("Origin"), "error", err)
 		return
 	}
 	defer conn.Close()
 
 	// Crear la sesión
-	session := NewChatSession(conn, h)
-	ctx := context.Background()
+	ip := c.ClientIP()
+	session := NewChatSession(conn, h, logger(c))
+	if !h.Sessions.reserveIP(ip, h.Config.MaxConnsPerIP) {
+		// Se rechaza después del upgrade para que el alumno vea por qué
+		session.log.Warn("demasiados chats abiertos desde la IP", "ip", ip)
+		session.closeWith(websocket.ClosePolicyViolation, "demasiadas conexiones",
+			chatproto.Error("too_many_connections", "⚠️ Hay demasiados chats abiertos desde tu conexión. Cerrá alguna pestaña y recargá la página."))
+		return
+	}
+	defer h.Sessions.releaseIP(ip)
+	if !h.Sessions.add(session) {
+		// Llegó mientras el servidor se apaga
+		session.send(msgRestarting)
+		return
+	}
+	defer h.Sessions.remove(session)
+	start := time.Now()
+	session.log.Info("chat abierto", "ip", ip)
+
+	// Una conexión que no contesta dos pings seguidos se da por muerta
+	conn.SetReadLimit(h.Config.MaxMessageSize)
+	pongWait := 2 * h.Config.PingInterval
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	stop := make(chan struct{})
+	defer close(stop)
+	go session.keepAlive(stop)
+	idle := time.AfterFunc(h.Config.IdleTimeout, session.closeIdle)
+	defer idle.Stop()
 
 	// Disparamos el evento de inicio para mostrar el menú
-	session.FSM.Event(ctx, "start")
+	session.FSM.Event(context.Background(), "start")
 
 	// Loop de lectura
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("Read Error:", err)
+			var netErr net.Error
+			switch {
+			case errors.Is(err, websocket.ErrReadLimit):
+				// gorilla ya mandó el cierre 1009 (mensaje demasiado grande)
+				session.log.Warn("mensaje del chat demasiado grande", "limit", h.Config.MaxMessageSize)
+			case errors.As(err, &netErr) && netErr.Timeout():
+				session.log.Info("chat sin respuesta a los pings")
+			case websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived):
+				session.log.Warn("leyendo del chat", "error", err)
+			}
+			session.log.Info("chat cerrado", "duration_ms", time.Since(start).Milliseconds())
 			break
 		}
-		session.ProcessMessage(string(msg))
-	}
-}
-
-// ============= Helpers Globales =============
-
-func botMsg(text string) string {
-	return `<div class="message-container bot"><div class="avatar">🤖</div><div class="message-content"><p>` + text + `</p></div></div>`
-}
-
-func formatDate(dateStr string) string {
-	if parsed, err := time.Parse("2006-01-02", dateStr); err == nil {
-		return parsed.Format("02/01/2006")
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		idle.Reset(h.Config.IdleTimeout)
+		if !h.MessageLimiter.Allow(ip) {
+			session.log.Warn("límite de mensajes del chat", "ip", ip)
+			session.send(chatproto.Error("rate_limited", "⏳ Estás enviando mensajes muy rápido. Esperá unos segundos y volvé a intentar."))
+			continue
+		}
+		input, err := session.decode(msg)
+		if err != nil {
+			session.send(chatproto.Error("bad_request", "⚠️ No entendí el mensaje: "+err.Error()))
+			continue
+		}
+		session.ProcessMessage(input)
 	}
-	return dateStr
 }
@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mi-bot-unne/internal/chatproto"
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// chatClient conversa con el bot por WebSocket como lo hace chat.html
+type chatClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func (s *testServer) chat(t *testing.T) *chatClient {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("conectando al chat: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &chatClient{t: t, conn: conn}
+}
+
+func (c *chatClient) send(text string) {
+	c.t.Helper()
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(text)); err != nil {
+		c.t.Fatalf("enviando %q: %v", text, err)
+	}
+}
+
+// expect lee mensajes hasta encontrar, en orden, uno o varios textos; varios pueden estar
+// en el mismo mensaje. Los mensajes intermedios se descartan, así el test fija lo que
+// importa de cada paso y no el texto exacto de todos.
+func (c *chatClient) expect(wants ...string) string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var seen []string
+	for {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			c.t.Fatalf("esperando %q: %v\nmensajes recibidos:\n%s", wants[0], err, strings.Join(seen, "\n"))
+		}
+		for len(wants) > 0 && strings.Contains(string(msg), wants[0]) {
+			wants = wants[1:]
+		}
+		if len(wants) == 0 {
+			return string(msg)
+		}
+		seen = append(seen, string(msg))
+	}
+}
+
+// Textos del bot que marcan cada estado de la conversación
+const (
+	msgCarreras  = "¿Qué carrera estudiás?"
+	msgMenu      = "¿Qué necesitás saber?"
+	msgMateria   = "¿Qué materia estás buscando?"
+	msgTurno     = "¿Qué número de turno te interesa?"
+	msgDescarga  = "¿Querés guardar esta información como imagen?"
+	msgOpciones  = "Encontré varias opciones"
+	msgNoEncontr = "No encontré ninguna materia con ese nombre"
+)
+
+type chatStep struct {
+	send string // vacío: solo se leen los mensajes de la conexión
+	want []string
+}
+
+func TestChatFlows(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []chatStep
+	}{
+		{"elegir carrera", []chatStep{
+			{"biologia", []string{"No pude identificar la carrera"}},
+			{"sistemas", []string{"🎓 Ingeniería en Sistemas", msgMenu}},
+		}},
+		{"todas las fechas y descarga", []chatStep{
+			{"1", []string{msgMenu}},
+			{"1", []string{msgMateria}},
+			{"ALGEBRA", []string{"Buscando <strong>algebra</strong>", "Encontré <strong>1 fechas</strong>", "18/02/2099", "Aula 1 - PB", "/cal/materia/"}},
+			{"", []string{msgDescarga}},
+			// looplab/fsm entra al menú antes de correr after_download_yes
+			{"sí", []string{msgMenu, `data-download="card-1"`}},
+		}},
+		{"por turno", []chatStep{
+			{"1", []string{msgMenu}},
+			{"2", []string{msgTurno}},
+			{"11", []string{"número de turno válido"}},
+			{"1", []string{"<strong>Turno 1</strong>"}},
+			{"algebra", []string{"1° Turno", "Ingeniería en Sistemas", "08:00"}},
+			{"", []string{msgDescarga}},
+			{"no", []string{msgMenu}},
+		}},
+		{"turno sin mesa", []chatStep{
+			{"1", []string{msgMenu}},
+			{"2", []string{msgTurno}},
+			{"5", []string{"<strong>Turno 5</strong>"}},
+			{"algebra", []string{"No encontré esta materia en el turno seleccionado", msgMenu}},
+		}},
+		{"búsqueda directa desde el menú", []chatStep{
+			{"1", []string{msgMenu}},
+			{"sistemas operativos", []string{"Encontré <strong>1 fechas</strong>", "Sistemas Operativos"}},
+			{"", []string{msgDescarga}},
+			{"no", []string{msgMenu}},
+		}},
+		{"desambiguación en todas las carreras", []chatStep{
+			{"0", []string{msgMenu}},
+			{"1", []string{msgMateria}},
+			{"i", []string{msgOpciones, `data-send="Álgebra I"`, `data-send="Física I"`}},
+			{"Álgebra I", []string{"Encontré <strong>2 fechas</strong>", "Aula 1 - PB", "Aula Magna"}},
+			{"", []string{msgDescarga}},
+			{"no", []string{msgMenu}},
+		}},
+		{"desambiguación por turno", []chatStep{
+			{"0", []string{msgMenu}},
+			{"2", []string{msgTurno}},
+			{"1", []string{"<strong>Turno 1</strong>"}},
+			{"i", []string{msgOpciones}},
+			{"Análisis Matemático I", []string{"1° Turno", "Laboratorio 1"}},
+			{"", []string{msgDescarga}},
+			{"sí", []string{msgMenu, "¡Listo! Descargando imagen"}},
+		}},
+		{"materia fuera del plan", []chatStep{
+			{"1", []string{msgMenu}},
+			{"fisica", []string{msgNoEncontr + " en el plan de <strong>Ingeniería en Sistemas</strong>", msgMenu}},
+		}},
+		{"la entrada del alumno se escapa", []chatStep{
+			{"0", []string{msgMenu}},
+			{`<img src=x onerror="alert(1)">`, []string{"Buscando <strong>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</strong>", msgNoEncontr}},
+		}},
+		{"materia inexistente en todas las carreras", []chatStep{
+			{"0", []string{msgMenu}},
+			{"quimica", []string{msgNoEncontr + ".", msgMenu}},
+		}},
+		{"mesas cargadas en otra carrera del plan", []chatStep{
+			{"3", []string{msgMenu}},
+			{"analisis", []string{"No hay mesas cargadas para <strong>Profesorado en Física</strong>", "Encontré <strong>1 fechas</strong>"}},
+		}},
+		{"turnos disponibles", []chatStep{
+			{"0", []string{msgMenu}},
+			{"3", []string{"Turnos disponibles", "Turno de invierno"}},
+			{"", []string{msgDescarga}},
+			{"no", []string{msgMenu}},
+		}},
+		{"comandos globales", []chatStep{
+			{"1", []string{msgMenu}},
+			{"1", []string{msgMateria}},
+			{"ayuda", []string{"Ayuda rápida"}},
+			{"2", []string{msgTurno}},
+			{"menu", []string{msgMenu}},
+			{"carrera", []string{msgCarreras}},
+			{"2", []string{"🎓 Licenciatura en Matemática"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			seedChat(t, srv)
+			c := srv.chat(t)
+			c.expect(msgCarreras, "1 - Ingeniería en Sistemas", "3 - Profesorado en Física", "0 - Todas las carreras")
+			for _, step := range tt.steps {
+				if step.send != "" {
+					c.send(step.send)
+				}
+				c.expect(step.want...)
+			}
+		})
+	}
+}
+
+func TestChatWithoutPlanSkipsCarrera(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.DB.Exec("DELETE FROM plan_materias"); err != nil {
+		t.Fatal(err)
+	}
+	c := srv.chat(t)
+	msg := c.expect(msgMenu)
+	if strings.Contains(msg, "🎓") {
+		t.Error("el menú muestra una carrera elegida sin que haya planes cargados")
+	}
+}
+
+func TestChatNoFutureTurnos(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.chat(t)
+	c.expect(msgCarreras)
+	c.send("0")
+	c.expect(msgMenu)
+	c.send("3")
+	c.expect("No hay turnos disponibles")
+	c.expect(msgMenu)
+}
+
+func TestChatMessageLimit(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.chat(t)
+	c.expect(msgCarreras)
+	for i := 0; i < 21; i++ {
+		c.send("ayuda")
+	}
+	c.expect("Estás enviando mensajes muy rápido")
+}
+
+// seedChat carga mesas de Álgebra en Sistemas y Matemática, Análisis y Sistemas
+// Operativos en Sistemas, Física en Física, y un turno futuro
+func seedChat(t *testing.T, srv *testServer) {
+	t.Helper()
+	seedPanel(t, srv)
+	mesas := repository.NewMesaRepository(srv.DB)
+	fecha, _ := models.ParseDate("2099-02-20")
+	hora, _ := models.ParseTimeOfDay("10:00")
+	for _, m := range []models.Mesa{
+		{MateriaID: 2, CarreraID: 1, TurnoID: 1, AulaID: 5, Fecha: fecha, Hora: hora},
+		{MateriaID: 3, CarreraID: 3, TurnoID: 2, AulaID: 6, Fecha: fecha, Hora: hora},
+		{MateriaID: 5, CarreraID: 1, TurnoID: 4},
+	} {
+		if _, err := mesas.Create(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inicio, _ := models.ParseDate("2099-07-01")
+	fin, _ := models.ParseDate("2099-07-10")
+	turno := models.TurnoConfig{Nombre: "Turno de invierno", FechaInicio: inicio, FechaFin: fin}
+	if _, err := repository.NewParamsRepository(srv.DB).CreateTurnoConfig(turno); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChatAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		wantOK  bool
+	}{
+		{"mismo host sin lista", nil, "", true},
+		{"otro sitio sin lista", nil, "https://otro-sitio.com", false},
+		{"origen listado", []string{"https://mesas.unne.edu.ar"}, "https://mesas.unne.edu.ar", true},
+		{"origen no listado", []string{"https://mesas.unne.edu.ar"}, "https://otro-sitio.com", false},
+		{"comodín", []string{"*"}, "https://otro-sitio.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestServer(t).DB
+			h := NewChatHandler(repository.NewMesaRepository(db), repository.NewParamsRepository(db), ChatConfig{AllowedOrigins: tt.allowed})
+			r := gin.New()
+			r.GET("/ws", h.HandleWebSocket)
+			srv := httptest.NewServer(r)
+			defer srv.Close()
+
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", header)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("Dial: err = %v, want ok = %v", err, tt.wantOK)
+			}
+			if err == nil {
+				conn.Close()
+			} else if res.StatusCode != http.StatusForbidden {
+				t.Errorf("status %d, want 403", res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestChatMaxTurno(t *testing.T) {
+	srv := newTestServer(t)
+	h := NewChatHandler(repository.NewMesaRepository(srv.DB), repository.NewParamsRepository(srv.DB), ChatConfig{MaxTurno: 12})
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	ws := httptest.NewServer(r)
+	defer ws.Close()
+
+	c := (&testServer{Server: ws}).chat(t)
+	c.expect(msgCarreras)
+	c.send("0")
+	c.send("2")
+	c.expect("(1 al 12)")
+	c.send("13")
+	c.expect("número de turno válido (1 al 12)")
+	c.send("12")
+	c.expect("<strong>Turno 12</strong>")
+}
+
+func TestChatShutdown(t *testing.T) {
+	srv := newTestServer(t)
+	clients := []*chatClient{srv.chat(t), srv.chat(t)}
+	for _, c := range clients {
+		c.expect(msgCarreras)
+	}
+	if n := srv.Router.Chat.Sessions.Len(); n != 2 {
+		t.Fatalf("sesiones abiertas = %d, want 2", n)
+	}
+
+	// Los clientes leen hasta el cierre, que es lo que responde al frame de cierre
+	done := make(chan error, len(clients))
+	for _, c := range clients {
+		go func() {
+			c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			var sawRestart bool
+			for {
+				_, msg, err := c.conn.ReadMessage()
+				if err != nil {
+					if !sawRestart {
+						err = errors.New("se cerró sin avisar del reinicio")
+					} else if websocket.IsCloseError(err, websocket.CloseGoingAway) {
+						err = nil
+					}
+					done <- err
+					return
+				}
+				sawRestart = sawRestart || strings.Contains(string(msg), "El servidor se está reiniciando")
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Router.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	for range clients {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+	if n := srv.Router.Chat.Sessions.Len(); n != 0 {
+		t.Errorf("sesiones abiertas = %d después de Shutdown", n)
+	}
+
+	// Un chat que llega mientras se apaga recibe el aviso y se cierra
+	c := srv.chat(t)
+	c.expect("El servidor se está reiniciando")
+	if _, _, err := c.conn.ReadMessage(); err == nil {
+		t.Error("la conexión sigue abierta después del aviso")
+	}
+}
+
+func TestChatShutdownTimeout(t *testing.T) {
+	srv := newTestServer(t)
+	// El cliente no lee, así que nunca contesta el cierre y la sesión no termina sola
+	srv.chat(t)
+	for srv.Router.Chat.Sessions.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Router.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown: err = %v, want DeadlineExceeded", err)
+	}
+	// Al cortar la conexión el loop de lectura termina y la sesión se quita
+	deadline := time.Now().Add(time.Second)
+	for srv.Router.Chat.Sessions.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("la sesión sigue registrada después de cortar la conexión")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// expectClose lee hasta que el servidor cierra la conexión y verifica el código
+func (c *chatClient) expectClose(code int) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, code) {
+			c.t.Fatalf("cierre: %v, want código %d", err, code)
+		}
+		return
+	}
+}
+
+// waitSessions espera a que el registro tenga n chats abiertos
+func waitSessions(t *testing.T, srv *testServer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.Router.Chat.Sessions.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("chats abiertos = %d, want %d", srv.Router.Chat.Sessions.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChatReadLimit(t *testing.T) {
+	srv := newTestServer(t, func(cfg *RouterConfig) { cfg.Chat.MaxMessageSize = 64 })
+	c := srv.chat(t)
+	c.expect(msgCarreras)
+	c.send(strings.Repeat("a", 64))
+	c.expect("No pude identificar la carrera")
+	c.send(strings.Repeat("a", 65))
+	c.expectClose(websocket.CloseMessageTooBig)
+	waitSessions(t, srv, 0)
+}
+
+func TestChatMaxConnsPerIP(t *testing.T) {
+	srv := newTestServer(t, func(cfg *RouterConfig) { cfg.Chat.MaxConnsPerIP = 2 })
+	first, second := srv.chat(t), srv.chat(t)
+	first.expect(msgCarreras)
+	second.expect(msgCarreras)
+
+	third := srv.chat(t)
+	third.expect("Hay demasiados chats abiertos desde tu conexión")
+	third.expectClose(websocket.ClosePolicyViolation)
+
+	// Al cerrar uno se libera el lugar
+	first.conn.Close()
+	waitSessions(t, srv, 1)
+	srv.chat(t).expect(msgCarreras)
+}
+
+func TestChatIdleTimeout(t *testing.T) {
+	srv := newTestServer(t, func(cfg *RouterConfig) { cfg.Chat.IdleTimeout = 300 * time.Millisecond })
+	c := srv.chat(t)
+	c.expect(msgCarreras)
+	// Cada mensaje reinicia la espera
+	time.Sleep(200 * time.Millisecond)
+	c.send("0")
+	c.expect(msgMenu)
+	time.Sleep(200 * time.Millisecond)
+	if n := srv.Router.Chat.Sessions.Len(); n != 1 {
+		t.Fatalf("el chat se cerró antes de la inactividad: %d abiertos", n)
+	}
+	c.expect("Cerré el chat por inactividad")
+	c.expectClose(websocket.CloseNormalClosure)
+	waitSessions(t, srv, 0)
+}
+
+func TestChatKeepAlive(t *testing.T) {
+	srv := newTestServer(t, func(cfg *RouterConfig) { cfg.Chat.PingInterval = 50 * time.Millisecond })
+
+	// Este cliente lee, y al leer contesta los pings
+	alive := srv.chat(t)
+	alive.expect(msgCarreras)
+	go func() {
+		for {
+			if _, _, err := alive.conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Este no lee nunca, así que no contesta y el servidor lo da por muerto
+	srv.chat(t)
+	waitSessions(t, srv, 2)
+	time.Sleep(300 * time.Millisecond)
+	waitSessions(t, srv, 1)
+
+	if err := alive.conn.WriteMessage(websocket.TextMessage, []byte("0")); err != nil {
+		t.Errorf("el cliente que contesta los pings quedó cortado: %v", err)
+	}
+}
+
+// jsonClient conversa con el bot con el protocolo JSON, como una app
+type jsonClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func (s *testServer) chatJSON(t *testing.T) *jsonClient {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: []string{chatproto.JSON}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("conectando al chat: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if conn.Subprotocol() != chatproto.JSON {
+		t.Fatalf("subprotocolo %q, want %q", conn.Subprotocol(), chatproto.JSON)
+	}
+	return &jsonClient{t: t, conn: conn}
+}
+
+func (c *jsonClient) send(text string) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(chatproto.Input{Type: "message", Text: text}); err != nil {
+		c.t.Fatalf("enviando %q: %v", text, err)
+	}
+}
+
+// expect lee mensajes hasta el primero de tipo typ y lo devuelve
+func (c *jsonClient) expect(typ string) chatproto.Message {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var seen []string
+	for {
+		var m chatproto.Message
+		if err := c.conn.ReadJSON(&m); err != nil {
+			c.t.Fatalf("esperando un mensaje %s: %v\ntipos recibidos: %v", typ, err, seen)
+		}
+		if m.Type == typ {
+			return m
+		}
+		seen = append(seen, m.Type)
+	}
+}
+
+func TestChatJSONProtocol(t *testing.T) {
+	srv := newTestServer(t)
+	seedChat(t, srv)
+	c := srv.chatJSON(t)
+
+	carreras := c.expect(chatproto.TypeOptions)
+	if carreras.Code != "carreras" || len(carreras.Options) != 4 || carreras.Options[0] != (chatproto.Option{Value: "1", Label: "Ingeniería en Sistemas"}) {
+		t.Errorf("carreras = %+v", carreras)
+	}
+	c.send("1")
+	if menu := c.expect(chatproto.TypeMenu); menu.Carrera != "Ingeniería en Sistemas" || len(menu.Options) != 3 {
+		t.Errorf("menú = %+v", menu)
+	}
+
+	c.send("algebra")
+	if found := c.expect(chatproto.TypeText); found.Code != "searching" || found.Emphasis[0] != "algebra" {
+		t.Errorf("aviso de búsqueda = %+v", found)
+	}
+	card := c.expect(chatproto.TypeSchedule).Schedule
+	if card.Materia != "Álgebra I" || len(card.Mesas) != 1 || card.Mesas[0].Fecha.String() != "2099-02-18" || card.Mesas[0].Aula != "Aula 1 - PB" {
+		t.Errorf("tarjeta = %+v", card)
+	}
+	if prompt := c.expect(chatproto.TypeDownloadPrompt); prompt.CardID != card.CardID {
+		t.Errorf("la descarga ofrece %q, want %q", prompt.CardID, card.CardID)
+	}
+	c.send("sí")
+	if download := c.expect(chatproto.TypeDownload); download.CardID != card.CardID {
+		t.Errorf("descarga de %q, want %q", download.CardID, card.CardID)
+	}
+
+	c.conn.WriteMessage(websocket.TextMessage, []byte("algebra"))
+	if e := c.expect(chatproto.TypeError); e.Code != "bad_request" {
+		t.Errorf("error = %+v", e)
+	}
+	c.send("3")
+	if turnos := c.expect(chatproto.TypeTurnos).Turnos; len(turnos.Turnos) != 1 || turnos.Turnos[0].Nombre != "Turno de invierno" {
+		t.Errorf("turnos = %+v", turnos)
+	}
+}
+
+func TestChatHTMLSubprotocol(t *testing.T) {
+	srv := newTestServer(t)
+	dialer := websocket.Dialer{Subprotocols: []string{chatproto.HTML}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != chatproto.HTML {
+		t.Errorf("subprotocolo %q, want %q", conn.Subprotocol(), chatproto.HTML)
+	}
+	c := &chatClient{t: t, conn: conn}
+	c.expect(`<div class="message-container bot">`, msgCarreras)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	csrfFormField = "csrf_token"
+	csrfHeader    = "X-CSRF-Token"
+)
+
+// csrfToken deriva el token CSRF del token de sesión con la clave del servidor. Como la
+// cookie es HttpOnly, otro sitio no puede leerla ni calcular el HMAC, y no hace falta
+// guardarlo aparte.
+func csrfToken(secret []byte, sessionToken string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("csrf:" + sessionToken))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CSRFMiddleware exige el token en toda request que modifique datos.
+// Va después de AuthMiddleware, que deja el token de sesión en el contexto.
+func CSRFMiddleware(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := csrfToken(secret, c.GetString("session_token"))
+		c.Set(csrfFormField, expected)
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		sent := c.GetHeader(csrfHeader)
+		if sent == "" {
+			sent = c.PostForm(csrfFormField)
+		}
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(expected)) != 1 {
+			c.String(http.StatusForbidden, "Token CSRF inválido. Recargá la página e intentá de nuevo.")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// maxFormBody es el tamaño máximo del cuerpo de los formularios del panel
+const maxFormBody = 1 << 20
+
+// bodyLimits son los límites de las rutas del panel que suben archivos. El margen cubre
+// los encabezados multipart y los demás campos del formulario.
+var bodyLimits = map[string]int64{
+	"/admin/importar": maxImportSize + 64<<10,
+}
+
+// BodyLimit corta los cuerpos que superan el límite de la ruta. Va antes de
+// CSRFMiddleware, que al buscar el token en el formulario ya lee el cuerpo entero
+// (y un multipart, con el archivo incluido).
+func BodyLimit(c *gin.Context) {
+	limit, ok := bodyLimits[c.FullPath()]
+	if !ok {
+		limit = maxFormBody
+	}
+	if c.Request.ContentLength > limit {
+		c.String(http.StatusRequestEntityTooLarge, fmt.Sprintf("El envío supera los %d MB.", limit>>20))
+		c.Abort()
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	c.Next()
+}
+
+// renderHTML agrega a la vista los datos comunes del panel (usuario y token CSRF)
+func renderHTML(c *gin.Context, status int, name string, data gin.H) {
+	if data == nil {
+		data = gin.H{}
+	}
+	if _, ok := data["user"]; !ok {
+		data["user"] = currentUser(c)
+	}
+	data[csrfFormField] = c.GetString(csrfFormField)
+	c.HTML(status, name, data)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// requiredTemplates son las páginas sin las que el servidor no sirve
+var requiredTemplates = []string{"chat.html", "login.html", "admin.html"}
+
+// HealthHandler responde las sondas de Docker y del balanceador
+type HealthHandler struct {
+	DB       *sql.DB
+	Engine   *gin.Engine
+	Sessions *ChatSessions
+}
+
+// Healthz dice que el proceso está vivo; no consulta nada para no reiniciarlo por una
+// falla de la base que se resuelve sola
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz dice si puede atender: la base responde, las plantillas están cargadas y el
+// servidor no se está apagando. Con algún chequeo fallido responde 503.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+	check := func(name string, err error) {
+		if err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+	check("database", h.DB.PingContext(ctx))
+	check("templates", templatesLoaded(h.Engine))
+	if h.Sessions.Closing() {
+		check("shutdown", fmt.Errorf("el servidor se está apagando"))
+	}
+
+	status, code := "ok", http.StatusOK
+	if !ready {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// templatesLoaded revisa que gin tenga las plantillas requeridas. En modo debug gin
+// las relee del disco en cada request, así que se revisan los archivos.
+func templatesLoaded(r *gin.Engine) error {
+	var lookup func(name string) bool
+	switch h := r.HTMLRender.(type) {
+	case render.HTMLProduction:
+		lookup = func(name string) bool { return h.Template.Lookup(name) != nil }
+	case render.HTMLDebug:
+		files := h.Files
+		if h.Glob != "" {
+			files, _ = filepath.Glob(h.Glob)
+		}
+		lookup = func(name string) bool {
+			for _, f := range files {
+				if filepath.Base(f) == name {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return fmt.Errorf("no hay plantillas cargadas")
+	}
+	for _, name := range requiredTemplates {
+		if !lookup(name) {
+			return fmt.Errorf("falta la plantilla %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"mi-bot-unne/internal/metrics"
+	"mi-bot-unne/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHealthz(t *testing.T) {
+	srv := newTestServer(t)
+	res := srv.client(t).get("/healthz")
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status %d, want 200", res.StatusCode)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name      string
+		breakIt   func(srv *testServer)
+		wantCode  int
+		wantCheck string // chequeo que tiene que fallar
+	}{
+		{"todo bien", func(*testServer) {}, http.StatusOK, ""},
+		{"base cerrada", func(srv *testServer) { srv.DB.Close() }, http.StatusServiceUnavailable, "database"},
+		{"apagando", func(srv *testServer) { srv.Router.Shutdown(context.Background()) }, http.StatusServiceUnavailable, "shutdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			tt.breakIt(srv)
+
+			res := srv.client(t).get("/readyz")
+			if res.StatusCode != tt.wantCode {
+				t.Errorf("status %d, want %d", res.StatusCode, tt.wantCode)
+			}
+			var body struct {
+				Status string            `json:"status"`
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Checks["templates"] != "ok" {
+				t.Errorf("templates = %q", body.Checks["templates"])
+			}
+			if tt.wantCheck != "" && body.Checks[tt.wantCheck] == "ok" {
+				t.Errorf("checks = %v, want %s fallido", body.Checks, tt.wantCheck)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	srv := newTestServer(t)
+	seedChat(t, srv)
+	hits := testutil.ToFloat64(metrics.ChatSearches.WithLabelValues(metrics.SearchHit))
+	misses := testutil.ToFloat64(metrics.ChatSearches.WithLabelValues(metrics.SearchMiss))
+	menuMsgs := testutil.ToFloat64(metrics.ChatMessages.WithLabelValues("menu"))
+	creates := testutil.ToFloat64(metrics.AdminMutations.WithLabelValues("mesa", models.AuditCreate))
+
+	c := srv.chat(t)
+	c.expect(msgCarreras)
+	c.send("0")
+	c.expect(msgMenu)
+	c.send("algebra")
+	c.expect(msgDescarga)
+	c.send("no")
+	c.send("quimica")
+	c.expect(msgNoEncontr)
+
+	if got := testutil.ToFloat64(metrics.ChatSearches.WithLabelValues(metrics.SearchHit)) - hits; got != 1 {
+		t.Errorf("búsquedas encontradas = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.ChatSearches.WithLabelValues(metrics.SearchMiss)) - misses; got != 1 {
+		t.Errorf("búsquedas sin resultado = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.ChatMessages.WithLabelValues("menu")) - menuMsgs; got != 2 {
+		t.Errorf("mensajes en el menú = %v, want 2", got)
+	}
+
+	if res := srv.login(t, superEmail).post("/admin/guardar", mesaForm("1")); res.StatusCode != http.StatusFound {
+		t.Fatalf("guardar mesa: status %d", res.StatusCode)
+	}
+	if got := testutil.ToFloat64(metrics.AdminMutations.WithLabelValues("mesa", models.AuditCreate)) - creates; got != 1 {
+		t.Errorf("altas de mesas = %v, want 1", got)
+	}
+
+	body := srv.client(t).get("/metrics").Body
+	for _, want := range []string{
+		"mibot_chat_sessions_active",
+		`mibot_chat_messages_total{state="menu"}`,
+		`mibot_chat_searches_total{result="hit"}`,
+		`mibot_repository_query_duration_seconds_count{query="mesas.GetUniqueMaterias"}`,
+		`mibot_repository_query_duration_seconds_count{query="params.GetMateria"}`,
+		`mibot_repository_query_duration_seconds_count{query="sessions.Create"}`,
+		`mibot_repository_query_duration_seconds_count{query="users.GetByEmail"}`,
+		`mibot_admin_mutations_total{action="create",entity="mesa"}`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics no tiene %s", want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mi-bot-unne/internal/logging"
+)
+
+// logBuffer junta las líneas JSON que escriben las goroutines del servidor
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) lines(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(l), &m); err != nil {
+			t.Fatalf("línea de log que no es JSON: %q", l)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+// find devuelve las líneas con ese msg
+func find(lines []map[string]any, msg string) []map[string]any {
+	var found []map[string]any
+	for _, l := range lines {
+		if l["msg"] == msg {
+			found = append(found, l)
+		}
+	}
+	return found
+}
+
+func newLoggedServer(t *testing.T, level string) (*testServer, *logBuffer) {
+	logs := &logBuffer{}
+	srv := newTestServer(t, func(cfg *RouterConfig) {
+		cfg.Logger = logging.New(logs, level)
+		cfg.RequestLog = true
+	})
+	return srv, logs
+}
+
+func TestRequestID(t *testing.T) {
+	srv, logs := newLoggedServer(t, "info")
+
+	res, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	generated := res.Header.Get(requestIDHeader)
+	if len(generated) != 16 {
+		t.Errorf("X-Request-ID generado = %q", generated)
+	}
+
+	for _, tt := range []struct{ sent, want string }{
+		{"abc-123", "abc-123"},
+		{"no vale<script>", ""}, // se reemplaza por uno nuevo
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/healthz", nil)
+		req.Header.Set(requestIDHeader, tt.sent)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		got := res.Header.Get(requestIDHeader)
+		if tt.want != "" && got != tt.want || tt.want == "" && (got == tt.sent || got == "") {
+			t.Errorf("enviado %q: X-Request-ID = %q", tt.sent, got)
+		}
+	}
+
+	requests := find(logs.lines(t), "request")
+	if len(requests) != 3 {
+		t.Fatalf("líneas de request = %d, want 3", len(requests))
+	}
+	if requests[0]["request_id"] != generated || requests[1]["request_id"] != "abc-123" || requests[0]["status"] != float64(200) {
+		t.Errorf("líneas de request = %v", requests)
+	}
+}
+
+func TestLogsIncludeUser(t *testing.T) {
+	srv, logs := newLoggedServer(t, "info")
+	srv.login(t, lecturaEmail).get("/admin")
+
+	var user any
+	for _, l := range find(logs.lines(t), "request") {
+		if l["path"] == "/admin" {
+			user = l["user"]
+		}
+	}
+	if user != lecturaEmail {
+		t.Errorf("user en la línea de /admin = %v, want %s", user, lecturaEmail)
+	}
+}
+
+func TestChatLogs(t *testing.T) {
+	tests := []struct {
+		level     string
+		wantInput bool
+	}{
+		{"info", false},
+		{"debug", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			srv, logs := newLoggedServer(t, tt.level)
+			c := srv.chat(t)
+			c.expect(msgCarreras)
+			c.send("0")
+			c.expect(msgMenu)
+			c.conn.Close()
+			// "chat cerrado" se registra antes de que la sesión salga del registro
+			for srv.Router.Chat.Sessions.Len() > 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			lines := logs.lines(t)
+			opened := find(lines, "chat abierto")
+			if len(opened) != 1 || opened[0]["session_id"] == nil || opened[0]["request_id"] == nil {
+				t.Fatalf("chat abierto = %v", opened)
+			}
+			session := opened[0]["session_id"]
+			closed := find(lines, "chat cerrado")
+			if len(closed) != 1 || closed[0]["session_id"] != session {
+				t.Errorf("chat cerrado = %v, want session_id %v", closed, session)
+			}
+
+			msgs := find(lines, "mensaje del chat")
+			if tt.wantInput != (len(msgs) == 1) {
+				t.Fatalf("mensajes registrados = %v", msgs)
+			}
+			if tt.wantInput && (msgs[0]["input"] != "0" || msgs[0]["session_id"] != session) {
+				t.Errorf("mensaje del chat = %v", msgs[0])
+			}
+		})
+	}
+}
+
+func TestFormDumpOnlyInDebug(t *testing.T) {
+	for _, level := range []string{"info", "debug"} {
+		t.Run(level, func(t *testing.T) {
+			srv, logs := newLoggedServer(t, level)
+			form := mesaForm("1")
+			form.Set("materia_id", "0")
+			srv.login(t, superEmail).post("/admin/guardar", form)
+
+			dumps := find(logs.lines(t), "mesa inválida")
+			if level == "info" && len(dumps) != 0 {
+				t.Errorf("con info se volcó el formulario: %v", dumps)
+			}
+			if level == "debug" {
+				if len(dumps) != 1 {
+					t.Fatalf("formularios volcados = %d, want 1", len(dumps))
+				}
+				form := dumps[0]["form"].(map[string]any)
+				if form["hora"] == nil || form[csrfFormField] != logging.Redacted {
+					t.Errorf("form = %v", form)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"mi-bot-unne/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit corta con 429 a las IPs que superan el límite del limiter
+func RateLimit(l *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.Allow(c.ClientIP()) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(l.Window.Seconds()))))
+			c.String(http.StatusTooManyRequests, "Demasiadas solicitudes, probá de nuevo en unos segundos")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
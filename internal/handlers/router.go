@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"mi-bot-unne/internal/display"
+	"mi-bot-unne/internal/metrics"
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/ratelimit"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouterConfig reúne lo que cambia entre el servidor y los tests
+type RouterConfig struct {
+	Templates      string // Glob de las plantillas, ej. "templates/*"
+	Cookie         CookieConfig
+	Chat           ChatConfig
+	API            APIConfig
+	TrustedProxies []string
+	// PublicLimit es el máximo de solicitudes por minuto y por IP a las rutas públicas; 0 usa 120
+	PublicLimit int
+	// RequestLog registra una línea por request en nivel info
+	RequestLog bool
+	// Logger recibe todos los logs de las requests y del chat; nil usa slog.Default()
+	Logger *slog.Logger
+}
+
+// Router es el engine de gin con lo que hace falta para apagarlo ordenadamente
+type Router struct {
+	*gin.Engine
+	Chat *ChatHandler
+}
+
+// Shutdown cierra los chats abiertos; se llama después de http.Server.Shutdown,
+// que no espera a las conexiones WebSocket
+func (r *Router) Shutdown(ctx context.Context) error {
+	return r.Chat.Sessions.Shutdown(ctx)
+}
+
+// NewRouter arma el servidor con todas las rutas públicas y del panel sobre db
+func NewRouter(db *sql.DB, cfg RouterConfig) (*Router, error) {
+	mesaRepo := repository.NewMesaRepository(db)
+	paramsRepo := repository.NewParamsRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+
+	chatHandler := NewChatHandler(mesaRepo, paramsRepo, cfg.Chat)
+	authHandler := NewAuthHandler(userRepo, sessionRepo, tokenRepo, cfg.Cookie)
+	adminHandler := NewAdminHandler(mesaRepo, paramsRepo)
+	userHandler := NewUserHandler(userRepo, sessionRepo, tokenRepo, paramsRepo)
+	auditHandler := NewAuditHandler(auditRepo)
+	calendarHandler := NewCalendarHandler(mesaRepo, paramsRepo)
+	apiHandler := NewAPIHandler(mesaRepo, paramsRepo)
+	adminAPIHandler := NewAdminAPIHandler(mesaRepo, paramsRepo)
+
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	r := gin.New()
+	r.Use(RequestID(cfg.Logger))
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logger(c).Error("panic atendiendo la request", "error", err, "stack", string(debug.Stack()))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	if cfg.RequestLog {
+		r.Use(RequestLog())
+	}
+	r.SetFuncMap(display.FuncMap())
+	r.LoadHTMLGlob(cfg.Templates)
+
+	// La IP del cliente alimenta los límites: solo se confía en X-Forwarded-For de los proxies declarados
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	if cfg.PublicLimit <= 0 {
+		cfg.PublicLimit = 120
+	}
+
+	// Rutas Públicas, con límite de solicitudes por IP
+	// Sondas y métricas, fuera del límite por IP para que Docker y Prometheus no lo agoten
+	healthHandler := &HealthHandler{DB: db, Engine: r, Sessions: chatHandler.Sessions}
+	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	public := r.Group("", RateLimit(ratelimit.New(cfg.PublicLimit, time.Minute)))
+	public.GET("/", chatHandler.ShowChat)
+	public.GET("/ws", chatHandler.HandleWebSocket)
+
+	// Calendarios iCalendar (.ics)
+	public.GET("/cal/materia/:name", calendarHandler.MateriaFeed)
+	public.GET("/cal/carrera/:name", calendarHandler.CarreraFeed)
+	public.GET("/cal/turno/:name", calendarHandler.TurnoFeed)
+
+	// API pública de solo lectura en JSON
+	api := public.Group("/api/v1", CORS(cfg.API.CORSOrigins))
+	api.GET("/mesas", apiHandler.ListMesas)
+	api.GET("/mesas/:id", apiHandler.GetMesa)
+	api.GET("/materias", apiHandler.ListMaterias)
+	api.GET("/carreras", apiHandler.ListCarreras)
+	api.GET("/sedes", apiHandler.ListSedes)
+	api.GET("/aulas", apiHandler.ListAulas)
+	api.GET("/turnos", apiHandler.ListTurnos)
+	api.GET("/openapi.json", apiHandler.OpenAPI)
+	// Los preflight los contesta el middleware CORS
+	api.OPTIONS("/*path", func(c *gin.Context) {})
+
+	csrf := CSRFMiddleware(authHandler.Cookie.Secret)
+
+	// Rutas de Autenticación
+	public.GET("/login", authHandler.ShowLogin)
+	public.POST("/do-login", authHandler.Login)
+	r.POST("/logout", authHandler.AuthMiddleware(), BodyLimit, csrf, authHandler.Logout)
+
+	// Rutas Protegidas (Admin)
+	adminGroup := r.Group("/admin")
+	adminGroup.Use(authHandler.AuthMiddleware(), BodyLimit, csrf)
+	{
+		// Cualquier usuario logueado (incluido solo lectura)
+		adminGroup.GET("", adminHandler.ShowDashboard)
+		adminGroup.GET("/config", adminHandler.ShowParams) // New config page
+		adminGroup.GET("/plan", adminHandler.ShowPlan)
+		adminGroup.GET("/exportar/:format", adminHandler.ExportMesas)
+		adminGroup.GET("/api/aulas", adminHandler.GetAulas)
+		adminGroup.GET("/cuenta", authHandler.ShowAccount)
+		adminGroup.POST("/cuenta/password", authHandler.ChangePassword)
+		adminGroup.POST("/cuenta/tokens", authHandler.CreateToken)
+		adminGroup.POST("/cuenta/tokens/:id/revocar", authHandler.RevokeToken)
+
+		// ABM de mesas: superadmin y secretaría (de su carrera)
+		mesasGroup := adminGroup.Group("", RequireRole(models.RoleSuperadmin, models.RoleSecretaria))
+		mesasGroup.POST("/guardar", adminHandler.CreateMesa)
+		mesasGroup.POST("/borrar/:id", adminHandler.DeleteMesa)
+		mesasGroup.GET("/mesas/:id/edit", adminHandler.ShowEditMesa)
+		mesasGroup.POST("/mesas/:id", adminHandler.UpdateMesa)
+		mesasGroup.GET("/importar", adminHandler.ShowImport)
+		mesasGroup.POST("/importar", adminHandler.PreviewImport)
+		mesasGroup.POST("/importar/confirmar", adminHandler.ConfirmImport)
+
+		// Planes de estudio: secretaría edita el de su carrera
+		mesasGroup.POST("/plan", adminHandler.StorePlanMateria)
+		mesasGroup.POST("/plan/:id", adminHandler.UpdatePlanMateria)
+		mesasGroup.POST("/plan/:id/delete", adminHandler.DeletePlanMateria)
+
+		// Parámetros globales y usuarios: solo superadmin
+		superGroup := adminGroup.Group("", RequireRole(models.RoleSuperadmin))
+		superGroup.POST("/materias", adminHandler.StoreMateria)
+		superGroup.POST("/carreras", adminHandler.StoreCarrera) // New carrera handler
+		superGroup.POST("/sedes", adminHandler.StoreSede)
+		superGroup.POST("/aulas", adminHandler.StoreAula)
+
+		superGroup.POST("/turnos", adminHandler.StoreTurnoConfig)
+		superGroup.POST("/turnos/update/:id", adminHandler.UpdateTurnoConfig)
+		superGroup.POST("/turnos/delete/:id", adminHandler.DeleteTurnoConfig)
+
+		// Generic Config CRUD
+		superGroup.GET("/config/edit/:type/:id", adminHandler.ShowEditParam)
+		superGroup.POST("/config/update/:type/:id", adminHandler.UpdateParam)
+		superGroup.GET("/config/delete/:type/:id", adminHandler.ShowDeleteParam)
+		superGroup.POST("/config/delete/:type/:id", adminHandler.DeleteParam)
+		superGroup.POST("/config/archive/:type/:id", adminHandler.ArchiveParam)
+		superGroup.POST("/config/restore/:type/:id", adminHandler.RestoreParam)
+
+		superGroup.GET("/usuarios", userHandler.ShowUsers)
+		superGroup.POST("/usuarios", userHandler.CreateUser)
+		superGroup.POST("/usuarios/:id", userHandler.UpdateUser)
+		superGroup.POST("/usuarios/:id/reset", userHandler.ResetPassword)
+		superGroup.POST("/usuarios/:id/delete", userHandler.DeleteUser)
+		superGroup.GET("/auditoria", auditHandler.ShowAudit)
+		superGroup.POST("/sin-mapear/:id/descartar", adminHandler.DismissUnmapped)
+	}
+
+	// API de administración en JSON: token en lugar de sesión, así que sin CSRF
+	adminAPI := r.Group("/api/admin/v1", authHandler.TokenAuth())
+	{
+		mesasRead := adminAPI.Group("", RequireScope(models.ScopeMesasRead))
+		mesasRead.GET("/mesas", apiHandler.ListMesas)
+		mesasRead.GET("/mesas/:id", apiHandler.GetMesa)
+
+		mesasWrite := adminAPI.Group("", RequireScope(models.ScopeMesasWrite))
+		mesasWrite.POST("/mesas", adminAPIHandler.CreateMesa)
+		mesasWrite.POST("/mesas/lote", adminAPIHandler.CreateMesas)
+		mesasWrite.PUT("/mesas/:id", adminAPIHandler.UpdateMesa)
+		mesasWrite.DELETE("/mesas/:id", adminAPIHandler.DeleteMesa)
+
+		paramsRead := adminAPI.Group("", RequireScope(models.ScopeParamsRead))
+		paramsRead.GET("/materias", apiHandler.ListMaterias)
+		paramsRead.GET("/carreras", apiHandler.ListCarreras)
+		paramsRead.GET("/sedes", apiHandler.ListSedes)
+		paramsRead.GET("/aulas", apiHandler.ListAulas)
+		paramsRead.GET("/turnos", adminAPIHandler.ListTurnos)
+		paramsRead.GET("/turnos/:id", adminAPIHandler.GetTurno)
+
+		paramsWrite := adminAPI.Group("", RequireScope(models.ScopeParamsWrite))
+		for path, tipo := range paramRoutes {
+			paramsRead.GET("/"+path+"/:id", adminAPIHandler.GetParam(tipo))
+			paramsWrite.POST("/"+path, adminAPIHandler.CreateParam(tipo))
+			paramsWrite.PUT("/"+path+"/:id", adminAPIHandler.UpdateParam(tipo))
+			paramsWrite.DELETE("/"+path+"/:id", adminAPIHandler.DeleteParam(tipo))
+			paramsWrite.POST("/"+path+"/:id/archivar", adminAPIHandler.ArchiveParam(tipo))
+			paramsWrite.POST("/"+path+"/:id/restaurar", adminAPIHandler.RestoreParam(tipo))
+		}
+		paramsWrite.POST("/turnos", adminAPIHandler.CreateTurno)
+		paramsWrite.PUT("/turnos/:id", adminAPIHandler.UpdateTurno)
+		paramsWrite.DELETE("/turnos/:id", adminAPIHandler.DeleteTurno)
+	}
+
+	return &Router{Engine: r, Chat: chatHandler}, nil
+}
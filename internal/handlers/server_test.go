@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"mi-bot-unne/internal/database"
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Usuarios que crea newTestServer, todos con testPassword
+const (
+	superEmail      = "admin@unne.edu.ar"
+	secretariaEmail = "sistemas@unne.edu.ar" // Secretaría de Ingeniería en Sistemas (carrera 1)
+	lecturaEmail    = "consulta@unne.edu.ar"
+	testPassword    = "secreto123"
+)
+
+var testSecret = []byte("clave-de-los-tests-con-32-caracteres")
+
+type testServer struct {
+	*httptest.Server
+	DB     *sql.DB
+	Router *Router
+}
+
+// newTestServer levanta el router completo sobre una base en memoria con los
+// datos de ejemplo de InitDB y un usuario por rol. opts ajustan la configuración
+// del router.
+func newTestServer(t *testing.T, opts ...func(*RouterConfig)) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	users := repository.NewUserRepository(db)
+	for _, u := range []models.User{
+		{Email: superEmail, Role: models.RoleSuperadmin},
+		{Email: secretariaEmail, Role: models.RoleSecretaria, CarreraID: 1},
+		{Email: lecturaEmail, Role: models.RoleLectura},
+	} {
+		if err := users.Create(u, testPassword); err != nil {
+			t.Fatalf("creando %s: %v", u.Email, err)
+		}
+	}
+
+	cfg := RouterConfig{
+		Templates: "../../templates/*",
+		Cookie:    CookieConfig{Secret: testSecret},
+		// Sin la pausa de 600 ms los flujos del chat corren al instante
+		Chat:        ChatConfig{ResultsPause: 10 * time.Millisecond},
+		PublicLimit: 10000,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	r, err := NewRouter(db, cfg)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, DB: db, Router: r}
+}
+
+// testClient guarda la cookie de sesión y no sigue redirecciones, para poder verificarlas
+type testClient struct {
+	t    *testing.T
+	srv  *testServer
+	http *http.Client
+}
+
+func (s *testServer) client(t *testing.T) *testClient {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	return &testClient{t: t, srv: s, http: &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// login devuelve un cliente con la sesión iniciada
+func (s *testServer) login(t *testing.T, email string) *testClient {
+	t.Helper()
+	c := s.client(t)
+	res := c.postForm("/do-login", url.Values{"email": {email}, "password": {testPassword}})
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("login de %s: status %d", email, res.StatusCode)
+	}
+	return c
+}
+
+// csrf calcula el token del formulario a partir de la cookie de sesión actual
+func (c *testClient) csrf() string {
+	u, _ := url.Parse(c.srv.URL)
+	for _, ck := range c.http.Jar.Cookies(u) {
+		if ck.Name == sessionCookie {
+			return csrfToken(testSecret, ck.Value)
+		}
+	}
+	return ""
+}
+
+type response struct {
+	StatusCode int
+	Location   string
+	Body       string
+}
+
+func (c *testClient) do(req *http.Request) response {
+	c.t.Helper()
+	res, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return response{StatusCode: res.StatusCode, Location: res.Header.Get("Location"), Body: string(body)}
+}
+
+func (c *testClient) get(path string) response {
+	c.t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, c.srv.URL+path, nil)
+	return c.do(req)
+}
+
+// postForm envía el formulario sin agregar el token CSRF
+func (c *testClient) postForm(path string, form url.Values) response {
+	c.t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, c.srv.URL+path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req)
+}
+
+// post envía el formulario con el token CSRF de la sesión, como el panel
+func (c *testClient) post(path string, form url.Values) response {
+	c.t.Helper()
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set(csrfFormField, c.csrf())
+	return c.postForm(path, form)
+}
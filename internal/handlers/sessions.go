@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"mi-bot-unne/internal/chatproto"
+	"mi-bot-unne/internal/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+// msgRestarting es lo último que recibe cada chat abierto cuando el servidor se apaga
+var msgRestarting = chatproto.Error("restarting", "🔄 El servidor se está reiniciando. Recargá la página en unos segundos para seguir consultando.")
+
+// ChatSessions lleva la cuenta de los chats abiertos para poder cerrarlos de forma
+// ordenada al apagar el servidor. http.Server.Shutdown no espera a las conexiones
+// WebSocket, porque dejan de ser requests HTTP al hacer el upgrade.
+type ChatSessions struct {
+	mu       sync.Mutex
+	sessions map[*ChatSession]struct{}
+	// perIP cuenta los chats abiertos desde cada IP
+	perIP   map[string]int
+	closing bool
+	// empty se cierra cuando, apagando, ya no queda ninguna sesión
+	empty chan struct{}
+}
+
+func NewChatSessions() *ChatSessions {
+	return &ChatSessions{
+		sessions: make(map[*ChatSession]struct{}),
+		perIP:    make(map[string]int),
+		empty:    make(chan struct{}),
+	}
+}
+
+// add registra la sesión; devuelve false si el servidor ya se está apagando
+func (r *ChatSessions) add(s *ChatSession) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closing {
+		return false
+	}
+	r.sessions[s] = struct{}{}
+	metrics.ChatSessionsActive.Inc()
+	return true
+}
+
+func (r *ChatSessions) remove(s *ChatSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[s]; ok {
+		delete(r.sessions, s)
+		metrics.ChatSessionsActive.Dec()
+	}
+	if r.closing && len(r.sessions) == 0 {
+		select {
+		case <-r.empty:
+		default:
+			close(r.empty)
+		}
+	}
+}
+
+// reserveIP ocupa un lugar de ip si tiene menos de max conexiones abiertas
+func (r *ChatSessions) reserveIP(ip string, max int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.perIP[ip] >= max {
+		return false
+	}
+	r.perIP[ip]++
+	return true
+}
+
+func (r *ChatSessions) releaseIP(ip string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.perIP[ip]--; r.perIP[ip] <= 0 {
+		delete(r.perIP, ip)
+	}
+}
+
+// Len es la cantidad de chats abiertos
+func (r *ChatSessions) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions)
+}
+
+// Closing dice si el servidor ya empezó a apagarse
+func (r *ChatSessions) Closing() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closing
+}
+
+// Shutdown avisa a cada chat que el servidor se reinicia, le envía el cierre de
+// WebSocket y espera a que terminen de procesar su último mensaje. Si ctx vence
+// antes, corta las conexiones que quedan y devuelve el error de ctx.
+func (r *ChatSessions) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closing = true
+	open := make([]*ChatSession, 0, len(r.sessions))
+	for s := range r.sessions {
+		open = append(open, s)
+	}
+	if len(open) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+
+	for _, s := range open {
+		s.closeWith(websocket.CloseGoingAway, "servidor reiniciando", msgRestarting)
+	}
+
+	select {
+	case <-r.empty:
+		return nil
+	case <-ctx.Done():
+		r.mu.Lock()
+		for s := range r.sessions {
+			s.Conn.Close()
+		}
+		r.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"mi-bot-unne/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiTokenKey = "api_token"
+
+// CreateToken genera un token de la API para el usuario logueado con los alcances
+// elegidos que su rol permite. El token se muestra una sola vez.
+func (h *AuthHandler) CreateToken(c *gin.Context) {
+	user := currentUser(c)
+	if user.MustChangePassword {
+		h.renderAccount(c, http.StatusForbidden, gin.H{"error": "Cambiá la contraseña antes de crear tokens"})
+		return
+	}
+
+	nombre := strings.TrimSpace(c.PostForm("nombre"))
+	if nombre == "" || utf8.RuneCountInString(nombre) > 100 {
+		h.renderAccount(c, http.StatusBadRequest, gin.H{"error": "El token necesita un nombre de hasta 100 caracteres"})
+		return
+	}
+	scopes := c.PostFormArray("scopes")
+	if len(scopes) == 0 {
+		h.renderAccount(c, http.StatusBadRequest, gin.H{"error": "Elegí al menos un permiso"})
+		return
+	}
+	for _, s := range scopes {
+		if !models.ValidScope(s) || !user.CanUseScope(s) {
+			h.renderAccount(c, http.StatusForbidden, gin.H{"error": "Tu rol no permite el permiso " + s})
+			return
+		}
+	}
+
+	token, _, err := h.Tokens.Create(user.ID, nombre, scopes)
+	if err != nil {
+		logger(c).Error("creando el token", "error", err)
+		h.renderAccount(c, http.StatusInternalServerError, gin.H{"error": "No se pudo crear el token"})
+		return
+	}
+	logger(c).Info("token de API creado", "nombre", nombre, "scopes", scopes)
+	h.renderAccount(c, http.StatusOK, gin.H{"success": "Token creado. Copialo ahora: no se vuelve a mostrar.", "new_token": token})
+}
+
+// RevokeToken borra un token del usuario logueado; deja de valer en la próxima request
+func (h *AuthHandler) RevokeToken(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	err := h.Tokens.Revoke(id, currentUser(c).ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.String(http.StatusNotFound, "Token no encontrado")
+		return
+	}
+	if err != nil {
+		logger(c).Error("revocando el token", "token_id", id, "error", err)
+		c.String(http.StatusInternalServerError, "Error revocando el token")
+		return
+	}
+	logger(c).Info("token de API revocado", "token_id", id)
+	c.Redirect(http.StatusSeeOther, "/admin/cuenta")
+}
+
+// TokenAuth exige un token de la API en Authorization: Bearer y deja en el contexto
+// el token y su usuario, como AuthMiddleware con la sesión
+func (h *AuthHandler) TokenAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || secret == "" {
+			unauthorized(c, "falta el token: Authorization: Bearer <token>")
+			return
+		}
+		token, err := h.Tokens.Authenticate(secret)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger(c).Error("leyendo el token", "error", err)
+			}
+			unauthorized(c, "token inválido o revocado")
+			return
+		}
+		user, err := h.Users.GetByID(token.UserID)
+		if err != nil {
+			unauthorized(c, "token inválido o revocado")
+			return
+		}
+		// Con la contraseña reseteada la cuenta queda frenada también por la API
+		if user.MustChangePassword {
+			apiError(c, http.StatusForbidden, "el usuario tiene que cambiar la contraseña en el panel")
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Set(apiTokenKey, token)
+		c.Set(loggerKey, logger(c).With("user", user.Email, "token_id", token.ID))
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context, msg string) {
+	c.Header("WWW-Authenticate", `Bearer realm="mibot"`)
+	apiError(c, http.StatusUnauthorized, msg)
+	c.Abort()
+}
+
+// RequireScope corta la request si el token no tiene el alcance o el rol actual del
+// usuario ya no lo permite
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !currentToken(c).HasScope(scope) || !currentUser(c).CanUseScope(scope) {
+			apiError(c, http.StatusForbidden, "el token no tiene el permiso "+scope)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func currentToken(c *gin.Context) models.APIToken {
+	if t, ok := c.Get(apiTokenKey); ok {
+		return t.(models.APIToken)
+	}
+	return models.APIToken{}
+}
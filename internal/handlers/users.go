@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler administra las cuentas del panel (solo superadmin)
+type UserHandler struct {
+	Users      *repository.UserRepository
+	Sessions   *repository.SessionRepository
+	Tokens     *repository.TokenRepository
+	ParamsRepo *repository.ParamsRepository
+}
+
+func NewUserHandler(users *repository.UserRepository, sessions *repository.SessionRepository, tokens *repository.TokenRepository, paramsRepo *repository.ParamsRepository) *UserHandler {
+	return &UserHandler{Users: users, Sessions: sessions, Tokens: tokens, ParamsRepo: paramsRepo}
+}
+
+func (h *UserHandler) ShowUsers(c *gin.Context) {
+	h.renderUsers(c, http.StatusOK, gin.H{})
+}
+
+func (h *UserHandler) renderUsers(c *gin.Context, status int, data gin.H) {
+	users, err := h.Users.GetAll()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error leyendo usuarios")
+		return
+	}
+	carreras, _ := h.ParamsRepo.GetAllCarreras()
+	// Una carrera archivada sigue asignada a sus usuarios hasta que se los reasigne
+	for _, u := range users {
+		if u.CarreraID != 0 && !containsID(carreras, u.CarreraID, func(ca models.Carrera) int { return ca.ID }) {
+			carreras = append(carreras, models.Carrera{ID: u.CarreraID, Nombre: u.Carrera + " (archivada)"})
+		}
+	}
+
+	data["users"] = users
+	data["carreras"] = carreras
+	data["roles"] = models.Roles
+	renderHTML(c, status, "admin_users.html", data)
+}
+
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	u, ok := h.bindUser(c)
+	if !ok {
+		return
+	}
+	u.Email = strings.TrimSpace(c.PostForm("email"))
+	if u.Email == "" {
+		h.renderUsers(c, http.StatusBadRequest, gin.H{"error": "El email es obligatorio"})
+		return
+	}
+
+	// El alta usa una contraseña temporal que el usuario debe cambiar al ingresar
+	password := temporaryPassword()
+	u.MustChangePassword = true
+	if err := h.Users.Create(u, password); err != nil {
+		logger(c).Error("creando el usuario", "email", u.Email, "error", err)
+		h.renderUsers(c, http.StatusBadRequest, gin.H{"error": "No se pudo crear el usuario (¿email repetido?)"})
+		return
+	}
+	h.renderUsers(c, http.StatusOK, gin.H{"temp_email": u.Email, "temp_password": password})
+}
+
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	existing, err := h.Users.GetByID(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Usuario no encontrado")
+		return
+	}
+	u, ok := h.bindUser(c)
+	if !ok {
+		return
+	}
+	u.ID = id
+
+	if existing.IsSuperadmin() && !u.IsSuperadmin() {
+		if err := h.ensureAnotherSuperadmin(); err != nil {
+			h.renderUsers(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.Users.Update(u); err != nil {
+		c.String(http.StatusInternalServerError, "Error actualizando usuario")
+		return
+	}
+	// Con otro rol tiene que volver a ingresar
+	if (u.Role != existing.Role || u.CarreraID != existing.CarreraID) && u.ID != currentUser(c).ID {
+		h.Sessions.DeleteForUser(u.ID)
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/usuarios")
+}
+
+// ResetPassword genera una contraseña temporal y obliga a cambiarla en el próximo ingreso
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	u, err := h.Users.GetByID(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Usuario no encontrado")
+		return
+	}
+
+	password := temporaryPassword()
+	if err := h.Users.SetPassword(id, password, true); err != nil {
+		c.String(http.StatusInternalServerError, "Error reseteando contraseña")
+		return
+	}
+	h.Sessions.DeleteForUser(id)
+	h.renderUsers(c, http.StatusOK, gin.H{"temp_email": u.Email, "temp_password": password})
+}
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if id == currentUser(c).ID {
+		h.renderUsers(c, http.StatusBadRequest, gin.H{"error": "No podés eliminar tu propio usuario"})
+		return
+	}
+	u, err := h.Users.GetByID(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Usuario no encontrado")
+		return
+	}
+	if u.IsSuperadmin() {
+		if err := h.ensureAnotherSuperadmin(); err != nil {
+			h.renderUsers(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.Users.Delete(id); err != nil {
+		c.String(http.StatusInternalServerError, "Error eliminando usuario")
+		return
+	}
+	h.Sessions.DeleteForUser(id)
+	h.Tokens.DeleteForUser(id)
+	c.Redirect(http.StatusSeeOther, "/admin/usuarios")
+}
+
+// bindUser lee rol y carrera del formulario; la secretaría necesita una carrera asignada
+func (h *UserHandler) bindUser(c *gin.Context) (models.User, bool) {
+	var u models.User
+	u.Role = c.PostForm("role")
+	if !models.ValidRole(u.Role) {
+		h.renderUsers(c, http.StatusBadRequest, gin.H{"error": "Rol inválido"})
+		return u, false
+	}
+	if u.Role == models.RoleSecretaria {
+		u.CarreraID, _ = strconv.Atoi(c.PostForm("carrera_id"))
+		if _, err := h.ParamsRepo.GetCarrera(u.CarreraID); err != nil {
+			h.renderUsers(c, http.StatusBadRequest, gin.H{"error": "La secretaría debe tener una carrera asignada"})
+			return u, false
+		}
+	}
+	return u, true
+}
+
+func (h *UserHandler) ensureAnotherSuperadmin() error {
+	count, err := h.Users.CountSuperadmins()
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return errors.New("Debe quedar al menos un superadmin")
+	}
+	return nil
+}
+
+const passwordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// temporaryPassword evita caracteres ambiguos (0/O, 1/l) porque se dicta o copia a mano
+func temporaryPassword() string {
+	b := make([]byte, 12)
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+		b[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(b)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldErrors son los errores de validación por campo. El panel los muestra junto a cada
+// campo del formulario y la API de administración los responde con 422.
+type fieldErrors map[string]string
+
+// add guarda el primer error de cada campo
+func (e fieldErrors) add(field, msg string) {
+	if _, ok := e[field]; !ok {
+		e[field] = msg
+	}
+}
+
+// --- Mesas ---
+
+// mesaInput son los datos de alta y edición de una mesa. Fecha y hora vacías quedan a
+// confirmar; turno y aula en 0, sin asignar.
+type mesaInput struct {
+	MateriaID int    `json:"materia_id"`
+	CarreraID int    `json:"carrera_id"`
+	TurnoID   int    `json:"turno_id"`
+	AulaID    int    `json:"aula_id"`
+	Fecha     string `json:"fecha"`
+	Hora      string `json:"hora"`
+}
+
+// mesaFromForm lee el formulario de mesas del panel. No usa ShouldBind para poder
+// marcar cada campo que no se pudo leer.
+func mesaFromForm(c *gin.Context, errs fieldErrors) models.Mesa {
+	in := mesaInput{Fecha: c.PostForm("fecha"), Hora: c.PostForm("hora")}
+	for field, dst := range map[string]*int{
+		"materia_id": &in.MateriaID, "carrera_id": &in.CarreraID, "turno_id": &in.TurnoID, "aula_id": &in.AulaID,
+	} {
+		if v := c.PostForm(field); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs.add(field, "Valor inválido")
+			}
+			*dst = n
+		}
+	}
+	return in.mesa("", errs)
+}
+
+// mesa revisa los campos obligatorios y el formato de fecha y hora; prefix antecede el
+// nombre de cada campo en los errores (los lotes de la API usan "[i].")
+func (in mesaInput) mesa(prefix string, errs fieldErrors) models.Mesa {
+	m := models.Mesa{MateriaID: in.MateriaID, CarreraID: in.CarreraID, TurnoID: in.TurnoID, AulaID: in.AulaID}
+	if in.MateriaID < 1 {
+		errs.add(prefix+"materia_id", "Elegí una materia")
+	}
+	if in.CarreraID < 1 {
+		errs.add(prefix+"carrera_id", "Elegí una carrera")
+	}
+	var err error
+	if m.Fecha, err = models.ParseDate(in.Fecha); err != nil {
+		errs.add(prefix+"fecha", "Fecha inválida (AAAA-MM-DD)")
+	}
+	if m.Hora, err = models.ParseTimeOfDay(in.Hora); err != nil {
+		errs.add(prefix+"hora", "Hora inválida (HH:MM)")
+	}
+	return m
+}
+
+// mesaChecker revisa contra la base que existan la materia, la carrera, el turno y el aula
+// de una mesa, y que la fecha caiga dentro del turno. Recuerda lo que ya leyó para no
+// repetir consultas en los lotes.
+type mesaChecker struct {
+	params *repository.ParamsRepository
+	found  map[string]bool
+	turnos map[int]models.TurnoConfig
+}
+
+func newMesaChecker(params *repository.ParamsRepository) *mesaChecker {
+	return &mesaChecker{params: params, found: map[string]bool{}, turnos: map[int]models.TurnoConfig{}}
+}
+
+// check agrega a errs los problemas de la mesa; solo devuelve error si falla la base
+func (v *mesaChecker) check(m models.Mesa, prefix string, errs fieldErrors) error {
+	refs := []struct {
+		field, msg string
+		id         int
+		get        func(id int) error
+	}{
+		{"materia_id", "La materia no existe", m.MateriaID, func(id int) error { _, err := v.params.GetMateria(id); return err }},
+		{"carrera_id", "La carrera no existe", m.CarreraID, func(id int) error { _, err := v.params.GetCarrera(id); return err }},
+		{"aula_id", "El aula no existe", m.AulaID, func(id int) error { _, err := v.params.GetAula(id); return err }},
+	}
+	for _, ref := range refs {
+		if ref.id < 1 {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", ref.field, ref.id)
+		ok, seen := v.found[key]
+		if !seen {
+			err := ref.get(ref.id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			ok = err == nil
+			v.found[key] = ok
+		}
+		if !ok {
+			errs.add(prefix+ref.field, ref.msg)
+		}
+	}
+
+	if m.TurnoID < 1 {
+		return nil
+	}
+	turno, ok := v.turnos[m.TurnoID]
+	if !ok {
+		var err error
+		turno, err = v.params.GetTurnoConfig(m.TurnoID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		v.turnos[m.TurnoID] = turno // con ID 0 si no existe
+	}
+	if turno.ID == 0 {
+		errs.add(prefix+"turno_id", "El turno no existe")
+		return nil
+	}
+	if m.Fecha.IsZero() {
+		return nil
+	}
+	if (!turno.FechaInicio.IsZero() && m.Fecha.Before(turno.FechaInicio)) || (!turno.FechaFin.IsZero() && m.Fecha.After(turno.FechaFin)) {
+		errs.add(prefix+"fecha", fmt.Sprintf("Fuera del %s (%s)", turno.Nombre, turnoRango(turno)))
+	}
+	return nil
+}
+
+// turnoRango describe las fechas del turno, ej. "del 17/02/2025 al 21/02/2025"
+func turnoRango(t models.TurnoConfig) string {
+	switch {
+	case t.FechaInicio.IsZero():
+		return "hasta el " + t.FechaFin.Format("02/01/2006")
+	case t.FechaFin.IsZero():
+		return "desde el " + t.FechaInicio.Format("02/01/2006")
+	}
+	return "del " + t.FechaInicio.Format("02/01/2006") + " al " + t.FechaFin.Format("02/01/2006")
+}
+
+// --- Turnos ---
+
+// turnoInput son los datos de alta y edición de un turno de examen
+type turnoInput struct {
+	Nombre      string `json:"nombre"`
+	FechaInicio string `json:"fecha_inicio"`
+	FechaFin    string `json:"fecha_fin"`
+	Receso      bool   `json:"receso"`
+}
+
+// turnoFromForm lee el formulario de turnos. El checkbox de receso llega como "on" o no
+// llega, por eso no se usa ShouldBind.
+func turnoFromForm(c *gin.Context) (models.TurnoConfig, fieldErrors) {
+	return turnoInput{
+		Nombre:      c.PostForm("nombre"),
+		FechaInicio: c.PostForm("fecha_inicio"),
+		FechaFin:    c.PostForm("fecha_fin"),
+		Receso:      c.PostForm("receso") == "on",
+	}.turno()
+}
+
+// turno revisa el nombre, el formato de las fechas y que el turno no termine antes de empezar
+func (in turnoInput) turno() (models.TurnoConfig, fieldErrors) {
+	errs := fieldErrors{}
+	t := models.TurnoConfig{Nombre: strings.TrimSpace(in.Nombre), Receso: in.Receso}
+	if t.Nombre == "" {
+		errs.add("nombre", "El nombre es obligatorio")
+	}
+	var err error
+	if t.FechaInicio, err = models.ParseDate(in.FechaInicio); err != nil {
+		errs.add("fecha_inicio", "Fecha inválida (AAAA-MM-DD)")
+	}
+	if t.FechaFin, err = models.ParseDate(in.FechaFin); err != nil {
+		errs.add("fecha_fin", "Fecha inválida (AAAA-MM-DD)")
+	}
+	if !t.FechaFin.IsZero() && t.FechaFin.Before(t.FechaInicio) {
+		errs.add("fecha_fin", "Es anterior a la fecha de inicio")
+	}
+	return t, errs
+}
+
+// turnoMesasFuera explica, en la fecha que corresponda, cuántas mesas del turno dejaría
+// afuera el nuevo rango. Se usa cuando UpdateTurnoConfig devuelve ErrMesasFueraDelTurno.
+func turnoMesasFuera(params *repository.ParamsRepository, t models.TurnoConfig) (fieldErrors, error) {
+	antes, despues, err := params.MesasFueraDelTurno(t)
+	if err != nil {
+		return nil, err
+	}
+	errs := fieldErrors{}
+	if antes > 0 {
+		errs.add("fecha_inicio", fmt.Sprintf("Hay %d mesa(s) del turno antes de esta fecha; cambiales la fecha primero", antes))
+	}
+	if despues > 0 {
+		errs.add("fecha_fin", fmt.Sprintf("Hay %d mesa(s) del turno después de esta fecha; cambiales la fecha primero", despues))
+	}
+	return errs, nil
+}
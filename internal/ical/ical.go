@@ -0,0 +1,99 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"mi-bot-unne/internal/models"
+)
+
+// Duración estimada de una mesa; el calendario no guarda hora de fin
+const mesaDuration = 2 * time.Hour
+
+// Calendar es un feed iCalendar (RFC 5545) de mesas de examen
+type Calendar struct {
+	Name   string // X-WR-CALNAME, lo que muestra Google Calendar/Outlook
+	Domain string // Sufijo de los UID, para que sean únicos globalmente
+	Mesas  []models.Mesa
+}
+
+// Write serializa el calendario. Las mesas sin fecha u hora se omiten en lugar
+// de publicarse con un horario inventado.
+func (cal Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	now := time.Now().UTC()
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:-//UNNE//Bot de Mesas de Examen//ES")
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	writeLine(bw, "X-WR-CALNAME:"+escapeText(cal.Name))
+	writeLine(bw, "X-WR-TIMEZONE:America/Argentina/Buenos_Aires")
+
+	for _, m := range cal.Mesas {
+		if m.Fecha.IsZero() || m.Hora.IsZero() {
+			continue
+		}
+		start := m.Hora.On(m.Fecha)
+		stamp := now
+		if edited, err := time.ParseInLocation("2006-01-02 15:04:05", m.FechaEdicion, models.Argentina); err == nil {
+			stamp = edited.UTC()
+		}
+
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:mesa-"+strconv.Itoa(m.ID)+"@"+cal.Domain)
+		writeLine(bw, "DTSTAMP:"+formatUTC(stamp))
+		writeLine(bw, "DTSTART:"+formatUTC(start))
+		writeLine(bw, "DTEND:"+formatUTC(start.Add(mesaDuration)))
+		writeLine(bw, "SUMMARY:"+escapeText("Final: "+m.Materia))
+		writeLine(bw, "LOCATION:"+escapeText(location(m)))
+		writeLine(bw, "DESCRIPTION:"+escapeText(fmt.Sprintf("%s\n%s\nCarrera: %s", m.Materia, m.Turno, m.Carrera)))
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+func location(m models.Mesa) string {
+	if m.Sede == "" {
+		return m.Aula
+	}
+	return m.Aula + " - " + m.Sede
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// writeLine termina en CRLF y pliega las líneas de más de 75 octetos sin cortar runas UTF-8
+func writeLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // el espacio inicial de la continuación cuenta
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,123 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"mi-bot-unne/internal/models"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Álgebra I", "Álgebra I"},
+		{"Aula 1, PB", `Aula 1\, PB`},
+		{"Teoría; Práctica", `Teoría\; Práctica`},
+		{`C:\aulas`, `C:\\aulas`},
+		{"línea 1\nlínea 2", `línea 1\nlínea 2`},
+		{"línea 1\r\nlínea 2", `línea 1\nlínea 2`},
+		{`a\,b`, `a\\\,b`}, // la barra se escapa antes que la coma
+	}
+	for _, tt := range tests {
+		if got := escapeText(tt.in); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"corta", "SUMMARY:Final: Álgebra I"},
+		{"justo 75 octetos", "DESCRIPTION:" + strings.Repeat("x", 63)},
+		{"larga ASCII", "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{"larga con acentos", "DESCRIPTION:" + strings.Repeat("Física ", 40)},
+		{"solo multibyte", "SUMMARY:" + strings.Repeat("ñ", 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeLine(w, tt.line)
+			w.Flush()
+
+			out, ok := strings.CutSuffix(buf.String(), "\r\n")
+			if !ok {
+				t.Fatalf("la línea no termina en CRLF: %q", buf.String())
+			}
+			physical := strings.Split(out, "\r\n")
+			for i, l := range physical {
+				if len(l) > 75 {
+					t.Errorf("línea %d: %d octetos, máximo 75", i, len(l))
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("línea %d: la continuación no empieza con espacio: %q", i, l)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("línea %d: corta una runa UTF-8: %q", i, l)
+				}
+			}
+			if len(tt.line) <= 75 && len(physical) != 1 {
+				t.Errorf("se plegó una línea de %d octetos", len(tt.line))
+			}
+			// Desplegar (RFC 5545 §3.1) devuelve la línea original
+			if got := strings.ReplaceAll(out, "\r\n ", ""); got != tt.line {
+				t.Errorf("desplegada = %q, want %q", got, tt.line)
+			}
+		})
+	}
+}
+
+func TestWriteConvertsToUTC(t *testing.T) {
+	mesa := func(id int, fecha string, hora string) models.Mesa {
+		m := models.Mesa{ID: id, Materia: "Álgebra I", Turno: "3° Turno", Carrera: "Ingeniería en Sistemas", Aula: "Aula Magna", Sede: "Campus Resistencia"}
+		m.Fecha, _ = models.ParseDate(fecha)
+		m.Hora, _ = models.ParseTimeOfDay(hora)
+		return m
+	}
+	edited := mesa(1, "2025-03-27", "09:30")
+	edited.FechaEdicion = "2025-03-20 10:00:00"
+	cal := Calendar{
+		Name:   "Mesas UNNE - Álgebra I",
+		Domain: "mesas.example.org",
+		Mesas: []models.Mesa{
+			edited,
+			mesa(2, "2025-03-31", "22:00"), // en UTC ya es el día siguiente
+			mesa(3, "2025-03-28", ""),      // sin hora: no se publica
+			mesa(4, "", "10:00"),           // sin fecha: no se publica
+		},
+	}
+	var buf bytes.Buffer
+	if err := cal.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := strings.ReplaceAll(buf.String(), "\r\n ", "")
+
+	for _, want := range []string{
+		"UID:mesa-1@mesas.example.org",
+		"DTSTAMP:20250320T130000Z",
+		"DTSTART:20250327T123000Z",
+		"DTEND:20250327T143000Z",
+		"UID:mesa-2@mesas.example.org",
+		"DTSTART:20250401T010000Z",
+		"DTEND:20250401T030000Z",
+		`LOCATION:Aula Magna - Campus Resistencia`,
+		`DESCRIPTION:Álgebra I\n3° Turno\nCarrera: Ingeniería en Sistemas`,
+	} {
+		if !strings.Contains(out, want+"\r\n") {
+			t.Errorf("falta %q en:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("eventos = %d, want 2", n)
+	}
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("el calendario no está bien delimitado:\n%s", out)
+	}
+}
@@ -0,0 +1,61 @@
+// Package logging arma el logger JSON del servidor y su política de redacción:
+// los valores de claves sensibles nunca se escriben, y lo que escriben los usuarios
+// (formularios, mensajes del chat) solo se registra en nivel debug.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Redacted reemplaza el valor de las claves sensibles
+const Redacted = "[REDACTADO]"
+
+// sensitiveKeys son claves cuyo valor no se registra en ningún nivel. Se comparan en
+// minúsculas y también como sufijo ("password_actual", "session_token").
+var sensitiveKeys = []string{"password", "token", "secret", "cookie", "authorization"}
+
+// IsSensitive dice si el valor de key se redacta
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if key == s || strings.HasPrefix(key, s+"_") || strings.HasSuffix(key, "_"+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLevel traduce debug, info, warn o error; cualquier otro valor es info
+func ParseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// New devuelve un logger JSON sobre w que descarta lo que está debajo de level y
+// redacta las claves sensibles
+func New(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: ParseLevel(level),
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if IsSensitive(a.Key) {
+				return slog.String(a.Key, Redacted)
+			}
+			return a
+		},
+	}))
+}
+
+// Form devuelve los campos de un formulario como atributos, con los sensibles redactados.
+// Solo se usa en logs de nivel debug.
+func Form(values map[string][]string) slog.Attr {
+	attrs := make([]any, 0, len(values))
+	for k, v := range values {
+		attrs = append(attrs, slog.String(k, strings.Join(v, ",")))
+	}
+	return slog.Group("form", attrs...)
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "debug")
+	log.Info("login", "email", "a@unne.edu.ar", "password", "secreto123", "session_token", "abc")
+	log.Debug("formulario", Form(map[string][]string{
+		"hora":            {"09:00"},
+		"csrf_token":      {"def"},
+		"password_actual": {"viejo"},
+	}))
+
+	out := buf.String()
+	for _, leaked := range []string{"secreto123", `"abc"`, `"def"`, "viejo"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("el log tiene %s:\n%s", leaked, out)
+		}
+	}
+	for _, want := range []string{`"email":"a@unne.edu.ar"`, `"hora":"09:00"`, `"csrf_token":"` + Redacted + `"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("el log no tiene %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestIsSensitive(t *testing.T) {
+	tests := map[string]bool{
+		"password": true, "Password": true, "password_actual": true, "csrf_token": true,
+		"session_secret": true, "Cookie": true, "authorization": true,
+		"session_id": false, "request_id": false, "email": false, "tokenizer": false,
+	}
+	for key, want := range tests {
+		if got := IsSensitive(key); got != want {
+			t.Errorf("IsSensitive(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, "info")
+	log.Debug("mensaje del chat", "input", "algebra")
+	log.Info("chat abierto")
+	if strings.Contains(buf.String(), "algebra") || !strings.Contains(buf.String(), "chat abierto") {
+		t.Errorf("con info se esperaba solo la línea de info:\n%s", buf.String())
+	}
+	if ParseLevel("WARN").String() != "WARN" || ParseLevel("cualquiera").String() != "INFO" {
+		t.Error("ParseLevel no traduce los niveles")
+	}
+}
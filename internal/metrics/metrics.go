@@ -0,0 +1,69 @@
+// Package metrics define las métricas de Prometheus del servidor. Viven en un
+// registro propio que se publica en /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Resultados de una búsqueda de materia en el chat
+const (
+	SearchHit       = "hit"       // una sola materia, se muestran sus mesas
+	SearchMiss      = "miss"      // ninguna materia con ese nombre
+	SearchAmbiguous = "ambiguous" // varias materias, se pide elegir
+	SearchError     = "error"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	ChatSessionsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mibot_chat_sessions_active",
+		Help: "Conexiones WebSocket del chat abiertas.",
+	})
+
+	ChatMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mibot_chat_messages_total",
+		Help: "Mensajes del chat procesados, por estado de la conversación al recibirlos.",
+	}, []string{"state"})
+
+	ChatSearches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mibot_chat_searches_total",
+		Help: "Búsquedas de materia en el chat, por resultado (hit, miss, ambiguous, error).",
+	}, []string{"result"})
+
+	// QueryDuration cubre todos los métodos exportados de los repositorios, con la
+	// etiqueta "repo.Método" (ej. "params.GetAula"); el alcance exacto está en observe
+	QueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "mibot_repository_query_duration_seconds",
+		Help: "Duración de las consultas de los repositorios, por método.",
+		// SQLite local responde en micro o milisegundos
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"query"})
+
+	AdminMutations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mibot_admin_mutations_total",
+		Help: "Altas, ediciones y bajas confirmadas, por entidad y acción (las mismas de la auditoría).",
+	}, []string{"entity", "action"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ChatSessionsActive,
+		ChatMessages,
+		ChatSearches,
+		QueryDuration,
+		AdminMutations,
+	)
+}
+
+// Handler publica el registro en el formato de texto de Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
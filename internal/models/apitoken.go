@@ -0,0 +1,47 @@
+package models
+
+import "slices"
+
+// Alcances de los tokens de la API de administración. Un token solo puede lo que
+// permiten a la vez sus alcances y el rol actual de su usuario.
+const (
+	ScopeMesasRead   = "mesas:read"
+	ScopeMesasWrite  = "mesas:write"  // Superadmin, o secretaría en su carrera
+	ScopeParamsRead  = "params:read"  // Materias, carreras, sedes, aulas y turnos
+	ScopeParamsWrite = "params:write" // Solo superadmin
+)
+
+// Scopes en el orden en que se muestran en el panel
+var Scopes = []string{ScopeMesasRead, ScopeMesasWrite, ScopeParamsRead, ScopeParamsWrite}
+
+// APIToken es un token de la API de un usuario. El token en claro se muestra una sola
+// vez al crearlo; en la base se guarda su hash.
+type APIToken struct {
+	ID         int      `json:"id"`
+	UserID     int      `json:"user_id"`
+	Nombre     string   `json:"nombre"` // Para qué se usa, ej. "Importación Guaraní"
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt string   `json:"last_used_at"` // Vacío si nunca se usó
+}
+
+func (t APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// CanUseScope indica si el rol del usuario permite el alcance
+func (u User) CanUseScope(scope string) bool {
+	switch scope {
+	case ScopeMesasRead, ScopeParamsRead:
+		return true
+	case ScopeMesasWrite:
+		return u.CanEditMesas()
+	case ScopeParamsWrite:
+		return u.IsSuperadmin()
+	}
+	return false
+}
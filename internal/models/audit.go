@@ -0,0 +1,20 @@
+package models
+
+// Acciones registradas en audit_log
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditEntry es un cambio hecho desde el panel, con el valor anterior y el nuevo en JSON
+type AuditEntry struct {
+	ID        int    `json:"id"`
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	Entity    string `json:"entity"` // mesa, materia, carrera, sede, aula, turno
+	EntityID  int    `json:"entity_id"`
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	CreatedAt string `json:"created_at"`
+}
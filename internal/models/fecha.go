@@ -0,0 +1,184 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Formatos con los que se guardan fechas y horas en la base y en los <input> del panel
+const (
+	DateLayout      = "2006-01-02"
+	TimeOfDayLayout = "15:04"
+)
+
+// Argentina no tiene horario de verano, así que un offset fijo alcanza
+// (y evita depender de tzdata en la imagen alpine).
+var Argentina = time.FixedZone("ART", -3*60*60)
+
+// Date es un día del calendario, sin hora. Se guarda como texto ISO (YYYY-MM-DD);
+// el valor cero significa "sin fecha" y se guarda como NULL.
+type Date struct {
+	t time.Time
+}
+
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{t: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// Today es la fecha actual en Argentina
+func Today() Date {
+	y, m, d := time.Now().In(Argentina).Date()
+	return NewDate(y, m, d)
+}
+
+// ParseDate interpreta una fecha YYYY-MM-DD; la cadena vacía es la fecha cero
+func ParseDate(s string) (Date, error) {
+	if s == "" {
+		return Date{}, nil
+	}
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("fecha %q inválida (se esperaba AAAA-MM-DD)", s)
+	}
+	return Date{t: t}, nil
+}
+
+func (d Date) IsZero() bool { return d.t.IsZero() }
+
+func (d Date) Before(o Date) bool { return d.t.Before(o.t) }
+
+func (d Date) After(o Date) bool { return d.t.After(o.t) }
+
+// Time es la medianoche de esa fecha en Argentina
+func (d Date) Time() time.Time {
+	y, m, day := d.t.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, Argentina)
+}
+
+// Format aplica un layout de time.Format; la fecha cero da ""
+func (d Date) Format(layout string) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.t.Format(layout)
+}
+
+// String es el formato ISO, el mismo que espera <input type="date">
+func (d Date) String() string { return d.Format(DateLayout) }
+
+func (d Date) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Date) UnmarshalText(b []byte) error {
+	parsed, err := ParseDate(string(b))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalParam permite usar Date en structs que se completan con c.ShouldBind
+func (d *Date) UnmarshalParam(s string) error { return d.UnmarshalText([]byte(s)) }
+
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.String(), nil
+}
+
+func (d *Date) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Date{}
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	case time.Time:
+		*d = NewDate(v.Date())
+		return nil
+	}
+	return fmt.Errorf("no se puede leer %T como fecha", src)
+}
+
+// TimeOfDay es una hora del día con precisión de minutos (HH:MM). El valor cero
+// significa "sin hora"; las 00:00 se distinguen porque valid queda en true.
+type TimeOfDay struct {
+	minutes int
+	valid   bool
+}
+
+func NewTimeOfDay(hour, minute int) TimeOfDay {
+	return TimeOfDay{minutes: hour*60 + minute, valid: true}
+}
+
+// ParseTimeOfDay interpreta una hora HH:MM; la cadena vacía es la hora cero
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	if s == "" {
+		return TimeOfDay{}, nil
+	}
+	t, err := time.Parse(TimeOfDayLayout, s)
+	if err != nil {
+		// <input type="time"> manda segundos si el navegador los muestra
+		if t, err = time.Parse("15:04:05", s); err != nil {
+			return TimeOfDay{}, fmt.Errorf("hora %q inválida (se esperaba HH:MM)", s)
+		}
+	}
+	return NewTimeOfDay(t.Hour(), t.Minute()), nil
+}
+
+func (t TimeOfDay) IsZero() bool { return !t.valid }
+
+func (t TimeOfDay) Hour() int { return t.minutes / 60 }
+
+func (t TimeOfDay) Minute() int { return t.minutes % 60 }
+
+// On combina la hora con una fecha, en horario de Argentina
+func (t TimeOfDay) On(d Date) time.Time {
+	return d.Time().Add(time.Duration(t.minutes) * time.Minute)
+}
+
+// String es el formato HH:MM, el mismo que espera <input type="time">
+func (t TimeOfDay) String() string {
+	if !t.valid {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d", t.Hour(), t.Minute())
+}
+
+func (t TimeOfDay) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *TimeOfDay) UnmarshalText(b []byte) error {
+	parsed, err := ParseTimeOfDay(string(b))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// UnmarshalParam permite usar TimeOfDay en structs que se completan con c.ShouldBind
+func (t *TimeOfDay) UnmarshalParam(s string) error { return t.UnmarshalText([]byte(s)) }
+
+func (t TimeOfDay) Value() (driver.Value, error) {
+	if !t.valid {
+		return nil, nil
+	}
+	return t.String(), nil
+}
+
+func (t *TimeOfDay) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = TimeOfDay{}
+		return nil
+	case string:
+		return t.UnmarshalText([]byte(v))
+	case []byte:
+		return t.UnmarshalText(v)
+	}
+	return fmt.Errorf("no se puede leer %T como hora", src)
+}
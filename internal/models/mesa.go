@@ -1,14 +1,34 @@
 package models
 
-// Mesa representa una mesa de examen
+// Mesa representa una mesa de examen. Los IDs son las referencias guardadas en la base;
+// los nombres (y la sede, que sale del aula) se completan con joins al leer.
 type Mesa struct {
-	ID           int    `json:"id"`
-	Materia      string `json:"materia" form:"materia"`
-	Turno        string `json:"turno" form:"turno"` // Ej: "1° Turno"
-	Fecha        string `json:"fecha" form:"fecha"` // Ej: "20/02/2025"
-	Hora         string `json:"hora" form:"hora"`
-	Aula         string `json:"aula" form:"aula"`
-	Sede         string `json:"sede"` // Populated via join
-	Carrera      string `json:"carrera" form:"carrera"`
-	FechaEdicion string `json:"fecha_edicion"`
+	ID           int       `json:"id"`
+	MateriaID    int       `json:"materia_id" form:"materia_id"`
+	Materia      string    `json:"materia"`
+	CarreraID    int       `json:"carrera_id" form:"carrera_id"`
+	Carrera      string    `json:"carrera"`
+	TurnoID      int       `json:"turno_id" form:"turno_id"`
+	Turno        string    `json:"turno"`              // Ej: "1° Turno"
+	Fecha        Date      `json:"fecha" form:"fecha"` // Ej: "2025-02-20"
+	Hora         TimeOfDay `json:"hora" form:"hora"`   // Ej: "08:00"
+	AulaID       int       `json:"aula_id" form:"aula_id"`
+	Aula         string    `json:"aula"`
+	SedeID       int       `json:"sede_id"`
+	Sede         string    `json:"sede"`
+	FechaEdicion string    `json:"fecha_edicion"`
+}
+
+// UnmappedMesa es una fila de la tabla de texto libre que la migración a ids no pudo resolver del todo
+type UnmappedMesa struct {
+	ID       int    `json:"id"`
+	LegacyID int    `json:"legacy_id"`
+	MesaID   int    `json:"mesa_id"` // 0 si la fila no se migró
+	Materia  string `json:"materia"`
+	Carrera  string `json:"carrera"`
+	Turno    string `json:"turno"`
+	Aula     string `json:"aula"`
+	Fecha    string `json:"fecha"`
+	Hora     string `json:"hora"`
+	Motivo   string `json:"motivo"`
 }
@@ -1,5 +1,7 @@
 package models
 
+import "strconv"
+
 type Sede struct {
 	ID     int    `json:"id"`
 	Nombre string `json:"nombre"`
@@ -20,3 +22,56 @@ type Materia struct {
 	ID     int    `json:"id"`
 	Nombre string `json:"nombre"`
 }
+
+// PlanMateria es una materia dentro del plan de estudios de una carrera.
+// Anio y Cuatrimestre en 0 significan sin definir y materia anual.
+type PlanMateria struct {
+	ID           int    `json:"id"`
+	CarreraID    int    `json:"carrera_id" form:"carrera_id"`
+	Carrera      string `json:"carrera"`
+	MateriaID    int    `json:"materia_id" form:"materia_id"`
+	Materia      string `json:"materia"`
+	Anio         int    `json:"anio" form:"anio"`
+	Cuatrimestre int    `json:"cuatrimestre" form:"cuatrimestre"`
+	Codigo       string `json:"codigo" form:"codigo"`
+}
+
+// Periodo describe cuándo se cursa la materia, ej. "2° año · 1° cuat."
+func (p PlanMateria) Periodo() string {
+	periodo := "Anual"
+	if p.Cuatrimestre > 0 {
+		periodo = strconv.Itoa(p.Cuatrimestre) + "° cuat."
+	}
+	if p.Anio > 0 {
+		periodo = strconv.Itoa(p.Anio) + "° año · " + periodo
+	}
+	return periodo
+}
+
+// Dependencias cuenta lo que referencia a una sede, aula, carrera o materia.
+// Las mesas y los usuarios impiden borrarla; aulas y plan se borran en cascada.
+type Dependencias struct {
+	Mesas        int `json:"mesas"` // Para una sede, las mesas de sus aulas
+	MesasFuturas int `json:"mesas_futuras"`
+	Aulas        int `json:"aulas"`    // Sólo sedes
+	Plan         int `json:"plan"`     // Filas de plan_materias
+	Usuarios     int `json:"usuarios"` // Secretarías asignadas, sólo carreras
+}
+
+// Bloquean indica que no se puede borrar y sólo queda archivar
+func (d Dependencias) Bloquean() bool {
+	return d.Mesas > 0 || d.Usuarios > 0
+}
+
+// Cascada indica que al borrar se eliminan también aulas o filas del plan
+func (d Dependencias) Cascada() bool {
+	return d.Aulas > 0 || d.Plan > 0
+}
+
+// ParamArchivado es una sede, aula, carrera o materia dada de baja lógica
+type ParamArchivado struct {
+	Tipo        string `json:"tipo"` // sede, aula, carrera, materia
+	ID          int    `json:"id"`
+	Nombre      string `json:"nombre"`
+	ArchivadoAt string `json:"archivado_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Session es una sesión del panel. El token en claro solo viaja en la cookie;
+// en la base se guarda su hash.
+type Session struct {
+	ID        string    `json:"-"`
+	UserID    int       `json:"user_id"`
+	CreatedAt string    `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -2,8 +2,8 @@ package models
 
 type TurnoConfig struct {
 	ID          int    `json:"id"`
-	Nombre      string `json:"nombre" form:"nombre"`             // e.g. "1", "Julio"
-	FechaInicio string `json:"fecha_inicio" form:"fecha_inicio"` // YYYY-MM-DD
-	FechaFin    string `json:"fecha_fin" form:"fecha_fin"`       // YYYY-MM-DD
-	Receso      bool   `json:"receso" form:"receso"`             // True if recess
+	Nombre      string `json:"nombre" form:"nombre"` // e.g. "1", "Julio"
+	FechaInicio Date   `json:"fecha_inicio" form:"fecha_inicio"`
+	FechaFin    Date   `json:"fecha_fin" form:"fecha_fin"`
+	Receso      bool   `json:"receso" form:"receso"` // True if recess
 }
@@ -0,0 +1,52 @@
+package models
+
+// Roles de los usuarios del panel
+const (
+	RoleSuperadmin = "superadmin" // Todo, incluida la gestión de usuarios y parámetros
+	RoleSecretaria = "secretaria" // ABM de mesas de su propia carrera
+	RoleLectura    = "lectura"    // Solo consulta
+)
+
+// Roles en el orden en que se muestran en el panel
+var Roles = []string{RoleSuperadmin, RoleSecretaria, RoleLectura}
+
+type User struct {
+	ID                 int    `json:"id"`
+	Email              string `json:"email"`
+	PasswordHash       string `json:"-"`
+	Role               string `json:"role"`
+	CarreraID          int    `json:"carrera_id"` // Solo para secretaría
+	Carrera            string `json:"carrera"`    // Populated via join
+	MustChangePassword bool   `json:"must_change_password"`
+	CreatedAt          string `json:"created_at"`
+}
+
+func (u User) IsSuperadmin() bool {
+	return u.Role == RoleSuperadmin
+}
+
+// CanEditMesas indica si el usuario puede cargar o modificar mesas de alguna carrera
+func (u User) CanEditMesas() bool {
+	return u.Role == RoleSuperadmin || u.Role == RoleSecretaria
+}
+
+// CanEditCarrera indica si el usuario puede modificar mesas de la carrera indicada
+func (u User) CanEditCarrera(carreraID int) bool {
+	switch u.Role {
+	case RoleSuperadmin:
+		return true
+	case RoleSecretaria:
+		return u.CarreraID != 0 && u.CarreraID == carreraID
+	default:
+		return false
+	}
+}
+
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+// Package ratelimit limita la frecuencia de requests y de intentos de login
+// usando go-cache como almacenamiento en memoria con expiración.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// Limiter permite hasta Limit eventos por clave en cada ventana fija de Window.
+// La ventana empieza con el primer evento de la clave.
+type Limiter struct {
+	Limit  int
+	Window time.Duration
+	hits   *cache.Cache
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		Limit:  limit,
+		Window: window,
+		hits:   cache.New(window, 2*window),
+	}
+}
+
+// Allow registra un evento para key y dice si todavía está dentro del límite
+func (l *Limiter) Allow(key string) bool {
+	if l.hits.Add(key, 1, l.Window) == nil {
+		return true
+	}
+	n, err := l.hits.IncrementInt(key, 1)
+	if err != nil {
+		// La entrada expiró entre Add e Increment: arranca una ventana nueva
+		l.hits.Set(key, 1, l.Window)
+		return true
+	}
+	return n <= l.Limit
+}
+
+// LoginGuard cuenta intentos fallidos por clave (IP o cuenta) y bloquea con
+// una espera que se duplica en cada fallo después de MaxFailures, hasta MaxLockout.
+type LoginGuard struct {
+	MaxFailures int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// Los fallos se olvidan si pasa este tiempo sin intentos nuevos
+	Memory time.Duration
+
+	mu      sync.Mutex
+	entries *cache.Cache
+}
+
+type loginState struct {
+	failures    int
+	lockedUntil time.Time
+}
+
+func NewLoginGuard(maxFailures int, baseLockout, maxLockout, memory time.Duration) *LoginGuard {
+	return &LoginGuard{
+		MaxFailures: maxFailures,
+		BaseLockout: baseLockout,
+		MaxLockout:  maxLockout,
+		Memory:      memory,
+		entries:     cache.New(memory, 10*time.Minute),
+	}
+}
+
+// Locked devuelve cuánto falta para que key pueda volver a intentar (0 si no está bloqueada)
+func (g *LoginGuard) Locked(key string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v, ok := g.entries.Get(key)
+	if !ok {
+		return 0
+	}
+	if wait := time.Until(v.(loginState).lockedUntil); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Fail registra un intento fallido y devuelve el bloqueo resultante (0 si todavía no corresponde)
+func (g *LoginGuard) Fail(key string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var st loginState
+	if v, ok := g.entries.Get(key); ok {
+		st = v.(loginState)
+	}
+	st.failures++
+
+	var lockout time.Duration
+	if st.failures >= g.MaxFailures {
+		lockout = g.BaseLockout
+		for i := g.MaxFailures; i < st.failures && lockout < g.MaxLockout; i++ {
+			lockout *= 2
+		}
+		if lockout > g.MaxLockout {
+			lockout = g.MaxLockout
+		}
+		st.lockedUntil = time.Now().Add(lockout)
+	}
+
+	// La entrada vive al menos lo que dure el bloqueo
+	ttl := g.Memory
+	if lockout > ttl {
+		ttl = lockout
+	}
+	g.entries.Set(key, st, ttl)
+	return lockout
+}
+
+// Reset olvida los fallos de key, por ejemplo después de un login correcto
+func (g *LoginGuard) Reset(key string) {
+	g.entries.Delete(key)
+}
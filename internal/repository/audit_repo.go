@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"mi-bot-unne/internal/metrics"
+	"mi-bot-unne/internal/models"
+)
+
+// SystemActor se registra cuando un cambio no viene de un usuario del panel
+const SystemActor = "sistema"
+
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{DB: db}
+}
+
+// AuditFilter restringe List; los campos vacíos no filtran. Desde/Hasta son YYYY-MM-DD inclusive.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Entity string
+	Desde  string
+	Hasta  string
+	Limit  int
+	Offset int
+}
+
+func (r *AuditRepository) List(f AuditFilter) ([]models.AuditEntry, error) {
+	defer observe("audit.List")()
+	sqlQuery := `
+		SELECT id, actor, action, entity, COALESCE(entity_id, 0), COALESCE(old_value, ''), COALESCE(new_value, ''), created_at
+		FROM audit_log
+		WHERE 1=1
+	`
+	var args []any
+	if f.Actor != "" {
+		sqlQuery += " AND actor LIKE ?"
+		args = append(args, "%"+f.Actor+"%")
+	}
+	if f.Action != "" {
+		sqlQuery += " AND action = ?"
+		args = append(args, f.Action)
+	}
+	if f.Entity != "" {
+		sqlQuery += " AND entity = ?"
+		args = append(args, f.Entity)
+	}
+	if f.Desde != "" {
+		sqlQuery += " AND created_at >= ?"
+		args = append(args, f.Desde)
+	}
+	if f.Hasta != "" {
+		sqlQuery += " AND created_at < date(?, '+1 day')"
+		args = append(args, f.Hasta)
+	}
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	sqlQuery += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, f.Limit, f.Offset)
+
+	rows, err := r.DB.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Entity, &e.EntityID, &e.OldValue, &e.NewValue, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// audited ejecuta fn en una transacción y registra el cambio en audit_log dentro de la misma,
+// así no puede quedar un cambio sin auditar ni una auditoría de un cambio que falló.
+// fn devuelve el id de la entidad afectada y su valor nuevo (nil para bajas).
+func audited(db *sql.DB, actor, action, entity string, old any, fn func(tx *sql.Tx) (int, any, error)) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, newValue, err := fn(tx)
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actor, action, entity, id, old, newValue); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	metrics.AdminMutations.WithLabelValues(entity, action).Inc()
+	return nil
+}
+
+func recordAudit(tx *sql.Tx, actor, action, entity string, id int, old, newValue any) error {
+	if actor == "" {
+		actor = SystemActor
+	}
+	oldJSON, err := auditJSON(old)
+	if err != nil {
+		return err
+	}
+	newJSON, err := auditJSON(newValue)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO audit_log (actor, action, entity, entity_id, old_value, new_value, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		actor, action, entity, id, oldJSON, newJSON, time.Now().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+func auditJSON(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
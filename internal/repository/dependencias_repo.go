@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"mi-bot-unne/internal/models"
+)
+
+var (
+	// ErrEnUso se devuelve al borrar algo que tiene mesas o usuarios; en ese caso se archiva
+	ErrEnUso = errors.New("hay mesas o usuarios que dependen de este elemento; archivalo en lugar de borrarlo")
+	// ErrConfirmarCascada se devuelve cuando el borrado arrastra aulas o filas del plan y no se confirmó
+	ErrConfirmarCascada = errors.New("el borrado elimina también aulas o materias del plan; hay que confirmarlo")
+	// ErrTurnoEnUso se devuelve al borrar un turno con mesas; los turnos no se archivan
+	ErrTurnoEnUso = errors.New("hay mesas en este turno; movelas a otro turno o borralas antes de eliminarlo")
+	// ErrMesasFueraDelTurno se devuelve al achicar un turno de modo que alguna de sus mesas queda afuera
+	ErrMesasFueraDelTurno = errors.New("hay mesas del turno fuera de las nuevas fechas; cambiales la fecha antes")
+	ErrTipoInvalido       = errors.New("tipo de parámetro inválido")
+)
+
+// paramTables son las tablas de los parámetros que se pueden archivar, por tipo
+var paramTables = map[string]string{
+	"sede":    "sedes",
+	"aula":    "aulas",
+	"carrera": "carreras",
+	"materia": "materias",
+}
+
+// querier es *sql.DB o *sql.Tx, para contar dependencias dentro o fuera de una transacción
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// GetDependencias counts the mesas, aulas, plan rows and users that reference a parameter or turno
+func (r *ParamsRepository) GetDependencias(tipo string, id int) (models.Dependencias, error) {
+	defer observe("params.GetDependencias")()
+	return dependencias(r.DB, tipo, id)
+}
+
+func dependencias(q querier, tipo string, id int) (models.Dependencias, error) {
+	var d models.Dependencias
+	var mesasWhere string
+	switch tipo {
+	case "sede":
+		mesasWhere = "aula_id IN (SELECT id FROM aulas WHERE sede_id = ?)"
+	case "aula":
+		mesasWhere = "aula_id = ?"
+	case "carrera":
+		mesasWhere = "carrera_id = ?"
+	case "materia":
+		mesasWhere = "materia_id = ?"
+	case "turno":
+		mesasWhere = "turno_id = ?"
+	default:
+		return d, ErrTipoInvalido
+	}
+
+	err := q.QueryRow(`SELECT COUNT(*), COUNT(CASE WHEN fecha IS NULL OR fecha >= ? THEN 1 END)
+		FROM mesas WHERE `+mesasWhere, models.Today(), id).Scan(&d.Mesas, &d.MesasFuturas)
+	if err != nil {
+		return d, err
+	}
+
+	counts := map[*int]string{}
+	switch tipo {
+	case "sede":
+		counts[&d.Aulas] = "SELECT COUNT(*) FROM aulas WHERE sede_id = ?"
+	case "carrera":
+		counts[&d.Plan] = "SELECT COUNT(*) FROM plan_materias WHERE carrera_id = ?"
+		counts[&d.Usuarios] = "SELECT COUNT(*) FROM users WHERE carrera_id = ?"
+	case "materia":
+		counts[&d.Plan] = "SELECT COUNT(*) FROM plan_materias WHERE materia_id = ?"
+	}
+	for dest, query := range counts {
+		if err := q.QueryRow(query, id).Scan(dest); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+// deleteParam borra el parámetro si nada lo bloquea. Las aulas de una sede y las
+// filas del plan se borran en la misma transacción sólo con cascada, y cada una
+// queda en la auditoría.
+func (r *ParamsRepository) deleteParam(tipo string, id int, old any, cascada bool) error {
+	return audited(r.DB, r.Actor, models.AuditDelete, tipo, old, func(tx *sql.Tx) (int, any, error) {
+		d, err := dependencias(tx, tipo, id)
+		if err != nil {
+			return 0, nil, err
+		}
+		if d.Bloquean() {
+			return 0, nil, ErrEnUso
+		}
+		if d.Cascada() && !cascada {
+			return 0, nil, ErrConfirmarCascada
+		}
+		if err := r.deleteDependientes(tx, tipo, id); err != nil {
+			return 0, nil, err
+		}
+		_, err = tx.Exec("DELETE FROM "+paramTables[tipo]+" WHERE id = ?", id)
+		return id, nil, err
+	})
+}
+
+func (r *ParamsRepository) deleteDependientes(tx *sql.Tx, tipo string, id int) error {
+	switch tipo {
+	case "sede":
+		aulas, err := queryAulas(tx, "SELECT id, nombre, sede_id FROM aulas WHERE sede_id = ?", id)
+		if err != nil {
+			return err
+		}
+		for _, a := range aulas {
+			if err := recordAudit(tx, r.Actor, models.AuditDelete, "aula", a.ID, a, nil); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("DELETE FROM aulas WHERE sede_id = ?", id)
+		return err
+	case "carrera", "materia":
+		column := "p." + tipo + "_id"
+		rows, err := tx.Query(planSelect+" WHERE "+column+" = ?", id)
+		if err != nil {
+			return err
+		}
+		var plan []models.PlanMateria
+		for rows.Next() {
+			p, err := scanPlanMateria(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			plan = append(plan, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, p := range plan {
+			if err := recordAudit(tx, r.Actor, models.AuditDelete, "plan_materia", p.ID, p, nil); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("DELETE FROM plan_materias WHERE "+tipo+"_id = ?", id)
+		return err
+	}
+	return nil
+}
+
+// Archivar hides a parameter from forms and the chat; mesas that use it keep showing it
+func (r *ParamsRepository) Archivar(tipo string, id int) error {
+	defer observe("params.Archivar")()
+	return r.setArchivado(tipo, id, time.Now().Format("2006-01-02 15:04:05"))
+}
+
+func (r *ParamsRepository) Restaurar(tipo string, id int) error {
+	defer observe("params.Restaurar")()
+	return r.setArchivado(tipo, id, "")
+}
+
+func (r *ParamsRepository) setArchivado(tipo string, id int, at string) error {
+	table, ok := paramTables[tipo]
+	if !ok {
+		return ErrTipoInvalido
+	}
+	old := models.ParamArchivado{Tipo: tipo}
+	err := r.DB.QueryRow("SELECT id, nombre, COALESCE(archivado_at, '') FROM "+table+" WHERE id = ?", id).
+		Scan(&old.ID, &old.Nombre, &old.ArchivadoAt)
+	if err != nil {
+		return err
+	}
+	value := old
+	value.ArchivadoAt = at
+	return r.update(tipo, id, old, value, "UPDATE "+table+" SET archivado_at = ? WHERE id = ?", nullableString(at), id)
+}
+
+// GetArchivados lists every archived sede, aula, carrera and materia
+func (r *ParamsRepository) GetArchivados() ([]models.ParamArchivado, error) {
+	defer observe("params.GetArchivados")()
+	rows, err := r.DB.Query(`
+		SELECT 'sede', id, nombre, archivado_at FROM sedes WHERE archivado_at IS NOT NULL
+		UNION ALL
+		SELECT 'aula', a.id, a.nombre || COALESCE(' (' || s.nombre || ')', ''), a.archivado_at
+			FROM aulas a LEFT JOIN sedes s ON s.id = a.sede_id WHERE a.archivado_at IS NOT NULL
+		UNION ALL
+		SELECT 'carrera', id, nombre, archivado_at FROM carreras WHERE archivado_at IS NOT NULL
+		UNION ALL
+		SELECT 'materia', id, nombre, archivado_at FROM materias WHERE archivado_at IS NOT NULL
+		ORDER BY 1, 3`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var archivados []models.ParamArchivado
+	for rows.Next() {
+		var p models.ParamArchivado
+		if err := rows.Scan(&p.Tipo, &p.ID, &p.Nombre, &p.ArchivadoAt); err != nil {
+			return nil, err
+		}
+		archivados = append(archivados, p)
+	}
+	return archivados, rows.Err()
+}
@@ -2,26 +2,61 @@ package repository
 
 import (
 	"database/sql"
+	"mi-bot-unne/internal/metrics"
 	"mi-bot-unne/internal/models"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type MesaRepository struct {
 	DB *sql.DB
+	// Actor es quien figura en audit_log para los cambios hechos con este repo
+	Actor string
 }
 
 func NewMesaRepository(db *sql.DB) *MesaRepository {
-	// Simple migration: try to add the column, ignore error if it exists
-	// In a real app, use a migration tool or check schema first.
-	// SQLite 'ADD COLUMN' is safe if we ignore duplication errors or check PRAGMA table_info.
-	// For simplicity in this agentic context, we'll brute-force try to add it.
-	_, _ = db.Exec("ALTER TABLE mesas ADD COLUMN fecha_edicion TEXT")
 	return &MesaRepository{DB: db}
 }
 
-func (r *MesaRepository) GetAll() ([]models.Mesa, error) {
-	rows, err := r.DB.Query("SELECT id, materia, turno, fecha, hora, aula, carrera, COALESCE(fecha_edicion, '') FROM mesas ORDER BY id DESC")
+// As returns a copy of the repository whose mutations are audited under actor
+func (r *MesaRepository) As(actor string) *MesaRepository {
+	c := *r
+	c.Actor = actor
+	return &c
+}
+
+// mesaSelect reads a mesa together with the names of everything it references.
+// LEFT JOINs keep a mesa visible even if one of its references was deleted.
+const mesaSelect = `
+	SELECT
+		m.id, m.materia_id, COALESCE(mat.nombre, ''), m.carrera_id, COALESCE(car.nombre, ''),
+		COALESCE(m.turno_id, 0), COALESCE(t.nombre, ''), COALESCE(m.fecha, ''), COALESCE(m.hora, ''),
+		COALESCE(m.aula_id, 0), COALESCE(a.nombre, ''), COALESCE(a.sede_id, 0), COALESCE(s.nombre, ''),
+		COALESCE(m.fecha_edicion, '')
+	FROM mesas m
+	LEFT JOIN materias mat ON mat.id = m.materia_id
+	LEFT JOIN carreras car ON car.id = m.carrera_id
+	LEFT JOIN turnos_config t ON t.id = m.turno_id
+	LEFT JOIN aulas a ON a.id = m.aula_id
+	LEFT JOIN sedes s ON s.id = a.sede_id
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMesa(row rowScanner) (models.Mesa, error) {
+	var m models.Mesa
+	err := row.Scan(&m.ID, &m.MateriaID, &m.Materia, &m.CarreraID, &m.Carrera,
+		&m.TurnoID, &m.Turno, &m.Fecha, &m.Hora,
+		&m.AulaID, &m.Aula, &m.SedeID, &m.Sede,
+		&m.FechaEdicion)
+	return m, err
+}
+
+func (r *MesaRepository) queryMesas(sqlQuery string, args ...any) ([]models.Mesa, error) {
+	rows, err := r.DB.Query(sqlQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -29,59 +64,227 @@ func (r *MesaRepository) GetAll() ([]models.Mesa, error) {
 
 	var mesas []models.Mesa
 	for rows.Next() {
-		var m models.Mesa
-		if err := rows.Scan(&m.ID, &m.Materia, &m.Turno, &m.Fecha, &m.Hora, &m.Aula, &m.Carrera, &m.FechaEdicion); err != nil {
+		m, err := scanMesa(rows)
+		if err != nil {
 			return nil, err
 		}
 		mesas = append(mesas, m)
 	}
-	return mesas, nil
+	return mesas, rows.Err()
+}
+
+func (r *MesaRepository) GetAll() ([]models.Mesa, error) {
+	defer observe("mesas.GetAll")()
+	return r.queryMesas(mesaSelect + " ORDER BY m.id DESC")
 }
 
-func (r *MesaRepository) Create(m models.Mesa) error {
-	stmt, err := r.DB.Prepare("INSERT INTO mesas(materia, turno, fecha, hora, aula, carrera, fecha_edicion) VALUES(?, ?, ?, ?, ?, ?, ?)")
+// MesaFilter restringe los resultados de Each y List; los campos vacíos no filtran
+type MesaFilter struct {
+	Materia string
+	// MateriaIDs deja las mesas de cualquiera de esas materias
+	MateriaIDs []int
+	Turno      string
+	TurnoID    int
+	CarreraID  int
+	SedeID     int
+	// Desde y Hasta acotan la fecha, inclusive; las mesas sin fecha quedan afuera
+	Desde, Hasta models.Date
+}
+
+// where arma la condición SQL del filtro sobre mesaSelect
+func (f MesaFilter) where() (string, []any) {
+	where := " WHERE 1=1"
+	var args []any
+	if f.Materia != "" {
+		where += " AND mat.nombre = ?"
+		args = append(args, f.Materia)
+	}
+	if len(f.MateriaIDs) > 0 {
+		where += " AND m.materia_id IN (?" + strings.Repeat(", ?", len(f.MateriaIDs)-1) + ")"
+		for _, id := range f.MateriaIDs {
+			args = append(args, id)
+		}
+	}
+	if f.Turno != "" {
+		where += " AND t.nombre = ?"
+		args = append(args, f.Turno)
+	}
+	if f.TurnoID != 0 {
+		where += " AND m.turno_id = ?"
+		args = append(args, f.TurnoID)
+	}
+	if f.CarreraID != 0 {
+		where += " AND m.carrera_id = ?"
+		args = append(args, f.CarreraID)
+	}
+	if f.SedeID != 0 {
+		where += " AND a.sede_id = ?"
+		args = append(args, f.SedeID)
+	}
+	if !f.Desde.IsZero() {
+		where += " AND m.fecha >= ?"
+		args = append(args, f.Desde)
+	}
+	if !f.Hasta.IsZero() {
+		where += " AND m.fecha <= ?"
+		args = append(args, f.Hasta)
+	}
+	return where, args
+}
+
+// Each recorre las mesas que cumplen el filtro (con su sede) en orden cronológico,
+// sin cargarlas todas en memoria. Si fn devuelve error, el recorrido se corta.
+func (r *MesaRepository) Each(f MesaFilter, fn func(models.Mesa) error) error {
+	var inFn time.Duration
+	defer observeExcluding("mesas.Each", &inFn)()
+	where, args := f.where()
+	rows, err := r.DB.Query(mesaSelect+where+" ORDER BY m.fecha ASC, m.hora ASC, m.id ASC", args...)
 	if err != nil {
 		return err
 	}
-	_, err = stmt.Exec(m.Materia, m.Turno, m.Fecha, m.Hora, m.Aula, m.Carrera, m.FechaEdicion)
-	return err
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scanMesa(rows)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		err = fn(m)
+		inFn += time.Since(start)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// List devuelve una página de las mesas que cumplen el filtro, en orden cronológico
+// con las que no tienen fecha al final, y el total sin paginar
+func (r *MesaRepository) List(f MesaFilter, limit, offset int) ([]models.Mesa, int, error) {
+	defer observe("mesas.List")()
+	where, args := f.where()
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM mesas m
+		LEFT JOIN materias mat ON mat.id = m.materia_id
+		LEFT JOIN turnos_config t ON t.id = m.turno_id
+		LEFT JOIN aulas a ON a.id = m.aula_id`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	mesas, err := r.queryMesas(mesaSelect+where+" ORDER BY m.fecha IS NULL, m.fecha ASC, m.hora ASC, m.id ASC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	return mesas, total, err
+}
+
+const insertMesa = "INSERT INTO mesas(materia_id, carrera_id, turno_id, aula_id, fecha, hora, fecha_edicion) VALUES(?, ?, ?, ?, ?, ?, ?)"
+
+func insertMesaArgs(m models.Mesa) []any {
+	return []any{m.MateriaID, m.CarreraID, nullableID(m.TurnoID), nullableID(m.AulaID), m.Fecha, m.Hora, m.FechaEdicion}
+}
+
+// Create inserts a mesa and returns its id
+func (r *MesaRepository) Create(m models.Mesa) (int, error) {
+	defer observe("mesas.Create")()
+	err := audited(r.DB, r.Actor, models.AuditCreate, "mesa", nil, func(tx *sql.Tx) (int, any, error) {
+		res, err := tx.Exec(insertMesa, insertMesaArgs(m)...)
+		if err != nil {
+			return 0, nil, err
+		}
+		id, _ := res.LastInsertId()
+		m.ID = int(id)
+		return m.ID, m, nil
+	})
+	return m.ID, err
+}
+
+// CreateBatch inserts all mesas in a single transaction: either every row is stored or none is
+func (r *MesaRepository) CreateBatch(mesas []models.Mesa) error {
+	defer observe("mesas.CreateBatch")()
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(insertMesa)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range mesas {
+		res, err := stmt.Exec(insertMesaArgs(m)...)
+		if err != nil {
+			return err
+		}
+		id, _ := res.LastInsertId()
+		m.ID = int(id)
+		if err := recordAudit(tx, r.Actor, models.AuditCreate, "mesa", m.ID, nil, m); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	metrics.AdminMutations.WithLabelValues("mesa", models.AuditCreate).Add(float64(len(mesas)))
+	return nil
+}
+
+// GetByID returns a single mesa by its id
+func (r *MesaRepository) GetByID(id int) (models.Mesa, error) {
+	defer observe("mesas.GetByID")()
+	return scanMesa(r.DB.QueryRow(mesaSelect+" WHERE m.id = ?", id))
+}
+
+// Update overwrites an existing mesa and refreshes its fecha_edicion
+func (r *MesaRepository) Update(m models.Mesa) error {
+	defer observe("mesas.Update")()
+	old, err := r.GetByID(m.ID)
+	if err != nil {
+		return err
+	}
+	m.FechaEdicion = time.Now().Format("2006-01-02 15:04:05")
+	return audited(r.DB, r.Actor, models.AuditUpdate, "mesa", old, func(tx *sql.Tx) (int, any, error) {
+		res, err := tx.Exec("UPDATE mesas SET materia_id=?, carrera_id=?, turno_id=?, aula_id=?, fecha=?, hora=?, fecha_edicion=? WHERE id=?",
+			m.MateriaID, m.CarreraID, nullableID(m.TurnoID), nullableID(m.AulaID), m.Fecha, m.Hora, m.FechaEdicion, m.ID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return 0, nil, sql.ErrNoRows
+		}
+		return m.ID, m, nil
+	})
 }
 
 func (r *MesaRepository) Delete(id string) error {
-	stmt, err := r.DB.Prepare("DELETE FROM mesas WHERE id = ?")
+	defer observe("mesas.Delete")()
+	mesaID, err := strconv.Atoi(id)
 	if err != nil {
 		return err
 	}
-	_, err = stmt.Exec(id)
-	return err
+	old, err := r.GetByID(mesaID)
+	if err != nil {
+		return err
+	}
+	return audited(r.DB, r.Actor, models.AuditDelete, "mesa", old, func(tx *sql.Tx) (int, any, error) {
+		_, err := tx.Exec("DELETE FROM mesas WHERE id = ?", mesaID)
+		return mesaID, nil, err
+	})
 }
 
 func (r *MesaRepository) SearchWithFilter(materia, mesaFilter string) ([]models.Mesa, error) {
+	defer observe("mesas.SearchWithFilter")()
 	// Query matches Mesa/Turno first (DB side)
-	sqlQuery := `
-		SELECT 
-			m.materia, m.turno, m.fecha, m.aula, m.hora, m.carrera, 
-			COALESCE(m.fecha_edicion, ''), COALESCE(s.nombre, '')
-		FROM mesas m
-		LEFT JOIN aulas a ON m.aula = a.nombre 
-		LEFT JOIN sedes s ON a.sede_id = s.id
-		WHERE (CAST(m.id AS TEXT) = ? OR m.turno LIKE ?)
-	`
-
-	rows, err := r.DB.Query(sqlQuery, mesaFilter, "%"+mesaFilter+"%")
+	mesas, err := r.queryMesas(mesaSelect+" WHERE (CAST(m.id AS TEXT) = ? OR t.nombre LIKE ?)", mesaFilter, "%"+mesaFilter+"%")
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	var resultados []models.Mesa
 	normalizedInput := Normalize(materia)
 
-	for rows.Next() {
-		var m models.Mesa
-		if err := rows.Scan(&m.Materia, &m.Turno, &m.Fecha, &m.Aula, &m.Hora, &m.Carrera, &m.FechaEdicion, &m.Sede); err != nil {
-			return nil, err
-		}
+	for _, m := range mesas {
 		if m.Sede == "" {
 			m.Sede = "Sin asignar / Consultar"
 		}
@@ -94,9 +297,18 @@ func (r *MesaRepository) SearchWithFilter(materia, mesaFilter string) ([]models.
 	return resultados, nil
 }
 
-func (r *MesaRepository) GetUniqueMaterias(pattern string) ([]string, error) {
-	// Fetch ALL distinct materias, then filter in Go
-	rows, err := r.DB.Query("SELECT DISTINCT materia FROM mesas")
+// GetUniqueMaterias returns the names of materias with mesas that loosely match pattern.
+// With a carreraID, only materias in that carrera's plan (or with mesas of that carrera) are offered.
+func (r *MesaRepository) GetUniqueMaterias(pattern string, carreraID int) ([]string, error) {
+	defer observe("mesas.GetUniqueMaterias")()
+	// Fetch ALL materias that have mesas, then filter in Go
+	query := "SELECT DISTINCT mat.nombre FROM mesas m JOIN materias mat ON mat.id = m.materia_id"
+	var args []any
+	if carreraID != 0 {
+		query += " WHERE m.carrera_id = ? OR m.materia_id IN (SELECT materia_id FROM plan_materias WHERE carrera_id = ?)"
+		args = append(args, carreraID, carreraID)
+	}
+	rows, err := r.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -126,99 +338,81 @@ func Normalize(s string) string {
 	return r.Replace(s)
 }
 
-func (r *MesaRepository) GetFullSchedule(materia string) ([]models.Mesa, error) {
-	sqlQuery := `
-		SELECT 
-			m.materia, m.turno, m.fecha, m.aula, m.hora, m.carrera, 
-			COALESCE(m.fecha_edicion, ''), COALESCE(s.nombre, '')
-		FROM mesas m
-		LEFT JOIN aulas a ON m.aula = a.nombre 
-		LEFT JOIN sedes s ON a.sede_id = s.id
-		WHERE m.materia = ?
-		ORDER BY m.id ASC
-	`
-
-	rows, err := r.DB.Query(sqlQuery, materia)
+// GetFullSchedule returns every mesa of the materia in chronological order (undated ones last).
+// A non-zero carreraID keeps only the mesas of that carrera.
+func (r *MesaRepository) GetFullSchedule(materia string, carreraID int) ([]models.Mesa, error) {
+	defer observe("mesas.GetFullSchedule")()
+	resultados, err := r.queryMesas(mesaSelect+" WHERE mat.nombre = ? AND (? = 0 OR m.carrera_id = ?) ORDER BY m.fecha IS NULL, m.fecha ASC, m.hora ASC, m.id ASC",
+		materia, carreraID, carreraID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var resultados []models.Mesa
-	for rows.Next() {
-		var m models.Mesa
-		if err := rows.Scan(&m.Materia, &m.Turno, &m.Fecha, &m.Aula, &m.Hora, &m.Carrera, &m.FechaEdicion, &m.Sede); err != nil {
-			return nil, err
-		}
-		if m.Sede == "" {
-			m.Sede = "Sin asignar"
+	for i := range resultados {
+		if resultados[i].Sede == "" {
+			resultados[i].Sede = "Sin asignar"
 		}
-		resultados = append(resultados, m)
 	}
 	return resultados, nil
 }
 
-// GetFutureDates returns only mesas with dates >= today
+// GetFutureDates returns the mesas from today on (and those still without a date), in chronological order
 func (r *MesaRepository) GetFutureDates(materia string) ([]models.Mesa, error) {
-	sqlQuery := `
-		SELECT 
-			m.materia, m.turno, m.fecha, m.aula, m.hora, m.carrera, 
-			COALESCE(m.fecha_edicion, ''), COALESCE(s.nombre, '')
-		FROM mesas m
-		LEFT JOIN aulas a ON m.aula = a.nombre 
-		LEFT JOIN sedes s ON a.sede_id = s.id
-		WHERE m.materia = ?
-		ORDER BY m.id ASC
-	`
-
-	rows, err := r.DB.Query(sqlQuery, materia)
+	defer observe("mesas.GetFutureDates")()
+	mesas, err := r.queryMesas(mesaSelect+" WHERE mat.nombre = ? AND (m.fecha IS NULL OR m.fecha >= ?) ORDER BY m.fecha IS NULL, m.fecha ASC, m.hora ASC",
+		materia, models.Today())
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var resultados []models.Mesa
-	today := time.Now()
-
-	for rows.Next() {
-		var m models.Mesa
-		if err := rows.Scan(&m.Materia, &m.Turno, &m.Fecha, &m.Aula, &m.Hora, &m.Carrera, &m.FechaEdicion, &m.Sede); err != nil {
-			return nil, err
-		}
-		if m.Sede == "" {
-			m.Sede = "Sin asignar"
-		}
 
-		// Parse date (DD/MM/YYYY format)
-		if fechaParsed, err := time.Parse("02/01/2006", m.Fecha); err == nil {
-			if fechaParsed.After(today) || fechaParsed.Equal(today.Truncate(24*time.Hour)) {
-				resultados = append(resultados, m)
-			}
-		} else {
-			// If parse fails, include it anyway
-			resultados = append(resultados, m)
+	for i := range mesas {
+		if mesas[i].Sede == "" {
+			mesas[i].Sede = "Sin asignar"
 		}
 	}
-	return resultados, nil
+	return mesas, nil
 }
 
-// GetByTurn returns mesas for a specific turn/turno
-func (r *MesaRepository) GetByTurn(materia string, turno string) (models.Mesa, error) {
-	sqlQuery := `
-		SELECT 
-			m.materia, m.turno, m.fecha, m.aula, m.hora, m.carrera, 
-			COALESCE(m.fecha_edicion, ''), COALESCE(s.nombre, '')
-		FROM mesas m
-		LEFT JOIN aulas a ON m.aula = a.nombre 
-		LEFT JOIN sedes s ON a.sede_id = s.id
-		WHERE m.materia = ? AND m.turno = ?
-		LIMIT 1
-	`
-
-	var m models.Mesa
-	err := r.DB.QueryRow(sqlQuery, materia, turno).Scan(&m.Materia, &m.Turno, &m.Fecha, &m.Aula, &m.Hora, &m.Carrera, &m.FechaEdicion, &m.Sede)
+// GetByTurn returns the mesa of a materia in a specific turno, preferring the given carrera
+func (r *MesaRepository) GetByTurn(materia string, turno string, carreraID int) (models.Mesa, error) {
+	defer observe("mesas.GetByTurn")()
+	m, err := scanMesa(r.DB.QueryRow(mesaSelect+" WHERE mat.nombre = ? AND t.nombre = ? ORDER BY m.carrera_id = ? DESC LIMIT 1", materia, turno, carreraID))
 	if m.Sede == "" {
 		m.Sede = "Sin asignar"
 	}
 	return m, err
 }
+
+// GetUnmapped lists the rows the text-to-id migration could not fully resolve.
+// Migrated mesas drop off the list once their turno, aula, fecha and hora are filled in.
+func (r *MesaRepository) GetUnmapped() ([]models.UnmappedMesa, error) {
+	defer observe("mesas.GetUnmapped")()
+	rows, err := r.DB.Query(`
+		SELECT u.id, u.legacy_id, COALESCE(u.mesa_id, 0), COALESCE(u.materia, ''), COALESCE(u.carrera, ''), COALESCE(u.turno, ''),
+			COALESCE(u.aula, ''), COALESCE(u.fecha, ''), COALESCE(u.hora, ''), u.motivo
+		FROM mesas_sin_mapear u
+		LEFT JOIN mesas m ON m.id = u.mesa_id
+		WHERE u.mesa_id IS NULL OR m.turno_id IS NULL OR m.aula_id IS NULL OR m.fecha IS NULL OR m.hora IS NULL
+		ORDER BY u.legacy_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var unmapped []models.UnmappedMesa
+	for rows.Next() {
+		var u models.UnmappedMesa
+		if err := rows.Scan(&u.ID, &u.LegacyID, &u.MesaID, &u.Materia, &u.Carrera, &u.Turno, &u.Aula, &u.Fecha, &u.Hora, &u.Motivo); err != nil {
+			return nil, err
+		}
+		unmapped = append(unmapped, u)
+	}
+	return unmapped, rows.Err()
+}
+
+// DismissUnmapped removes a row from the migration report once it was handled by hand
+func (r *MesaRepository) DismissUnmapped(id int) error {
+	defer observe("mesas.DismissUnmapped")()
+	_, err := r.DB.Exec("DELETE FROM mesas_sin_mapear WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"mi-bot-unne/internal/models"
+)
+
+func TestGetUniqueMaterias(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db)
+	seedMesas(t, repo)
+
+	tests := []struct {
+		name      string
+		pattern   string
+		carreraID int
+		want      []string
+	}{
+		{"sin tildes ni mayúsculas", "ALGEBRA", 0, []string{"Álgebra I"}},
+		{"coincidencia parcial", "ana", 0, []string{"Análisis Matemático I"}},
+		{"varias coincidencias", "i", 0, []string{"Análisis Matemático I", "Física I", "Sistemas Operativos", "Álgebra I"}},
+		{"materia del plan con mesas en la carrera", "algebra", carreraMatematica, []string{"Álgebra I"}},
+		{"materia del plan con mesas en otra carrera", "analisis", carreraFisica, []string{"Análisis Matemático I"}},
+		{"materia fuera del plan", "fisica", carreraSistemas, nil},
+		{"materia sin mesas", "algoritmos", 0, nil},
+		{"sin coincidencias", "quimica", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetUniqueMaterias(tt.pattern, tt.carreraID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			slices.Sort(tt.want)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetUniqueMaterias(%q, %d) = %q, want %q", tt.pattern, tt.carreraID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFullSchedule(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db)
+	seedMesas(t, repo)
+
+	tests := []struct {
+		name       string
+		materia    string
+		carreraID  int
+		wantFechas []string
+	}{
+		{"todas las carreras en orden cronológico", "Álgebra I", 0, []string{"2025-02-18", "2025-02-19", "2025-03-11"}},
+		{"solo una carrera", "Álgebra I", carreraMatematica, []string{"2025-02-19"}},
+		{"carrera sin mesas de la materia", "Álgebra I", carreraFisica, nil},
+		{"sin fecha al final", "Sistemas Operativos", 0, []string{""}},
+		{"nombre exacto", "algebra", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mesas, err := repo.GetFullSchedule(tt.materia, tt.carreraID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fechas []string
+			for _, m := range mesas {
+				fechas = append(fechas, m.Fecha.String())
+				if m.Sede == "" {
+					t.Errorf("mesa %d sin sede; se esperaba el texto por defecto", m.ID)
+				}
+			}
+			if !slices.Equal(fechas, tt.wantFechas) {
+				t.Errorf("fechas = %q, want %q", fechas, tt.wantFechas)
+			}
+		})
+	}
+}
+
+func TestGetByTurn(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db)
+	seedMesas(t, repo)
+
+	tests := []struct {
+		name        string
+		materia     string
+		turno       string
+		carreraID   int
+		wantCarrera int
+		wantErr     error
+	}{
+		{"prefiere la carrera elegida", "Álgebra I", "1° Turno", carreraMatematica, carreraMatematica, nil},
+		{"prefiere la otra carrera elegida", "Álgebra I", "1° Turno", carreraSistemas, carreraSistemas, nil},
+		{"única mesa del turno", "Álgebra I", "2° Turno", carreraMatematica, carreraSistemas, nil},
+		{"turno sin mesa", "Álgebra I", "5° Turno", 0, 0, sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := repo.GetByTurn(tt.materia, tt.turno, tt.carreraID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (m.CarreraID != tt.wantCarrera || m.Turno != tt.turno) {
+				t.Errorf("GetByTurn = carrera %d turno %q, want carrera %d turno %q", m.CarreraID, m.Turno, tt.wantCarrera, tt.turno)
+			}
+		})
+	}
+}
+
+func TestGetFutureDates(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db)
+	seedMesas(t, repo)
+
+	tests := []struct {
+		materia string
+		want    int
+	}{
+		{"Física I", 1},            // 2099
+		{"Álgebra I", 0},           // todas en 2025
+		{"Sistemas Operativos", 1}, // sin fecha todavía
+	}
+	for _, tt := range tests {
+		mesas, err := repo.GetFutureDates(tt.materia)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mesas) != tt.want {
+			t.Errorf("GetFutureDates(%q) = %d mesas, want %d", tt.materia, len(mesas), tt.want)
+		}
+	}
+}
+
+func TestEachFilters(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db)
+	seedMesas(t, repo)
+
+	tests := []struct {
+		name   string
+		filter MesaFilter
+		want   int
+	}{
+		{"sin filtro", MesaFilter{}, 6},
+		{"materia", MesaFilter{Materia: "Álgebra I"}, 3},
+		{"turno por nombre", MesaFilter{Turno: "1° Turno"}, 3},
+		{"turno por id", MesaFilter{TurnoID: 2}, 1},
+		{"carrera", MesaFilter{CarreraID: carreraSistemas}, 4},
+		{"sede", MesaFilter{SedeID: sedeResistencia}, 3},
+		{"combinados", MesaFilter{Materia: "Álgebra I", CarreraID: carreraSistemas, TurnoID: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			err := repo.Each(tt.filter, func(m models.Mesa) error {
+				n++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Errorf("Each(%+v) recorrió %d mesas, want %d", tt.filter, n, tt.want)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db)
+	seedMesas(t, repo)
+
+	tests := []struct {
+		name          string
+		filter        MesaFilter
+		limit, offset int
+		wantFechas    []string
+		wantTotal     int
+	}{
+		{"todas, sin fecha al final", MesaFilter{}, 10, 0, []string{"2025-02-18", "2025-02-19", "2025-02-20", "2025-03-11", "2099-03-26", ""}, 6},
+		{"página", MesaFilter{}, 2, 2, []string{"2025-02-20", "2025-03-11"}, 6},
+		{"después del final", MesaFilter{}, 2, 10, nil, 6},
+		{"varias materias", MesaFilter{MateriaIDs: []int{materiaAnalisis, materiaFisica}}, 10, 0, []string{"2025-02-20", "2099-03-26"}, 2},
+		{"rango de fechas", MesaFilter{Desde: date(t, "2025-02-19"), Hasta: date(t, "2025-03-11")}, 10, 0, []string{"2025-02-19", "2025-02-20", "2025-03-11"}, 3},
+		{"sede y carrera", MesaFilter{SedeID: sedeResistencia, CarreraID: carreraSistemas}, 10, 0, []string{"2025-02-18", "2025-03-11"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mesas, total, err := repo.List(tt.filter, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fechas []string
+			for _, m := range mesas {
+				fechas = append(fechas, m.Fecha.String())
+			}
+			if !slices.Equal(fechas, tt.wantFechas) || total != tt.wantTotal {
+				t.Errorf("List(%+v) = %q (total %d), want %q (total %d)", tt.filter, fechas, total, tt.wantFechas, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestMesaUpdateAndDeleteAreAudited(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db).As("secretaria@unne.edu.ar")
+	mesas := seedMesas(t, repo)
+	audit := NewAuditRepository(db)
+
+	m := mesas[0]
+	m.AulaID = aulaMagna
+	m.Hora = hora(t, "18:00")
+	if err := repo.Update(m); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := repo.GetByID(m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Aula != "Aula Magna" || got.Hora.String() != "18:00" || got.FechaEdicion == "" {
+		t.Errorf("mesa actualizada = %+v", got)
+	}
+
+	if err := repo.Delete(strconv.Itoa(m.ID)); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.GetByID(m.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID después de borrar: err = %v, want sql.ErrNoRows", err)
+	}
+
+	m.ID = 9999
+	if err := repo.Update(m); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Update de una mesa inexistente: err = %v, want sql.ErrNoRows", err)
+	}
+
+	entries, err := audit.List(AuditFilter{Entity: "mesa", Actor: "secretaria"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	// Seis altas de seedMesas, la edición y la baja, de la más nueva a la más vieja
+	want := []string{models.AuditDelete, models.AuditUpdate}
+	if len(actions) != 8 || !slices.Equal(actions[:2], want) {
+		t.Errorf("auditoría = %q, want 8 entradas empezando por %q", actions, want)
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewMesaRepository(db)
+
+	batch := []models.Mesa{
+		{MateriaID: materiaAlgebra, CarreraID: carreraSistemas, TurnoID: 5, AulaID: aulaUno, Fecha: date(t, "2025-07-01"), Hora: hora(t, "08:00")},
+		{MateriaID: materiaAnalisis, CarreraID: carreraSistemas, TurnoID: 5, AulaID: aulaLab2, Fecha: date(t, "2025-07-02"), Hora: hora(t, "08:00")},
+	}
+	if err := repo.CreateBatch(batch); err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("GetAll = %d mesas, want 2", len(all))
+	}
+	if all[0].Sede != "Campus Corrientes" {
+		t.Errorf("sede de la última mesa = %q, want Campus Corrientes", all[0].Sede)
+	}
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"time"
+
+	"mi-bot-unne/internal/metrics"
+)
+
+// observe mide una consulta; se usa como defer observe("mesas.GetAll")(). Todos los
+// métodos exportados de los repositorios lo llaman, salvo As y los que solo combinan
+// otros métodos ya medidos (UserRepository.Authenticate, EnsureBootstrapAdmin). En los
+// que hashean contraseñas se llama después del bcrypt.
+func observe(query string) func() {
+	return observeExcluding(query, new(time.Duration))
+}
+
+// observeExcluding descuenta *excluded de la medición: Each lo usa para no contar el
+// tiempo de la función que recibe cada fila (escribir la respuesta, por ejemplo)
+func observeExcluding(query string, excluded *time.Duration) func() {
+	start := time.Now()
+	return func() {
+		metrics.QueryDuration.WithLabelValues(query).Observe((time.Since(start) - *excluded).Seconds())
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Los métodos exportados que no llaman a observe (ver su comentario)
+var notObserved = map[string]bool{
+	"MesaRepository.As":                   true,
+	"ParamsRepository.As":                 true,
+	"UserRepository.Authenticate":         true,
+	"UserRepository.EnsureBootstrapAdmin": true,
+}
+
+// TestEveryMethodObserved evita que un método nuevo quede fuera de
+// mibot_repository_query_duration_seconds
+func TestEveryMethodObserved(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || !fn.Name.IsExported() {
+				continue
+			}
+			star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			recv := star.X.(*ast.Ident).Name
+			name := recv + "." + fn.Name.Name
+			if !strings.HasSuffix(recv, "Repository") || notObserved[name] {
+				continue
+			}
+			observed := false
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					if id, ok := call.Fun.(*ast.Ident); ok && (id.Name == "observe" || id.Name == "observeExcluding") {
+						observed = true
+					}
+				}
+				return !observed
+			})
+			if !observed {
+				t.Errorf("%s (%s) no llama a observe", name, fset.Position(fn.Pos()))
+			}
+		}
+	}
+}
@@ -2,42 +2,71 @@ package repository
 
 import (
 	"database/sql"
-	"fmt"
 	"mi-bot-unne/internal/models"
-	"time"
+	"sort"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type ParamsRepository struct {
 	DB *sql.DB
+	// Actor es quien figura en audit_log para los cambios hechos con este repo
+	Actor string
 }
 
 func NewParamsRepository(db *sql.DB) *ParamsRepository {
-	repo := &ParamsRepository{DB: db}
-
-	// Create table if not exists
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS turnos_config (
-			id INTEGER PRIMARY KEY, 
-			nombre TEXT, 
-			fecha_inicio TEXT, 
-			fecha_fin TEXT, 
-			receso INTEGER
-		);
-	`)
-	if err != nil {
-		fmt.Println("Error creating table turnos_config:", err)
-	}
+	return &ParamsRepository{DB: db}
+}
+
+// As returns a copy of the repository whose mutations are audited under actor
+func (r *ParamsRepository) As(actor string) *ParamsRepository {
+	c := *r
+	c.Actor = actor
+	return &c
+}
 
-	repo.EnsureAulaUndefined()
-	repo.SeedTurnos()
+// insert runs an audited INSERT and returns the generated id; value builds the new_value from it
+func (r *ParamsRepository) insert(entity string, value func(id int) any, query string, args ...any) (int, error) {
+	var newID int
+	err := audited(r.DB, r.Actor, models.AuditCreate, entity, nil, func(tx *sql.Tx) (int, any, error) {
+		res, err := tx.Exec(query, args...)
+		if err != nil {
+			return 0, nil, err
+		}
+		id, _ := res.LastInsertId()
+		newID = int(id)
+		return newID, value(newID), nil
+	})
+	return newID, err
+}
+
+// update runs an audited UPDATE of a single row; old is the value read before the change
+func (r *ParamsRepository) update(entity string, id int, old, value any, query string, args ...any) error {
+	return audited(r.DB, r.Actor, models.AuditUpdate, entity, old, func(tx *sql.Tx) (int, any, error) {
+		res, err := tx.Exec(query, args...)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return 0, nil, sql.ErrNoRows
+		}
+		return id, value, nil
+	})
+}
 
-	return repo
+// remove runs an audited DELETE of a single row
+func (r *ParamsRepository) remove(entity string, id int, old any, query string) error {
+	return audited(r.DB, r.Actor, models.AuditDelete, entity, old, func(tx *sql.Tx) (int, any, error) {
+		_, err := tx.Exec(query, id)
+		return id, nil, err
+	})
 }
 
+// Los GetAll* devuelven sólo lo que no está archivado, que es lo que se ofrece en los formularios
 func (r *ParamsRepository) GetAllSedes() ([]models.Sede, error) {
-	rows, err := r.DB.Query("SELECT id, nombre FROM sedes")
+	defer observe("params.GetAllSedes")()
+	rows, err := r.DB.Query("SELECT id, nombre FROM sedes WHERE archivado_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -54,26 +83,19 @@ func (r *ParamsRepository) GetAllSedes() ([]models.Sede, error) {
 	return sedes, nil
 }
 
+// GetAulasBySede returns the aulas of a sede that are not archived
 func (r *ParamsRepository) GetAulasBySede(sedeID int) ([]models.Aula, error) {
-	rows, err := r.DB.Query("SELECT id, nombre, sede_id FROM aulas WHERE sede_id = ?", sedeID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var aulas []models.Aula
-	for rows.Next() {
-		var a models.Aula
-		if err := rows.Scan(&a.ID, &a.Nombre, &a.SedeID); err != nil {
-			return nil, err
-		}
-		aulas = append(aulas, a)
-	}
-	return aulas, nil
+	defer observe("params.GetAulasBySede")()
+	return queryAulas(r.DB, "SELECT id, nombre, COALESCE(sede_id, 0) FROM aulas WHERE COALESCE(sede_id, 0) = ? AND archivado_at IS NULL", sedeID)
 }
 
 func (r *ParamsRepository) GetAllAulas() ([]models.Aula, error) {
-	rows, err := r.DB.Query("SELECT id, nombre, sede_id FROM aulas")
+	defer observe("params.GetAllAulas")()
+	return queryAulas(r.DB, "SELECT id, nombre, COALESCE(sede_id, 0) FROM aulas WHERE archivado_at IS NULL")
+}
+
+func queryAulas(q querier, query string, args ...any) ([]models.Aula, error) {
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -87,11 +109,12 @@ func (r *ParamsRepository) GetAllAulas() ([]models.Aula, error) {
 		}
 		aulas = append(aulas, a)
 	}
-	return aulas, nil
+	return aulas, rows.Err()
 }
 
 func (r *ParamsRepository) GetAllCarreras() ([]models.Carrera, error) {
-	rows, err := r.DB.Query("SELECT id, nombre FROM carreras")
+	defer observe("params.GetAllCarreras")()
+	rows, err := r.DB.Query("SELECT id, nombre FROM carreras WHERE archivado_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -109,7 +132,8 @@ func (r *ParamsRepository) GetAllCarreras() ([]models.Carrera, error) {
 }
 
 func (r *ParamsRepository) GetAllMaterias() ([]models.Materia, error) {
-	rows, err := r.DB.Query("SELECT id, nombre FROM materias")
+	defer observe("params.GetAllMaterias")()
+	rows, err := r.DB.Query("SELECT id, nombre FROM materias WHERE archivado_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -126,81 +150,116 @@ func (r *ParamsRepository) GetAllMaterias() ([]models.Materia, error) {
 	return materias, nil
 }
 
-func (r *ParamsRepository) CreateMateria(nombre string) error {
-	_, err := r.DB.Exec("INSERT INTO materias (nombre) VALUES (?)", nombre)
-	return err
-}
-
-func (r *ParamsRepository) CreateSede(nombre string) error {
-	_, err := r.DB.Exec("INSERT INTO sedes (nombre) VALUES (?)", nombre)
-	return err
+// SearchMaterias devuelve las materias no archivadas cuyo nombre contiene q, sin
+// distinguir mayúsculas ni acentos, como las busca el chat; q vacío las devuelve todas
+func (r *ParamsRepository) SearchMaterias(q string) ([]models.Materia, error) {
+	defer observe("params.SearchMaterias")()
+	materias, err := r.GetAllMaterias()
+	if err != nil {
+		return nil, err
+	}
+	q = Normalize(q)
+	var found []models.Materia
+	for _, m := range materias {
+		if strings.Contains(Normalize(m.Nombre), q) {
+			found = append(found, m)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return Normalize(found[i].Nombre) < Normalize(found[j].Nombre) })
+	return found, nil
 }
 
-func (r *ParamsRepository) CreateAula(nombre string, sedeID int) error {
-	_, err := r.DB.Exec("INSERT INTO aulas (nombre, sede_id) VALUES (?, ?)", nombre, sedeID)
-	return err
+func (r *ParamsRepository) CreateMateria(nombre string) (int, error) {
+	defer observe("params.CreateMateria")()
+	return r.insert("materia", func(id int) any { return models.Materia{ID: id, Nombre: nombre} },
+		"INSERT INTO materias (nombre) VALUES (?)", nombre)
 }
 
-func (r *ParamsRepository) CreateCarrera(nombre string) error {
-	_, err := r.DB.Exec("INSERT INTO carreras (nombre) VALUES (?)", nombre)
-	return err
+func (r *ParamsRepository) CreateSede(nombre string) (int, error) {
+	defer observe("params.CreateSede")()
+	return r.insert("sede", func(id int) any { return models.Sede{ID: id, Nombre: nombre} },
+		"INSERT INTO sedes (nombre) VALUES (?)", nombre)
 }
 
-func (r *ParamsRepository) EnsureAulaUndefined() {
-	var count int
-	r.DB.QueryRow("SELECT COUNT(*) FROM aulas WHERE nombre = 'Sin definir'").Scan(&count)
-	if count == 0 {
-		r.DB.Exec("INSERT INTO aulas (nombre, sede_id) VALUES ('Sin definir', 0)")
-	}
+func (r *ParamsRepository) CreateAula(nombre string, sedeID int) (int, error) {
+	defer observe("params.CreateAula")()
+	return r.insert("aula", func(id int) any { return models.Aula{ID: id, Nombre: nombre, SedeID: sedeID} },
+		"INSERT INTO aulas (nombre, sede_id) VALUES (?, ?)", nombre, nullableID(sedeID))
 }
 
-func (r *ParamsRepository) SeedTurnos() {
-	var count int
-	r.DB.QueryRow("SELECT COUNT(*) FROM turnos_config").Scan(&count)
-	if count == 0 {
-		// Specific Seeding data provided by User
-		// 1° Turno 17 al 21 de febrero (Receso=true)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "1° Turno", "2025-02-17", "2025-02-21", 1)
-		// 2° Turno 10 al 14 de marzo (Receso=true)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "2° Turno", "2025-03-10", "2025-03-14", 1)
-		// 3° Turno 25 al 31 de marzo (Receso=true)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "3° Turno", "2025-03-25", "2025-03-31", 1)
-		// 4° Turno 05 al 30 de mayo (Receso=false - Mesa expandida)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "4° Turno", "2025-05-05", "2025-05-30", 0)
-		// 5° Turno 30 junio al 4 de julio (Receso=true)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "5° Turno", "2025-06-30", "2025-07-04", 1)
-		// 6° Turno 28 de julio al 01 de agosto (Receso=true)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "6° Turno", "2025-07-28", "2025-08-01", 1)
-		// 7° Turno 01 al 26 de septiembre (Receso=false - Mesa expandida)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "7° Turno", "2025-09-01", "2025-09-26", 0)
-		// 8° Turno 06 al 31 de octubre (Receso=false)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "8° Turno", "2025-10-06", "2025-10-31", 0)
-		// 9° Turno 24 de noviembre al 1 de diciembre (Receso=true)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "9° Turno", "2025-11-24", "2025-12-01", 1)
-		// 10° Turno 15 al 19 de diciembre (Receso=true)
-		r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", "10° Turno", "2025-12-15", "2025-12-19", 1)
-	}
+func (r *ParamsRepository) CreateCarrera(nombre string) (int, error) {
+	defer observe("params.CreateCarrera")()
+	return r.insert("carrera", func(id int) any { return models.Carrera{ID: id, Nombre: nombre} },
+		"INSERT INTO carreras (nombre) VALUES (?)", nombre)
 }
 
-func (r *ParamsRepository) CreateTurnoConfig(t models.TurnoConfig) error {
+func (r *ParamsRepository) CreateTurnoConfig(t models.TurnoConfig) (int, error) {
+	defer observe("params.CreateTurnoConfig")()
 	recesoInt := 0
 	if t.Receso {
 		recesoInt = 1
 	}
-	_, err := r.DB.Exec("INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", t.Nombre, t.FechaInicio, t.FechaFin, recesoInt)
-	return err
+	return r.insert("turno", func(id int) any { t.ID = id; return t },
+		"INSERT INTO turnos_config (nombre, fecha_inicio, fecha_fin, receso) VALUES (?, ?, ?, ?)", t.Nombre, t.FechaInicio, t.FechaFin, recesoInt)
 }
 
+// UpdateTurnoConfig modifica un turno. Si el nuevo rango deja afuera alguna de sus mesas
+// devuelve ErrMesasFueraDelTurno y no cambia nada.
 func (r *ParamsRepository) UpdateTurnoConfig(t models.TurnoConfig) error {
+	defer observe("params.UpdateTurnoConfig")()
 	recesoInt := 0
 	if t.Receso {
 		recesoInt = 1
 	}
-	_, err := r.DB.Exec("UPDATE turnos_config SET nombre=?, fecha_inicio=?, fecha_fin=?, receso=? WHERE id=?", t.Nombre, t.FechaInicio, t.FechaFin, recesoInt, t.ID)
-	return err
+	old, err := r.GetTurnoConfig(t.ID)
+	if err != nil {
+		return err
+	}
+	return audited(r.DB, r.Actor, models.AuditUpdate, "turno", old, func(tx *sql.Tx) (int, any, error) {
+		antes, despues, err := mesasFueraDelTurno(tx, t)
+		if err != nil {
+			return 0, nil, err
+		}
+		if antes+despues > 0 {
+			return 0, nil, ErrMesasFueraDelTurno
+		}
+		res, err := tx.Exec("UPDATE turnos_config SET nombre=?, fecha_inicio=?, fecha_fin=?, receso=? WHERE id=?", t.Nombre, t.FechaInicio, t.FechaFin, recesoInt, t.ID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return 0, nil, sql.ErrNoRows
+		}
+		return t.ID, t, nil
+	})
+}
+
+// MesasFueraDelTurno cuenta las mesas del turno t.ID con fecha anterior al inicio y
+// posterior al fin de t
+func (r *ParamsRepository) MesasFueraDelTurno(t models.TurnoConfig) (antes, despues int, err error) {
+	defer observe("params.MesasFueraDelTurno")()
+	return mesasFueraDelTurno(r.DB, t)
+}
+
+func mesasFueraDelTurno(q querier, t models.TurnoConfig) (antes, despues int, err error) {
+	// Un extremo vacío llega como NULL y la comparación no deja afuera ninguna mesa
+	err = q.QueryRow("SELECT COUNT(CASE WHEN fecha < ? THEN 1 END), COUNT(CASE WHEN fecha > ? THEN 1 END) FROM mesas WHERE turno_id = ?",
+		t.FechaInicio, t.FechaFin, t.ID).Scan(&antes, &despues)
+	return antes, despues, err
+}
+
+func (r *ParamsRepository) GetTurnoConfig(id int) (models.TurnoConfig, error) {
+	defer observe("params.GetTurnoConfig")()
+	var t models.TurnoConfig
+	var recesoInt int
+	err := r.DB.QueryRow("SELECT id, nombre, fecha_inicio, fecha_fin, receso FROM turnos_config WHERE id = ?", id).
+		Scan(&t.ID, &t.Nombre, &t.FechaInicio, &t.FechaFin, &recesoInt)
+	t.Receso = recesoInt == 1
+	return t, err
 }
 
 func (r *ParamsRepository) GetTurnoConfigs() ([]models.TurnoConfig, error) {
+	defer observe("params.GetTurnoConfigs")()
 	rows, err := r.DB.Query("SELECT id, nombre, fecha_inicio, fecha_fin, receso FROM turnos_config ORDER BY CAST(nombre AS INTEGER) ASC")
 	if err != nil {
 		return nil, err
@@ -220,88 +279,140 @@ func (r *ParamsRepository) GetTurnoConfigs() ([]models.TurnoConfig, error) {
 	return turnos, nil
 }
 
+// DeleteTurnoConfig borra un turno sin mesas; con mesas devuelve ErrTurnoEnUso
 func (r *ParamsRepository) DeleteTurnoConfig(id int) error {
-	_, err := r.DB.Exec("DELETE FROM turnos_config WHERE id = ?", id)
-	return err
+	defer observe("params.DeleteTurnoConfig")()
+	old, err := r.GetTurnoConfig(id)
+	if err != nil {
+		return err
+	}
+	return audited(r.DB, r.Actor, models.AuditDelete, "turno", old, func(tx *sql.Tx) (int, any, error) {
+		d, err := dependencias(tx, "turno", id)
+		if err != nil {
+			return 0, nil, err
+		}
+		if d.Mesas > 0 {
+			return 0, nil, ErrTurnoEnUso
+		}
+		_, err = tx.Exec("DELETE FROM turnos_config WHERE id = ?", id)
+		return id, nil, err
+	})
 }
 
 // --- CRUD Operations ---
 
 // Materia
 func (r *ParamsRepository) GetMateria(id int) (models.Materia, error) {
+	defer observe("params.GetMateria")()
 	var m models.Materia
 	err := r.DB.QueryRow("SELECT id, nombre FROM materias WHERE id = ?", id).Scan(&m.ID, &m.Nombre)
 	return m, err
 }
 func (r *ParamsRepository) UpdateMateria(id int, nombre string) error {
-	_, err := r.DB.Exec("UPDATE materias SET nombre = ? WHERE id = ?", nombre, id)
-	return err
+	defer observe("params.UpdateMateria")()
+	old, err := r.GetMateria(id)
+	if err != nil {
+		return err
+	}
+	return r.update("materia", id, old, models.Materia{ID: id, Nombre: nombre}, "UPDATE materias SET nombre = ? WHERE id = ?", nombre, id)
 }
-func (r *ParamsRepository) DeleteMateria(id int) error {
-	_, err := r.DB.Exec("DELETE FROM materias WHERE id = ?", id)
-	return err
+func (r *ParamsRepository) DeleteMateria(id int, cascada bool) error {
+	defer observe("params.DeleteMateria")()
+	old, err := r.GetMateria(id)
+	if err != nil {
+		return err
+	}
+	return r.deleteParam("materia", id, old, cascada)
 }
 
 // Carrera
 func (r *ParamsRepository) GetCarrera(id int) (models.Carrera, error) {
+	defer observe("params.GetCarrera")()
 	var c models.Carrera
 	err := r.DB.QueryRow("SELECT id, nombre FROM carreras WHERE id = ?", id).Scan(&c.ID, &c.Nombre)
 	return c, err
 }
 func (r *ParamsRepository) UpdateCarrera(id int, nombre string) error {
-	_, err := r.DB.Exec("UPDATE carreras SET nombre = ? WHERE id = ?", nombre, id)
-	return err
+	defer observe("params.UpdateCarrera")()
+	old, err := r.GetCarrera(id)
+	if err != nil {
+		return err
+	}
+	return r.update("carrera", id, old, models.Carrera{ID: id, Nombre: nombre}, "UPDATE carreras SET nombre = ? WHERE id = ?", nombre, id)
 }
-func (r *ParamsRepository) DeleteCarrera(id int) error {
-	_, err := r.DB.Exec("DELETE FROM carreras WHERE id = ?", id)
-	return err
+func (r *ParamsRepository) DeleteCarrera(id int, cascada bool) error {
+	defer observe("params.DeleteCarrera")()
+	old, err := r.GetCarrera(id)
+	if err != nil {
+		return err
+	}
+	return r.deleteParam("carrera", id, old, cascada)
 }
 
 // Sede
 func (r *ParamsRepository) GetSede(id int) (models.Sede, error) {
+	defer observe("params.GetSede")()
 	var s models.Sede
 	err := r.DB.QueryRow("SELECT id, nombre FROM sedes WHERE id = ?", id).Scan(&s.ID, &s.Nombre)
 	return s, err
 }
 func (r *ParamsRepository) UpdateSede(id int, nombre string) error {
-	_, err := r.DB.Exec("UPDATE sedes SET nombre = ? WHERE id = ?", nombre, id)
-	return err
+	defer observe("params.UpdateSede")()
+	old, err := r.GetSede(id)
+	if err != nil {
+		return err
+	}
+	return r.update("sede", id, old, models.Sede{ID: id, Nombre: nombre}, "UPDATE sedes SET nombre = ? WHERE id = ?", nombre, id)
 }
-func (r *ParamsRepository) DeleteSede(id int) error {
-	_, err := r.DB.Exec("DELETE FROM sedes WHERE id = ?", id)
-	return err
+func (r *ParamsRepository) DeleteSede(id int, cascada bool) error {
+	defer observe("params.DeleteSede")()
+	old, err := r.GetSede(id)
+	if err != nil {
+		return err
+	}
+	return r.deleteParam("sede", id, old, cascada)
 }
 
 // Aula
 func (r *ParamsRepository) GetAula(id int) (models.Aula, error) {
+	defer observe("params.GetAula")()
 	var a models.Aula
-	err := r.DB.QueryRow("SELECT id, nombre, sede_id FROM aulas WHERE id = ?", id).Scan(&a.ID, &a.Nombre, &a.SedeID)
+	err := r.DB.QueryRow("SELECT id, nombre, COALESCE(sede_id, 0) FROM aulas WHERE id = ?", id).Scan(&a.ID, &a.Nombre, &a.SedeID)
 	return a, err
 }
 func (r *ParamsRepository) UpdateAula(id int, nombre string, sedeID int) error {
-	_, err := r.DB.Exec("UPDATE aulas SET nombre = ?, sede_id = ? WHERE id = ?", nombre, sedeID, id)
-	return err
+	defer observe("params.UpdateAula")()
+	old, err := r.GetAula(id)
+	if err != nil {
+		return err
+	}
+	return r.update("aula", id, old, models.Aula{ID: id, Nombre: nombre, SedeID: sedeID},
+		"UPDATE aulas SET nombre = ?, sede_id = ? WHERE id = ?", nombre, nullableID(sedeID), id)
 }
-func (r *ParamsRepository) DeleteAula(id int) error {
-	_, err := r.DB.Exec("DELETE FROM aulas WHERE id = ?", id)
-	return err
+func (r *ParamsRepository) DeleteAula(id int, cascada bool) error {
+	defer observe("params.DeleteAula")()
+	old, err := r.GetAula(id)
+	if err != nil {
+		return err
+	}
+	return r.deleteParam("aula", id, old, cascada)
 }
 
 // GetFutureTurnos returns turnos with fecha_inicio >= today
 func (r *ParamsRepository) GetFutureTurnos() ([]models.TurnoConfig, error) {
+	defer observe("params.GetFutureTurnos")()
 	rows, err := r.DB.Query(`
-SELECT id, nombre, fecha_inicio, fecha_fin, receso 
-FROM turnos_config 
+SELECT id, nombre, fecha_inicio, fecha_fin, receso
+FROM turnos_config
+WHERE fecha_inicio >= ?
 ORDER BY fecha_inicio ASC
-`)
+`, models.Today())
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var turnos []models.TurnoConfig
-	today := time.Now()
-
 	for rows.Next() {
 		var t models.TurnoConfig
 		var recesoInt int
@@ -309,13 +420,7 @@ ORDER BY fecha_inicio ASC
 			return nil, err
 		}
 		t.Receso = recesoInt == 1
-
-		// Parse fecha_inicio (YYYY-MM-DD)
-		if fechaParsed, err := time.Parse("2006-01-02", t.FechaInicio); err == nil {
-			if fechaParsed.After(today) || fechaParsed.Equal(today.Truncate(24*time.Hour)) {
-				turnos = append(turnos, t)
-			}
-		}
+		turnos = append(turnos, t)
 	}
-	return turnos, nil
+	return turnos, rows.Err()
 }
@@ -0,0 +1,438 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"mi-bot-unne/internal/models"
+)
+
+func TestCreateParams(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewParamsRepository(db).As("admin@unne.edu.ar")
+
+	tests := []struct {
+		entity string
+		create func() (int, error)
+		count  func() (int, error)
+		want   int
+	}{
+		{"materia", func() (int, error) { return repo.CreateMateria("Química General") },
+			func() (int, error) { m, err := repo.GetAllMaterias(); return len(m), err }, 6},
+		{"carrera", func() (int, error) { return repo.CreateCarrera("Bioquímica") },
+			func() (int, error) { c, err := repo.GetAllCarreras(); return len(c), err }, 4},
+		{"sede", func() (int, error) { return repo.CreateSede("Campus Sargento Cabral") },
+			func() (int, error) { s, err := repo.GetAllSedes(); return len(s), err }, 4},
+		{"aula", func() (int, error) { return repo.CreateAula("Aula 3 - PA", sedeResistencia) },
+			func() (int, error) { a, err := repo.GetAulasBySede(sedeResistencia); return len(a), err }, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entity, func(t *testing.T) {
+			id, err := tt.create()
+			if err != nil || id == 0 {
+				t.Fatalf("alta: id %d, %v", id, err)
+			}
+			n, err := tt.count()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Errorf("hay %d, want %d", n, tt.want)
+			}
+			entries, err := NewAuditRepository(db).List(AuditFilter{Entity: tt.entity, Action: models.AuditCreate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Actor != "admin@unne.edu.ar" {
+				t.Errorf("auditoría = %+v, want una alta de admin@unne.edu.ar", entries)
+			}
+		})
+	}
+}
+
+func TestUpdateParams(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewParamsRepository(db)
+
+	if err := repo.UpdateMateria(materiaAlgebra, "Álgebra y Geometría"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateAula(aulaLab2, "Laboratorio 2 (ex Lab B)", sedeCentral); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := repo.GetMateria(materiaAlgebra); m.Nombre != "Álgebra y Geometría" {
+		t.Errorf("materia = %q", m.Nombre)
+	}
+	if a, _ := repo.GetAula(aulaLab2); a.SedeID != sedeCentral {
+		t.Errorf("aula en sede %d, want %d", a.SedeID, sedeCentral)
+	}
+	if err := repo.UpdateSede(999, "No existe"); err == nil {
+		t.Error("UpdateSede de una sede inexistente no devolvió error")
+	}
+}
+
+func TestDependencias(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewParamsRepository(db)
+	seedMesas(t, NewMesaRepository(db))
+	if err := NewUserRepository(db).Create(models.User{Email: "sec@unne.edu.ar", Role: models.RoleSecretaria, CarreraID: carreraFisica}, "secreto123"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tipo string
+		id   int
+		want models.Dependencias
+	}{
+		{"materia", materiaAlgebra, models.Dependencias{Mesas: 3, Plan: 2}},
+		{"materia", materiaFisica, models.Dependencias{Mesas: 1, MesasFuturas: 1, Plan: 1}},
+		{"materia", materiaAlgoritmos, models.Dependencias{Plan: 1}},
+		{"carrera", carreraSistemas, models.Dependencias{Mesas: 4, MesasFuturas: 1, Plan: 4}},
+		{"carrera", carreraFisica, models.Dependencias{Mesas: 1, MesasFuturas: 1, Plan: 2, Usuarios: 1}},
+		{"sede", sedeResistencia, models.Dependencias{Mesas: 3, Aulas: 3}},
+		{"sede", sedeCentral, models.Dependencias{Aulas: 1}},
+		{"aula", aulaLab1, models.Dependencias{Mesas: 2, MesasFuturas: 1}},
+		{"aula", aulaLab2, models.Dependencias{}},
+		{"turno", 1, models.Dependencias{Mesas: 3}},
+		{"turno", 3, models.Dependencias{Mesas: 1, MesasFuturas: 1}},
+		{"turno", 5, models.Dependencias{}},
+	}
+	for _, tt := range tests {
+		got, err := repo.GetDependencias(tt.tipo, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GetDependencias(%s, %d) = %+v, want %+v", tt.tipo, tt.id, got, tt.want)
+		}
+	}
+
+	if _, err := repo.GetDependencias("plan", 1); !errors.Is(err, ErrTipoInvalido) {
+		t.Errorf("tipo inválido: err = %v, want ErrTipoInvalido", err)
+	}
+}
+
+func TestDeleteParamIntegrity(t *testing.T) {
+	tests := []struct {
+		name    string
+		del     func(r *ParamsRepository) error
+		wantErr error
+		// gone verifica que se borró el elemento y lo que dependía de él
+		gone func(t *testing.T, r *ParamsRepository)
+	}{
+		{
+			name:    "materia con mesas",
+			del:     func(r *ParamsRepository) error { return r.DeleteMateria(materiaAlgebra, true) },
+			wantErr: ErrEnUso,
+		},
+		{
+			name:    "materia en un plan sin confirmar",
+			del:     func(r *ParamsRepository) error { return r.DeleteMateria(materiaAlgoritmos, false) },
+			wantErr: ErrConfirmarCascada,
+		},
+		{
+			name: "materia en un plan confirmando",
+			del:  func(r *ParamsRepository) error { return r.DeleteMateria(materiaAlgoritmos, true) },
+			gone: func(t *testing.T, r *ParamsRepository) {
+				plan, _ := r.GetPlan(carreraSistemas)
+				for _, p := range plan {
+					if p.MateriaID == materiaAlgoritmos {
+						t.Error("la fila del plan sigue existiendo")
+					}
+				}
+			},
+		},
+		{
+			name:    "sede con mesas en sus aulas",
+			del:     func(r *ParamsRepository) error { return r.DeleteSede(sedeResistencia, true) },
+			wantErr: ErrEnUso,
+		},
+		{
+			name:    "sede con aulas sin confirmar",
+			del:     func(r *ParamsRepository) error { return r.DeleteSede(sedeCentral, false) },
+			wantErr: ErrConfirmarCascada,
+		},
+		{
+			name: "sede con aulas confirmando",
+			del:  func(r *ParamsRepository) error { return r.DeleteSede(sedeCentral, true) },
+			gone: func(t *testing.T, r *ParamsRepository) {
+				if aulas, _ := r.GetAulasBySede(sedeCentral); len(aulas) != 0 {
+					t.Errorf("quedaron %d aulas huérfanas", len(aulas))
+				}
+			},
+		},
+		{
+			name: "aula sin mesas",
+			del:  func(r *ParamsRepository) error { return r.DeleteAula(aulaLab2, false) },
+			gone: func(t *testing.T, r *ParamsRepository) {
+				if _, err := r.GetAula(aulaLab2); err == nil {
+					t.Error("el aula sigue existiendo")
+				}
+			},
+		},
+		{
+			name:    "turno con mesas",
+			del:     func(r *ParamsRepository) error { return r.DeleteTurnoConfig(1) },
+			wantErr: ErrTurnoEnUso,
+		},
+		{
+			name: "turno sin mesas",
+			del:  func(r *ParamsRepository) error { return r.DeleteTurnoConfig(5) },
+			gone: func(t *testing.T, r *ParamsRepository) {
+				if _, err := r.GetTurnoConfig(5); !errors.Is(err, sql.ErrNoRows) {
+					t.Errorf("el turno sigue existiendo: err = %v", err)
+				}
+			},
+		},
+		{
+			name:    "carrera con usuarios y sin mesas",
+			del:     func(r *ParamsRepository) error { return r.DeleteCarrera(4, true) },
+			wantErr: ErrEnUso,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewParamsRepository(db)
+			seedMesas(t, NewMesaRepository(db))
+			if _, err := repo.CreateCarrera("Bioquímica"); err != nil {
+				t.Fatal(err)
+			}
+			if err := NewUserRepository(db).Create(models.User{Email: "bio@unne.edu.ar", Role: models.RoleSecretaria, CarreraID: 4}, "secreto123"); err != nil {
+				t.Fatal(err)
+			}
+
+			err := tt.del(repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.gone != nil {
+				tt.gone(t, repo)
+			}
+		})
+	}
+}
+
+func TestDeleteCascadeIsAudited(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewParamsRepository(db).As("admin@unne.edu.ar")
+
+	if err := repo.DeleteCarrera(carreraMatematica, true); err != nil {
+		t.Fatal(err)
+	}
+	audit := NewAuditRepository(db)
+	for entity, want := range map[string]int{"carrera": 1, "plan_materia": 2} {
+		entries, err := audit.List(AuditFilter{Entity: entity, Action: models.AuditDelete})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != want {
+			t.Errorf("%d bajas de %s auditadas, want %d", len(entries), entity, want)
+		}
+	}
+}
+
+func TestArchivarYRestaurar(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewParamsRepository(db)
+	mesas := NewMesaRepository(db)
+	seedMesas(t, mesas)
+
+	if err := repo.Archivar("materia", materiaAlgebra); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Archivar("aula", aulaLab1); err != nil {
+		t.Fatal(err)
+	}
+
+	materias, _ := repo.GetAllMaterias()
+	for _, m := range materias {
+		if m.ID == materiaAlgebra {
+			t.Error("GetAllMaterias ofrece una materia archivada")
+		}
+	}
+	if aulas, _ := repo.GetAulasBySede(2); len(aulas) != 1 {
+		t.Errorf("GetAulasBySede = %d aulas, want 1 (la otra está archivada)", len(aulas))
+	}
+
+	archivados, err := repo.GetArchivados()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archivados) != 2 || archivados[0].Tipo != "aula" || archivados[0].Nombre != "Laboratorio 1 (Campus Corrientes)" ||
+		archivados[1].Tipo != "materia" || archivados[1].ArchivadoAt == "" {
+		t.Errorf("GetArchivados = %+v", archivados)
+	}
+
+	// Las mesas históricas conservan el nombre
+	schedule, _ := mesas.GetFullSchedule("Álgebra I", 0)
+	if len(schedule) != 3 {
+		t.Errorf("GetFullSchedule de una materia archivada = %d mesas, want 3", len(schedule))
+	}
+
+	if err := repo.Restaurar("materia", materiaAlgebra); err != nil {
+		t.Fatal(err)
+	}
+	if archivados, _ := repo.GetArchivados(); len(archivados) != 1 {
+		t.Errorf("después de restaurar quedan %d archivados, want 1", len(archivados))
+	}
+
+	if err := repo.Archivar("turno", 1); !errors.Is(err, ErrTipoInvalido) {
+		t.Errorf("archivar un turno: err = %v, want ErrTipoInvalido", err)
+	}
+}
+
+func TestSearchMaterias(t *testing.T) {
+	repo := NewParamsRepository(newTestDB(t))
+	if err := repo.Archivar("materia", materiaFisica); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"ALGEBRA", []string{"Álgebra I"}},
+		{"algo", []string{"Algoritmos y Estructuras de Datos"}},
+		{"al", []string{"Álgebra I", "Algoritmos y Estructuras de Datos", "Análisis Matemático I"}},
+		{"fisica", nil}, // archivada
+		{"", []string{"Álgebra I", "Algoritmos y Estructuras de Datos", "Análisis Matemático I", "Sistemas Operativos"}},
+	}
+	for _, tt := range tests {
+		materias, err := repo.SearchMaterias(tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range materias {
+			got = append(got, m.Nombre)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SearchMaterias(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestPlanMaterias(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewParamsRepository(db)
+
+	err := repo.CreatePlanMateria(models.PlanMateria{CarreraID: carreraMatematica, MateriaID: materiaFisica, Anio: 2, Cuatrimestre: 1, Codigo: "MAT-201"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.CreatePlanMateria(models.PlanMateria{CarreraID: carreraMatematica, MateriaID: materiaAlgebra})
+	if !errors.Is(err, ErrAlreadyInPlan) {
+		t.Errorf("materia repetida: err = %v, want ErrAlreadyInPlan", err)
+	}
+
+	plan, err := repo.GetPlan(carreraMatematica)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range plan {
+		got = append(got, p.Codigo+" "+p.Periodo())
+	}
+	want := []string{"MAT-101 1° año · 1° cuat.", "MAT-102 1° año · 2° cuat.", "MAT-201 2° año · 1° cuat."}
+	if len(got) != len(want) {
+		t.Fatalf("GetPlan = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GetPlan[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	last := plan[2]
+	last.Anio, last.Cuatrimestre, last.Codigo = 0, 0, ""
+	last.CarreraID = carreraSistemas // se ignora: la carrera no cambia
+	if err := repo.UpdatePlanMateria(last); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := repo.GetPlanMateria(last.ID)
+	if updated.CarreraID != carreraMatematica || updated.Periodo() != "Anual" || updated.Codigo != "" {
+		t.Errorf("UpdatePlanMateria = %+v", updated)
+	}
+
+	if err := repo.DeletePlanMateria(last.ID); err != nil {
+		t.Fatal(err)
+	}
+	if carreras, _ := repo.GetCarrerasWithPlan(); len(carreras) != 3 {
+		t.Errorf("GetCarrerasWithPlan = %d carreras, want 3", len(carreras))
+	}
+	if err := repo.Archivar("carrera", carreraFisica); err != nil {
+		t.Fatal(err)
+	}
+	if carreras, _ := repo.GetCarrerasWithPlan(); len(carreras) != 2 {
+		t.Errorf("GetCarrerasWithPlan con una carrera archivada = %d carreras, want 2", len(carreras))
+	}
+}
+
+func TestGetFutureTurnos(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewParamsRepository(db)
+
+	turno := models.TurnoConfig{Nombre: "Turno Especial", FechaInicio: date(t, "2099-08-03"), FechaFin: date(t, "2099-08-07")}
+	if _, err := repo.CreateTurnoConfig(turno); err != nil {
+		t.Fatal(err)
+	}
+	turnos, err := repo.GetFutureTurnos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Los turnos sembrados son de 2025
+	if len(turnos) != 1 || turnos[0].Nombre != "Turno Especial" || turnos[0].FechaFin.String() != "2099-08-07" {
+		t.Errorf("GetFutureTurnos = %+v", turnos)
+	}
+
+	turnos[0].Receso = true
+	if err := repo.UpdateTurnoConfig(turnos[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.GetTurnoConfig(turnos[0].ID); !got.Receso {
+		t.Error("UpdateTurnoConfig no guardó el receso")
+	}
+	if err := repo.DeleteTurnoConfig(turnos[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := repo.GetTurnoConfigs(); len(all) != 10 {
+		t.Errorf("GetTurnoConfigs = %d turnos, want 10", len(all))
+	}
+}
+
+func TestUpdateTurnoConfigMesasFuera(t *testing.T) {
+	db := newTestDB(t)
+	seedMesas(t, NewMesaRepository(db))
+	repo := NewParamsRepository(db)
+
+	// Las mesas del 1° Turno son del 18 al 20 de febrero
+	turno, err := repo.GetTurnoConfig(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turno.FechaInicio = date(t, "2025-02-19")
+	turno.FechaFin = date(t, "2025-02-19")
+	if antes, despues, _ := repo.MesasFueraDelTurno(turno); antes != 1 || despues != 1 {
+		t.Errorf("MesasFueraDelTurno = %d antes, %d después, want 1 y 1", antes, despues)
+	}
+	if err := repo.UpdateTurnoConfig(turno); !errors.Is(err, ErrMesasFueraDelTurno) {
+		t.Fatalf("UpdateTurnoConfig = %v, want ErrMesasFueraDelTurno", err)
+	}
+	if got, _ := repo.GetTurnoConfig(1); got.FechaInicio.String() != "2025-02-17" {
+		t.Errorf("fecha_inicio = %s, el turno no tenía que cambiar", got.FechaInicio)
+	}
+
+	// Sin fecha de fin el rango queda abierto y ninguna mesa queda afuera
+	turno.FechaInicio = date(t, "2025-02-18")
+	turno.FechaFin = models.Date{}
+	if err := repo.UpdateTurnoConfig(turno); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.GetTurnoConfig(1); got.FechaInicio.String() != "2025-02-18" || !got.FechaFin.IsZero() {
+		t.Errorf("turno = %+v", got)
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"mi-bot-unne/internal/models"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrAlreadyInPlan se devuelve al agregar una materia que la carrera ya tiene en su plan
+var ErrAlreadyInPlan = errors.New("la materia ya está en el plan de esta carrera")
+
+// planSelect lee el plan con los nombres de carrera y materia. Los planes de estudio
+// se administran con ParamsRepository, igual que carreras y materias.
+const planSelect = `
+	SELECT p.id, p.carrera_id, car.nombre, p.materia_id, mat.nombre,
+		COALESCE(p.anio, 0), COALESCE(p.cuatrimestre, 0), COALESCE(p.codigo, '')
+	FROM plan_materias p
+	JOIN carreras car ON car.id = p.carrera_id
+	JOIN materias mat ON mat.id = p.materia_id`
+
+func scanPlanMateria(row rowScanner) (models.PlanMateria, error) {
+	var p models.PlanMateria
+	err := row.Scan(&p.ID, &p.CarreraID, &p.Carrera, &p.MateriaID, &p.Materia, &p.Anio, &p.Cuatrimestre, &p.Codigo)
+	return p, err
+}
+
+// GetPlan returns the plan of a carrera ordered by año, cuatrimestre and materia
+func (r *ParamsRepository) GetPlan(carreraID int) ([]models.PlanMateria, error) {
+	defer observe("params.GetPlan")()
+	rows, err := r.DB.Query(planSelect+`
+		WHERE p.carrera_id = ?
+		ORDER BY p.anio IS NULL, p.anio, p.cuatrimestre IS NULL, p.cuatrimestre, mat.nombre`, carreraID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plan []models.PlanMateria
+	for rows.Next() {
+		p, err := scanPlanMateria(rows)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, p)
+	}
+	return plan, rows.Err()
+}
+
+func (r *ParamsRepository) GetPlanMateria(id int) (models.PlanMateria, error) {
+	defer observe("params.GetPlanMateria")()
+	return scanPlanMateria(r.DB.QueryRow(planSelect+" WHERE p.id = ?", id))
+}
+
+// GetCarrerasWithPlan lists the active carreras that have at least one materia in their plan
+func (r *ParamsRepository) GetCarrerasWithPlan() ([]models.Carrera, error) {
+	defer observe("params.GetCarrerasWithPlan")()
+	rows, err := r.DB.Query(`
+		SELECT id, nombre FROM carreras
+		WHERE id IN (SELECT carrera_id FROM plan_materias) AND archivado_at IS NULL
+		ORDER BY nombre`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var carreras []models.Carrera
+	for rows.Next() {
+		var c models.Carrera
+		if err := rows.Scan(&c.ID, &c.Nombre); err != nil {
+			return nil, err
+		}
+		carreras = append(carreras, c)
+	}
+	return carreras, rows.Err()
+}
+
+func (r *ParamsRepository) CreatePlanMateria(p models.PlanMateria) error {
+	defer observe("params.CreatePlanMateria")()
+	_, err := r.insert("plan_materia", func(id int) any { p.ID = id; return p },
+		"INSERT INTO plan_materias (carrera_id, materia_id, anio, cuatrimestre, codigo) VALUES (?, ?, ?, ?, ?)",
+		p.CarreraID, p.MateriaID, nullableID(p.Anio), nullableID(p.Cuatrimestre), nullableString(p.Codigo))
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrAlreadyInPlan
+	}
+	return err
+}
+
+// UpdatePlanMateria changes año, cuatrimestre and código; the carrera and materia are fixed
+func (r *ParamsRepository) UpdatePlanMateria(p models.PlanMateria) error {
+	defer observe("params.UpdatePlanMateria")()
+	old, err := r.GetPlanMateria(p.ID)
+	if err != nil {
+		return err
+	}
+	p.CarreraID, p.Carrera, p.MateriaID, p.Materia = old.CarreraID, old.Carrera, old.MateriaID, old.Materia
+	return r.update("plan_materia", p.ID, old, p,
+		"UPDATE plan_materias SET anio = ?, cuatrimestre = ?, codigo = ? WHERE id = ?",
+		nullableID(p.Anio), nullableID(p.Cuatrimestre), nullableString(p.Codigo), p.ID)
+}
+
+func (r *ParamsRepository) DeletePlanMateria(id int) error {
+	defer observe("params.DeletePlanMateria")()
+	old, err := r.GetPlanMateria(id)
+	if err != nil {
+		return err
+	}
+	return r.remove("plan_materia", id, old, "DELETE FROM plan_materias WHERE id = ?")
+}
+
+func nullableString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+
+	"mi-bot-unne/internal/database"
+	"mi-bot-unne/internal/models"
+)
+
+// Datos sembrados por database.InitDB que usan los tests:
+//
+//	sedes:    1 Campus Resistencia, 2 Campus Corrientes, 3 Edificio Central
+//	aulas:    1 Sin definir (sin sede), 2-4 en la sede 1, 5-6 en la 2, 7 en la 3
+//	carreras: 1 Sistemas, 2 Matemática, 3 Física
+//	materias: 1 Álgebra I, 2 Análisis Matemático I, 3 Física I, 4 Algoritmos, 5 Sistemas Operativos
+//	plan:     carrera 1 → 1, 2, 4, 5; carrera 2 → 1, 2; carrera 3 → 2, 3
+//	turnos:   1 a 10 ("1° Turno"...), con fechas de 2025
+const (
+	sedeResistencia = 1
+	sedeCentral     = 3
+	aulaUno         = 2
+	aulaMagna       = 4
+	aulaLab1        = 5
+	aulaLab2        = 6
+
+	carreraSistemas   = 1
+	carreraMatematica = 2
+	carreraFisica     = 3
+
+	materiaAlgebra    = 1
+	materiaAnalisis   = 2
+	materiaFisica     = 3
+	materiaAlgoritmos = 4
+	materiaSO         = 5
+)
+
+// newTestDB crea una base en memoria con el esquema y los datos de ejemplo
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func date(t *testing.T, s string) models.Date {
+	t.Helper()
+	d, err := models.ParseDate(s)
+	if err != nil {
+		t.Fatalf("ParseDate(%q): %v", s, err)
+	}
+	return d
+}
+
+func hora(t *testing.T, s string) models.TimeOfDay {
+	t.Helper()
+	h, err := models.ParseTimeOfDay(s)
+	if err != nil {
+		t.Fatalf("ParseTimeOfDay(%q): %v", s, err)
+	}
+	return h
+}
+
+// seedMesas carga un calendario chico y devuelve las mesas con sus ids
+func seedMesas(t *testing.T, repo *MesaRepository) []models.Mesa {
+	t.Helper()
+	mesas := []models.Mesa{
+		{MateriaID: materiaAlgebra, CarreraID: carreraSistemas, TurnoID: 1, AulaID: aulaUno, Fecha: date(t, "2025-02-18"), Hora: hora(t, "08:00")},
+		{MateriaID: materiaAlgebra, CarreraID: carreraMatematica, TurnoID: 1, AulaID: aulaMagna, Fecha: date(t, "2025-02-19"), Hora: hora(t, "14:00")},
+		{MateriaID: materiaAlgebra, CarreraID: carreraSistemas, TurnoID: 2, AulaID: aulaUno, Fecha: date(t, "2025-03-11"), Hora: hora(t, "08:00")},
+		{MateriaID: materiaAnalisis, CarreraID: carreraSistemas, TurnoID: 1, AulaID: aulaLab1, Fecha: date(t, "2025-02-20"), Hora: hora(t, "10:00")},
+		{MateriaID: materiaFisica, CarreraID: carreraFisica, TurnoID: 3, AulaID: aulaLab1, Fecha: date(t, "2099-03-26"), Hora: hora(t, "09:30")},
+		{MateriaID: materiaSO, CarreraID: carreraSistemas, TurnoID: 4},
+	}
+	for _, m := range mesas {
+		if _, err := repo.Create(m); err != nil {
+			t.Fatalf("Create(%+v): %v", m, err)
+		}
+	}
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	return all
+}
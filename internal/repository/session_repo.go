@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"mi-bot-unne/internal/models"
+)
+
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{DB: db}
+}
+
+// Create abre una sesión nueva y devuelve el token para la cookie
+func (r *SessionRepository) Create(userID int, ttl time.Duration) (string, error) {
+	defer observe("sessions.Create")()
+	// Aprovechamos cada login para limpiar sesiones vencidas
+	r.DeleteExpired()
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	_, err := r.DB.Exec("INSERT INTO sessions (id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		hashToken(token), userID, time.Now().Format("2006-01-02 15:04:05"), time.Now().Add(ttl).Unix())
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Get devuelve la sesión si existe y no venció; si no, sql.ErrNoRows
+func (r *SessionRepository) Get(token string) (models.Session, error) {
+	defer observe("sessions.Get")()
+	var s models.Session
+	var expires int64
+	err := r.DB.QueryRow("SELECT id, user_id, created_at, expires_at FROM sessions WHERE id = ? AND expires_at > ?",
+		hashToken(token), time.Now().Unix()).Scan(&s.ID, &s.UserID, &s.CreatedAt, &expires)
+	s.ExpiresAt = time.Unix(expires, 0)
+	return s, err
+}
+
+func (r *SessionRepository) Delete(token string) error {
+	defer observe("sessions.Delete")()
+	_, err := r.DB.Exec("DELETE FROM sessions WHERE id = ?", hashToken(token))
+	return err
+}
+
+// DeleteForUser revoca todas las sesiones de un usuario (cambio de contraseña, baja, cambio de rol)
+func (r *SessionRepository) DeleteForUser(userID int) error {
+	defer observe("sessions.DeleteForUser")()
+	_, err := r.DB.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	return err
+}
+
+func (r *SessionRepository) DeleteExpired() error {
+	defer observe("sessions.DeleteExpired")()
+	_, err := r.DB.Exec("DELETE FROM sessions WHERE expires_at <= ?", time.Now().Unix())
+	return err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"mi-bot-unne/internal/models"
+)
+
+// TokenPrefix identifica los tokens de la API, para reconocerlos si se filtran en un log o un repo
+const TokenPrefix = "mibot_"
+
+type TokenRepository struct {
+	DB *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{DB: db}
+}
+
+// Create genera un token para el usuario y lo devuelve en claro; no se puede volver a leer
+func (r *TokenRepository) Create(userID int, nombre string, scopes []string) (string, models.APIToken, error) {
+	defer observe("tokens.Create")()
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", models.APIToken{}, err
+	}
+	token := TokenPrefix + hex.EncodeToString(b)
+
+	t := models.APIToken{UserID: userID, Nombre: nombre, Scopes: scopes, CreatedAt: time.Now().Format("2006-01-02 15:04:05")}
+	res, err := r.DB.Exec("INSERT INTO api_tokens (user_id, nombre, token_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)",
+		userID, nombre, hashToken(token), strings.Join(scopes, " "), t.CreatedAt)
+	if err != nil {
+		return "", t, err
+	}
+	id, _ := res.LastInsertId()
+	t.ID = int(id)
+	return token, t, nil
+}
+
+const tokenSelect = "SELECT id, user_id, nombre, scopes, created_at, COALESCE(last_used_at, '') FROM api_tokens"
+
+func scanToken(row interface{ Scan(...any) error }) (models.APIToken, error) {
+	var t models.APIToken
+	var scopes string
+	err := row.Scan(&t.ID, &t.UserID, &t.Nombre, &scopes, &t.CreatedAt, &t.LastUsedAt)
+	t.Scopes = strings.Fields(scopes)
+	return t, err
+}
+
+// Authenticate devuelve el token y registra su uso; si no existe o fue revocado, sql.ErrNoRows
+func (r *TokenRepository) Authenticate(token string) (models.APIToken, error) {
+	defer observe("tokens.Authenticate")()
+	t, err := scanToken(r.DB.QueryRow(tokenSelect+" WHERE token_hash = ?", hashToken(token)))
+	if err != nil {
+		return t, err
+	}
+	t.LastUsedAt = time.Now().Format("2006-01-02 15:04:05")
+	_, err = r.DB.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", t.LastUsedAt, t.ID)
+	return t, err
+}
+
+// ListForUser devuelve los tokens del usuario, los más nuevos primero
+func (r *TokenRepository) ListForUser(userID int) ([]models.APIToken, error) {
+	defer observe("tokens.ListForUser")()
+	rows, err := r.DB.Query(tokenSelect+" WHERE user_id = ? ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke borra un token del usuario; si no es suyo o no existe, sql.ErrNoRows
+func (r *TokenRepository) Revoke(id, userID int) error {
+	defer observe("tokens.Revoke")()
+	res, err := r.DB.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteForUser revoca todos los tokens de un usuario (baja del usuario)
+func (r *TokenRepository) DeleteForUser(userID int) error {
+	defer observe("tokens.DeleteForUser")()
+	_, err := r.DB.Exec("DELETE FROM api_tokens WHERE user_id = ?", userID)
+	return err
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"mi-bot-unne/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength es el largo mínimo aceptado para contraseñas nuevas
+const MinPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("credenciales incorrectas")
+	ErrPasswordTooShort   = errors.New("la contraseña debe tener al menos 8 caracteres")
+)
+
+type UserRepository struct {
+	DB *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{DB: db}
+}
+
+const userSelect = `
+	SELECT u.id, u.email, u.password_hash, u.role, COALESCE(u.carrera_id, 0), COALESCE(c.nombre, ''),
+		u.must_change_password, u.created_at
+	FROM users u
+	LEFT JOIN carreras c ON u.carrera_id = c.id
+`
+
+func scanUser(row interface{ Scan(...any) error }) (models.User, error) {
+	var u models.User
+	var mustChange int
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CarreraID, &u.Carrera, &mustChange, &u.CreatedAt)
+	u.MustChangePassword = mustChange == 1
+	return u, err
+}
+
+func (r *UserRepository) GetAll() ([]models.User, error) {
+	defer observe("users.GetAll")()
+	rows, err := r.DB.Query(userSelect + " ORDER BY u.email ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (r *UserRepository) GetByID(id int) (models.User, error) {
+	defer observe("users.GetByID")()
+	return scanUser(r.DB.QueryRow(userSelect+" WHERE u.id = ?", id))
+}
+
+func (r *UserRepository) GetByEmail(email string) (models.User, error) {
+	defer observe("users.GetByEmail")()
+	return scanUser(r.DB.QueryRow(userSelect+" WHERE u.email = ?", email))
+}
+
+func (r *UserRepository) Count() (int, error) {
+	defer observe("users.Count")()
+	var count int
+	err := r.DB.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	return count, err
+}
+
+// Authenticate devuelve el usuario si el email y la contraseña coinciden
+func (r *UserRepository) Authenticate(email, password string) (models.User, error) {
+	u, err := r.GetByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Comparamos igual contra un hash para no revelar por tiempo si el email existe
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return models.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return models.User{}, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Create da de alta un usuario con la contraseña indicada
+func (r *UserRepository) Create(u models.User, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	defer observe("users.Create")() // después del bcrypt, que no es tiempo de la base
+	_, err = r.DB.Exec("INSERT INTO users (email, password_hash, role, carrera_id, must_change_password, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		u.Email, hash, u.Role, nullableID(u.CarreraID), boolToInt(u.MustChangePassword), time.Now().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// Update modifica rol y carrera; el email y la contraseña se cambian por separado
+func (r *UserRepository) Update(u models.User) error {
+	defer observe("users.Update")()
+	_, err := r.DB.Exec("UPDATE users SET role = ?, carrera_id = ? WHERE id = ?", u.Role, nullableID(u.CarreraID), u.ID)
+	return err
+}
+
+// SetPassword reemplaza la contraseña. mustChange obliga a cambiarla en el próximo ingreso
+// (se usa cuando un superadmin la resetea).
+func (r *UserRepository) SetPassword(id int, password string, mustChange bool) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	defer observe("users.SetPassword")()
+	_, err = r.DB.Exec("UPDATE users SET password_hash = ?, must_change_password = ? WHERE id = ?", hash, boolToInt(mustChange), id)
+	return err
+}
+
+func (r *UserRepository) Delete(id int) error {
+	defer observe("users.Delete")()
+	_, err := r.DB.Exec("DELETE FROM users WHERE id = ?", id)
+	return err
+}
+
+// CountSuperadmins se usa para no dejar el sistema sin ningún superadmin
+func (r *UserRepository) CountSuperadmins() (int, error) {
+	defer observe("users.CountSuperadmins")()
+	var count int
+	err := r.DB.QueryRow("SELECT COUNT(*) FROM users WHERE role = ?", models.RoleSuperadmin).Scan(&count)
+	return count, err
+}
+
+// EnsureBootstrapAdmin crea el primer superadmin a partir de las credenciales configuradas.
+// Solo actúa si la tabla está vacía; después se ignoran.
+func (r *UserRepository) EnsureBootstrapAdmin(email, password string) (bool, error) {
+	count, err := r.Count()
+	if err != nil || count > 0 {
+		return false, err
+	}
+	if email == "" || password == "" {
+		return false, errors.New("no hay usuarios y admin.email/admin.password (ADMIN_EMAIL/ADMIN_PASSWORD) no están definidos")
+	}
+	err = r.Create(models.User{Email: email, Role: models.RoleSuperadmin}, password)
+	return err == nil, err
+}
+
+func nullableID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
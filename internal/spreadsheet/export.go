@@ -0,0 +1,131 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"mi-bot-unne/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ExportHeader son las columnas de la exportación; coinciden con las que acepta la importación
+var ExportHeader = []string{"id", "materia", "carrera", "turno", "fecha", "hora", "aula", "sede", "fecha_edicion"}
+
+// MesaWriter escribe mesas una a una en algún formato de salida
+type MesaWriter interface {
+	Write(m models.Mesa) error
+	Close() error
+}
+
+func exportRecord(m models.Mesa) []string {
+	return []string{strconv.Itoa(m.ID), m.Materia, m.Carrera, m.Turno, m.Fecha.String(), m.Hora.String(), m.Aula, m.Sede, m.FechaEdicion}
+}
+
+// --- CSV ---
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter escribe un CSV UTF-8 con BOM para que Excel respete los acentos
+func NewCSVWriter(w io.Writer) (MesaWriter, error) {
+	if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ExportHeader); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(m models.Mesa) error {
+	return c.w.Write(exportRecord(m))
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// --- JSON ---
+
+type jsonWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	first bool
+}
+
+// NewJSONWriter escribe un array JSON elemento por elemento
+func NewJSONWriter(w io.Writer) (MesaWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &jsonWriter{w: w, enc: json.NewEncoder(w), first: true}, nil
+}
+
+func (j *jsonWriter) Write(m models.Mesa) error {
+	if !j.first {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.first = false
+	return j.enc.Encode(m)
+}
+
+func (j *jsonWriter) Close() error {
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
+
+// --- XLSX ---
+
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+// NewXLSXWriter arma la planilla con el stream writer de excelize; el archivo se
+// vuelca a w recién en Close porque el formato zip no permite escribirlo parcial.
+func NewXLSXWriter(w io.Writer) (MesaWriter, error) {
+	f := excelize.NewFile()
+	stream, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SetRow("A1", toCells(ExportHeader)); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{out: w, file: f, stream: stream, row: 1}, nil
+}
+
+func (x *xlsxWriter) Write(m models.Mesa) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, toCells(exportRecord(m)))
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.out)
+}
+
+func toCells(values []string) []any {
+	cells := make([]any, len(values))
+	for i, v := range values {
+		cells[i] = v
+	}
+	return cells
+}
@@ -0,0 +1,324 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mi-bot-unne/internal/models"
+	"mi-bot-unne/internal/repository"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ImportRow es una fila de la planilla ya convertida a Mesa, con sus errores de validación
+type ImportRow struct {
+	Line   int
+	Mesa   models.Mesa
+	Errors []string
+}
+
+// Valid indica si la fila puede importarse
+func (r ImportRow) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// AddError agrega un error a la fila si no lo tiene ya
+func (r *ImportRow) AddError(msg string) {
+	if !slices.Contains(r.Errors, msg) {
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+// Catalog contiene los valores válidos de cada columna, indexados por nombre normalizado
+type Catalog struct {
+	Materias map[string]models.Materia
+	Carreras map[string]models.Carrera
+	Turnos   map[string]models.TurnoConfig
+	Aulas    map[string][]models.Aula
+	Sedes    map[string]models.Sede
+}
+
+// NewCatalog arma el catálogo a partir de los parámetros cargados en la base
+func NewCatalog(materias []models.Materia, carreras []models.Carrera, turnos []models.TurnoConfig, aulas []models.Aula, sedes []models.Sede) Catalog {
+	cat := Catalog{
+		Materias: map[string]models.Materia{},
+		Carreras: map[string]models.Carrera{},
+		Turnos:   map[string]models.TurnoConfig{},
+		Aulas:    map[string][]models.Aula{},
+		Sedes:    map[string]models.Sede{},
+	}
+	for _, m := range materias {
+		cat.Materias[key(m.Nombre)] = m
+	}
+	for _, c := range carreras {
+		cat.Carreras[key(c.Nombre)] = c
+	}
+	for _, t := range turnos {
+		cat.Turnos[key(t.Nombre)] = t
+	}
+	for _, a := range aulas {
+		cat.Aulas[key(a.Nombre)] = append(cat.Aulas[key(a.Nombre)], a)
+	}
+	for _, s := range sedes {
+		cat.Sedes[key(s.Nombre)] = s
+	}
+	return cat
+}
+
+// columnAliases maps accepted header names to the Mesa field they fill
+var columnAliases = map[string]string{
+	"materia":    "materia",
+	"asignatura": "materia",
+	"carrera":    "carrera",
+	"turno":      "turno",
+	"fecha":      "fecha",
+	"dia":        "fecha",
+	"hora":       "hora",
+	"horario":    "hora",
+	"aula":       "aula",
+	"sede":       "sede",
+}
+
+var requiredColumns = []string{"materia", "carrera", "turno", "fecha", "hora", "aula"}
+
+// ReadRows lee un CSV o XLSX (según la extensión) y devuelve sus celdas como texto.
+// En XLSX se toma la primera hoja y las fechas llegan como número de serie de Excel.
+func ReadRows(r io.Reader, filename string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt":
+		return readCSV(r)
+	case ".xlsx":
+		return readXLSX(r)
+	default:
+		return nil, fmt.Errorf("formato no soportado: %q (usar .csv o .xlsx)", filepath.Ext(filename))
+	}
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // BOM de Excel
+
+	// Excel en configuración regional es-AR exporta con ';'
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("el archivo no tiene hojas")
+	}
+	return f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+}
+
+// ParseMesas convierte las filas (la primera es el encabezado) en mesas y resuelve cada
+// nombre contra el catálogo. Las reglas que dependen de la base (permisos, fecha dentro
+// del turno) las aplica el panel antes de ofrecer la confirmación.
+func ParseMesas(rows [][]string, cat Catalog) ([]ImportRow, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("el archivo está vacío")
+	}
+
+	columns := map[string]int{}
+	for i, h := range rows[0] {
+		if field, ok := columnAliases[key(h)]; ok {
+			columns[field] = i
+		}
+	}
+	var missing []string
+	for _, col := range requiredColumns {
+		if _, ok := columns[col]; !ok {
+			missing = append(missing, col)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("faltan columnas: %s", strings.Join(missing, ", "))
+	}
+
+	cell := func(row []string, field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var result []ImportRow
+	for i, row := range rows[1:] {
+		if isBlank(row) {
+			continue
+		}
+		ir := ImportRow{Line: i + 2}
+
+		materia := lookup(cat.Materias, cell(row, "materia"), "materia", &ir.Errors)
+		ir.Mesa.MateriaID, ir.Mesa.Materia = materia.ID, orRaw(materia.Nombre, cell(row, "materia"))
+		carrera := lookup(cat.Carreras, cell(row, "carrera"), "carrera", &ir.Errors)
+		ir.Mesa.CarreraID, ir.Mesa.Carrera = carrera.ID, orRaw(carrera.Nombre, cell(row, "carrera"))
+		turno := lookupTurno(cat.Turnos, cell(row, "turno"), &ir.Errors)
+		ir.Mesa.TurnoID, ir.Mesa.Turno = turno.ID, orRaw(turno.Nombre, cell(row, "turno"))
+		aula := lookupAula(cat, cell(row, "aula"), cell(row, "sede"), &ir.Errors)
+		ir.Mesa.AulaID, ir.Mesa.Aula, ir.Mesa.SedeID = aula.ID, orRaw(aula.Nombre, cell(row, "aula")), aula.SedeID
+
+		if fecha, err := parseFecha(cell(row, "fecha")); err != nil {
+			ir.Errors = append(ir.Errors, err.Error())
+		} else {
+			ir.Mesa.Fecha = fecha
+		}
+		if hora, err := parseHora(cell(row, "hora")); err != nil {
+			ir.Errors = append(ir.Errors, err.Error())
+		} else {
+			ir.Mesa.Hora = hora
+		}
+
+		result = append(result, ir)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("el archivo no tiene filas de datos")
+	}
+	return result, nil
+}
+
+// lookup busca raw en values; si no está, agrega el error y devuelve el valor cero
+func lookup[T any](values map[string]T, raw, field string, errs *[]string) T {
+	var zero T
+	if raw == "" {
+		*errs = append(*errs, field+" vacía")
+		return zero
+	}
+	if v, ok := values[key(raw)]; ok {
+		return v
+	}
+	*errs = append(*errs, fmt.Sprintf("%s %q no existe", field, raw))
+	return zero
+}
+
+func lookupTurno(turnos map[string]models.TurnoConfig, raw string, errs *[]string) models.TurnoConfig {
+	// La planilla suele traer sólo el número ("3" en lugar de "3° Turno")
+	if _, err := strconv.Atoi(raw); err == nil {
+		if v, ok := turnos[key(raw+"° Turno")]; ok {
+			return v
+		}
+	}
+	return lookup(turnos, raw, "turno", errs)
+}
+
+// lookupAula resuelve el aula por nombre; si hay varias con el mismo nombre, la sede decide
+func lookupAula(cat Catalog, raw, sede string, errs *[]string) models.Aula {
+	if raw == "" {
+		*errs = append(*errs, "aula vacía")
+		return models.Aula{}
+	}
+	candidates, ok := cat.Aulas[key(raw)]
+	if !ok {
+		*errs = append(*errs, fmt.Sprintf("aula %q no existe", raw))
+		return models.Aula{}
+	}
+	if sede == "" {
+		if len(candidates) > 1 {
+			*errs = append(*errs, fmt.Sprintf("hay %d aulas %q; indicar la sede", len(candidates), raw))
+			return models.Aula{}
+		}
+		return candidates[0]
+	}
+	s, ok := cat.Sedes[key(sede)]
+	if !ok {
+		*errs = append(*errs, fmt.Sprintf("sede %q no existe", sede))
+		return models.Aula{}
+	}
+	for _, a := range candidates {
+		if a.SedeID == s.ID {
+			return a
+		}
+	}
+	*errs = append(*errs, fmt.Sprintf("aula %q no pertenece a la sede %q", raw, sede))
+	return models.Aula{}
+}
+
+// orRaw muestra en la vista previa lo que vino en la planilla cuando no se encontró en el catálogo
+func orRaw(nombre, raw string) string {
+	if nombre == "" {
+		return raw
+	}
+	return nombre
+}
+
+var fechaLayouts = []string{"2006-01-02", "02/01/2006", "2/1/2006", "02-01-2006", "02/01/06"}
+
+// parseFecha acepta los formatos habituales de las planillas y los seriales de Excel
+func parseFecha(raw string) (models.Date, error) {
+	if raw == "" {
+		return models.Date{}, errors.New("fecha vacía")
+	}
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err != nil {
+			return models.Date{}, fmt.Errorf("fecha %q inválida", raw)
+		}
+		return models.NewDate(t.Date()), nil
+	}
+	for _, layout := range fechaLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return models.NewDate(t.Date()), nil
+		}
+	}
+	return models.Date{}, fmt.Errorf("fecha %q inválida (usar DD/MM/AAAA)", raw)
+}
+
+var horaLayouts = []string{"15:04", "15:04:05", "15.04", "15"}
+
+// parseHora acepta "14:30", "14.30", "14hs" y las fracciones de día de Excel
+func parseHora(raw string) (models.TimeOfDay, error) {
+	if raw == "" {
+		return models.TimeOfDay{}, errors.New("hora vacía")
+	}
+	raw = strings.TrimSuffix(strings.ToLower(raw), "hs")
+	raw = strings.TrimSpace(strings.TrimSuffix(raw, "h"))
+
+	// Excel guarda las horas como fracción del día
+	if frac, err := strconv.ParseFloat(raw, 64); err == nil && frac > 0 && frac < 1 {
+		minutes := int(frac*24*60 + 0.5)
+		return models.NewTimeOfDay(minutes/60, minutes%60), nil
+	}
+	for _, layout := range horaLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return models.NewTimeOfDay(t.Hour(), t.Minute()), nil
+		}
+	}
+	return models.TimeOfDay{}, fmt.Errorf("hora %q inválida (usar HH:MM)", raw)
+}
+
+func isBlank(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func key(s string) string {
+	return repository.Normalize(strings.TrimSpace(s))
+}